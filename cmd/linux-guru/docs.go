@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/ronoaldo/genai-demos/pkg/docs"
+	"github.com/ronoaldo/genai-demos/pkg/readline"
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// docsContext introduces the passages of the local documentation in the
+// prompt.
+const docsContext = `Trechos da documentação instalada neste sistema. Prefira os comandos e opções
+descritos neles e cite a fonte de cada informação com o número do trecho entre
+colchetes, como [1]:
+
+`
+
+// index is the local documentation index, if -docs is enabled and the
+// index was built with -index.
+var index *docs.Index
+
+// loadIndex loads the index of the local documentation. A missing index
+// is ignored, since it is only built with -index.
+func loadIndex() {
+	ix, err := docs.Load(indexFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	} else if err != nil {
+		log.Printf("Aviso: não foi possível ler o índice da documentação: %v", err)
+		return
+	}
+	index = ix
+}
+
+// withDocs adds the passages of the local documentation most relevant to
+// the question to the prompt context. Errors are logged and the prompt
+// context is returned unchanged.
+func withDocs(ctx context.Context, model text.Embedder, promptContext, question string) (string, []docs.Result) {
+	if index == nil || passages <= 0 {
+		return promptContext, nil
+	}
+	results, err := index.Search(ctx, model, question, passages)
+	if err != nil {
+		log.Printf("Aviso: não foi possível consultar a documentação local: %v", err)
+		return promptContext, nil
+	}
+	return beforeQuestion(promptContext, docsContext+docs.FormatPassages(results)), results
+}
+
+// buildIndex indexes the man pages, the package documentation and the
+// Markdown directories selected by the flags. Ctrl-C stops the indexing,
+// saving the files indexed so far.
+func buildIndex(model text.Embedder) {
+	var paths []string
+	if indexMan != "" {
+		pages, err := docs.ManPages(docs.ManDirs(), strings.Split(indexMan, ","))
+		if err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+		paths = append(paths, pages...)
+	}
+	if indexDocs {
+		files, err := docs.DocFiles(docs.DocDir)
+		if err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+		paths = append(paths, files...)
+	}
+	for _, dir := range strings.Split(indexDirs, ",") {
+		if dir = strings.TrimSpace(dir); dir == "" {
+			continue
+		}
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+		files, err := docs.MarkdownFiles(dir)
+		if err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+		paths = append(paths, files...)
+	}
+
+	ix, err := docs.Load(indexFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		ix = docs.NewIndex()
+	} else if err != nil {
+		log.Fatalf("Erro: %v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ixr := docs.NewIndexer(model)
+	if readline.IsTerminal(int(os.Stderr.Fd())) {
+		ixr.Progress = func(path string, done, total int) {
+			fmt.Fprintf(os.Stderr, "\rIndexando a documentação: %d de %d arquivos", done, total)
+		}
+	}
+	stats, err := ixr.Update(ctx, ix, paths)
+	if ixr.Progress != nil {
+		fmt.Fprintln(os.Stderr)
+	}
+	if saveErr := ix.Save(indexFile); saveErr != nil {
+		log.Fatalf("Erro: não foi possível salvar o índice: %v", saveErr)
+	}
+	fmt.Printf("Índice salvo em %s: %d arquivos indexados, %d sem alterações, %d removidos e %d ignorados; %d trechos no total.\n",
+		indexFile, stats.Indexed, stats.Unchanged, stats.Removed, stats.Failed, len(ix.Passages))
+	if err != nil {
+		log.Fatalf("Erro: indexação interrompida: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// linuxPolicy allows questions about Linux and the usual command line
+// tools. Questions without keywords are compared with the examples and,
+// when still undecided, classified by the model.
+var linuxPolicy = text.Policy{
+	Topics: []text.Topic{{
+		Name: "linux",
+		Keywords: []string{
+			"linux", "gnu", "unix", "posix", "kernel", "distro", "distribuição",
+			"ubuntu", "debian", "fedora", "centos", "rhel", "arch", "mint", "opensuse", "alpine",
+			"bash", "zsh", "fish", "shell", "terminal", "console", "tty", "script",
+			"comando", "comandos", "command", "sudo", "root", "systemd", "systemctl", "journalctl",
+			"apt", "apt-get", "dpkg", "dnf", "yum", "rpm", "pacman", "snap", "flatpak",
+			"ls", "cd", "cp", "mv", "rm", "mkdir", "chmod", "chown", "grep", "sed", "awk", "find",
+			"tar", "gzip", "ssh", "scp", "rsync", "curl", "wget", "cron", "crontab", "vim", "nano",
+			"df", "du", "mount", "fstab", "partição", "swap", "grub", "permissão", "permissões",
+			"processo", "processos", "daemon", "serviço", "firewall", "iptables", "nftables",
+		},
+		Examples: []string{
+			"como listar os arquivos por tamanho?",
+			"como ver quanto espaço livre tenho no disco?",
+			"como instalar um pacote?",
+			"como descobrir qual programa está usando a porta 80?",
+		},
+	}},
+	Description: "Linux, GNU/Linux systems and their command line tools",
+	Refusals: map[string]string{
+		"pt": "Não sei sobre este tema, tente outra pergunta.",
+		"en": "I don't know about this topic, try another question.",
+		"es": "No sé sobre este tema, intente otra pregunta.",
+	},
+	Language:      "pt",
+	Threshold:     0.8,
+	MinSimilarity: 0.55,
+}
+
+// guardrail refuses the questions that are not about Linux, if enabled
+// with -guardrail.
+var guardrail *text.Guardrail
+
+// newGuardrail configures the guardrail, logging its decisions to the file
+// set by -guardrail-log.
+func newGuardrail(model *text.TextClient) {
+	guardrail = text.NewGuardrail(linuxPolicy)
+	guardrail.Embedder = model
+	guardrail.Generator = model
+	if guardrailLog == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(guardrailLog), 0700); err != nil {
+		log.Printf("Aviso: não foi possível registrar as decisões do filtro de temas: %v", err)
+		return
+	}
+	f, err := os.OpenFile(guardrailLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		log.Printf("Aviso: não foi possível registrar as decisões do filtro de temas: %v", err)
+		return
+	}
+	guardrail.Log = f
+}
+
+// allowed checks the question with the guardrail, returning the refusal
+// when it is off-topic. Follow-up questions are checked alone, with the
+// previous question given only to the model classifier, since they are
+// often incomplete. Errors are logged and the question is allowed,
+// leaving it to the prompt instructions.
+func allowed(ctx context.Context, conv *conversation, question string) (refusal string, ok bool) {
+	if guardrail == nil {
+		return "", true
+	}
+	var previous string
+	if n := len(conv.turns); n > 0 {
+		previous = conv.turns[n-1].Question
+	}
+	d, err := guardrail.CheckFollowUp(ctx, question, previous, conv.lang)
+	if err != nil {
+		// canceled questions are reported when answering them
+		if ctx.Err() == nil {
+			log.Printf("Aviso: não foi possível verificar o tema da pergunta: %v", err)
+		}
+		return "", true
+	}
+	return d.Refusal, d.Allowed
+}
+
+func defaultGuardrailLog() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "linux-guru", "guardrail.jsonl")
+}
@@ -6,16 +6,107 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/ronoaldo/genai-demos/pkg/agent"
+	"github.com/ronoaldo/genai-demos/pkg/docs"
+	"github.com/ronoaldo/genai-demos/pkg/markdown"
+	"github.com/ronoaldo/genai-demos/pkg/rank"
+	"github.com/ronoaldo/genai-demos/pkg/readline"
+	"github.com/ronoaldo/genai-demos/pkg/session"
+	"github.com/ronoaldo/genai-demos/pkg/sysinfo"
 	"github.com/ronoaldo/genai-demos/pkg/text"
 )
 
 var projectID string
+var credentialsFile string
+var impersonate string
+var quotaProject string
+var timeout time.Duration
+var candidates int
+var rankers string
+var interactive bool
+var historyFile string
+var review bool
+var run bool
+var force bool
+var systemContext bool
+var showContext bool
+var sessionName string
+var sessionsDir string
+var listSessions bool
+var deleteSession string
+var exportFormat string
+var useDocs bool
+var passages int
+var buildIndexFlag bool
+var indexFile string
+var indexMan string
+var indexDocs bool
+var indexDirs string
+var probe bool
+var autoApprove bool
+var maxSteps int
+var probeBudget time.Duration
+var useGuardrail bool
+var guardrailLog string
 
 func init() {
 	flag.StringVar(&projectID, "project",
 		os.Getenv("GOOGLE_CLOUD_PROJECT"), "The Google `PROJECT_ID` to be used.")
+	flag.StringVar(&credentialsFile, "credentials", "",
+		"Service account key or other credentials JSON `FILE`, used instead of the Application Default Credentials.")
+	flag.StringVar(&impersonate, "impersonate", "",
+		"Email of the service `ACCOUNT` to impersonate when calling the model.")
+	flag.StringVar(&quotaProject, "quota-project", "",
+		"The `PROJECT_ID` billed for the quota of the calls, instead of the project of the credentials.")
+	flag.DurationVar(&timeout, "timeout", text.DefaultCallOptions.Timeout,
+		"Maximum time of each call to the model, including retries. Zero disables it.")
+	flag.IntVar(&candidates, "candidates", 1,
+		"Number of candidate answers to generate. The best-ranked answer, by the -rank scorers, is shown.")
+	flag.StringVar(&rankers, "rank", "safety,majority,centroid",
+		"Comma separated list of scorers used to rank candidates: safety, length, majority, centroid or judge.")
+	flag.BoolVar(&interactive, "i", false,
+		"Start an interactive session, where follow-up questions keep the conversation context. "+
+			"It is the default when no question is given and the input is a terminal.")
+	flag.StringVar(&historyFile, "history", defaultHistoryFile(),
+		"`FILE` where the questions of interactive sessions are saved. Empty disables it.")
+	flag.BoolVar(&review, "review", true,
+		"List the commands found in the answer with their risk and an explanation of each part.")
+	flag.BoolVar(&run, "run", false, "Offer to run each command found in the answer, after confirmation.")
+	flag.BoolVar(&force, "force", false, "Allow -run to execute high risk commands.")
+	flag.BoolVar(&systemContext, "context", false,
+		"Include a summary of this system (distribution, kernel, shell, package managers and available commands) in the prompt.")
+	flag.BoolVar(&showContext, "show-context", false, "Print the system summary shared by -context and exit.")
+	flag.StringVar(&sessionName, "session", "",
+		"Resume the conversation saved as `NAME`, or start a new one, saving each question and answer.")
+	flag.StringVar(&sessionsDir, "sessions-dir", session.DefaultDir("linux-guru"), "`DIR` where the sessions are saved.")
+	flag.BoolVar(&listSessions, "sessions", false, "List the saved sessions and exit.")
+	flag.StringVar(&deleteSession, "delete-session", "", "Delete the session `NAME` and exit.")
+	flag.StringVar(&exportFormat, "export", "",
+		"Print the -session in `FORMAT` and exit: "+strings.Join(session.Formats, " or ")+".")
+	flag.BoolVar(&useDocs, "docs", true,
+		"Include the passages of the local documentation relevant to the question in the prompt, if the -index was built.")
+	flag.IntVar(&passages, "passages", 4, "Number of documentation passages included in the prompt.")
+	flag.BoolVar(&buildIndexFlag, "index", false,
+		"Build or update the index of the local documentation used by -docs and exit. Only new or changed files are indexed.")
+	flag.StringVar(&indexFile, "index-file", docs.DefaultPath("linux-guru"), "`FILE` where the documentation index is saved.")
+	flag.StringVar(&indexMan, "index-man", "1,8", "Comma separated `SECTIONS` of the man pages to index. Empty disables them.")
+	flag.BoolVar(&indexDocs, "index-docs", false, "Also index the README and text files in "+docs.DocDir+".")
+	flag.StringVar(&indexDirs, "index-dir", "", "Comma separated `DIRS` of Markdown files to index.")
+	flag.BoolVar(&probe, "probe", false,
+		"Let the model run read-only probes (df, du, systemctl status and ss) to inspect this system before answering.")
+	flag.BoolVar(&autoApprove, "yes", false, "Run the probes requested with -probe without asking for confirmation.")
+	flag.IntVar(&maxSteps, "max-steps", agent.DefaultMaxSteps, "Maximum number of probes run for each question.")
+	flag.DurationVar(&probeBudget, "probe-budget", agent.DefaultTimeout,
+		"Maximum `DURATION` spent running probes for each question, after which the model must answer.")
+	flag.BoolVar(&useGuardrail, "guardrail", true,
+		"Refuse questions that are not about Linux before sending them to the model.")
+	flag.StringVar(&guardrailLog, "guardrail-log", defaultGuardrailLog(),
+		"`FILE` where the decisions of the -guardrail are logged as JSON lines. Empty disables it.")
 }
 
 var promptContext = `Context: apenas responda a perguntas sobre Linux e GNU/Linux.
@@ -24,47 +115,221 @@ Para outras perguntas, responda: Não sei sobre este tema, tente outra pergunta.
 Pergunta: %s
 Resposta: `
 
-var disclaimer = `
-+--[Aviso]----------------------------------------+
-| Este é um conteúdo gerado por IA.               |
-| Revise quaisquer comandos antes de executá-los. |
-+-------------------------------------------------+
+const disclaimer = "Este é um conteúdo gerado por IA.\nRevise quaisquer comandos antes de executá-los."
 
-`
+// renderer formats the answers as styled Markdown when the output is a
+// terminal.
+var renderer = markdown.NewRenderer(os.Stdout)
+
+// printDisclaimer prints the warning shown before the answers.
+func printDisclaimer() {
+	fmt.Printf("\n%s\n", renderer.Box("Aviso", disclaimer))
+}
 
 func main() {
 	// Parse command line options
 	flag.Parse()
+	params := text.DefaultParameters
+	if candidates < 1 {
+		log.Fatalf("Erro: -candidates deve ser maior que zero.")
+	}
+	params.CandidateCount = candidates
+
+	// Include the system summary before the question
+	if systemContext || showContext {
+		summary := sysinfo.NewCollector().Collect().Summary()
+		if showContext {
+			fmt.Print(summary)
+			return
+		}
+		promptContext = beforeQuestion(promptContext, summary+"\n")
+	}
+
+	if sessionCommands() {
+		return
+	}
+	if buildIndexFlag {
+		buildIndex(newClient())
+		return
+	}
+	if useDocs {
+		loadIndex()
+	}
+	conv := &conversation{params: params}
+	if sessionName != "" {
+		conv.resume(openSession())
+	}
+
+	model := newClient()
+	if useGuardrail {
+		newGuardrail(model)
+	}
+	if interactive || (len(flag.Args()) == 0 && readline.IsTerminal(int(os.Stdin.Fd()))) {
+		// Ctrl-C only cancels the current question in the session
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+		defer stop()
+		repl(ctx, model, conv)
+		return
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	if len(flag.Args()) < 1 {
 		log.Fatalf("Erro: nenhuma pergunta informada na linha de comandos.")
 	}
 	prompt := strings.Join(flag.Args(), " ")
-	params := text.DefaultParameters
-
-	ctx := context.Background()
+	if refusal, ok := allowed(ctx, conv, prompt); !ok {
+		fmt.Println(refusal)
+		return
+	}
 
 	// Call the model to generate text
-	model := text.NewClient(projectID)
-	resp, err := model.GenerateText(ctx, promptContext, prompt, params)
-	if err != nil {
-		log.Fatalf("Erro: model.GenerateText: %v", err.Error())
+	r, err := answer(ctx, model, conv.context(), prompt, params, nil)
+	if ctx.Err() != nil {
+		log.Fatal("Pergunta cancelada.")
+	} else if err != nil {
+		log.Fatalf("Erro: %v", err)
 	}
-
-	// Print the full response as JSON to standard output
-	generated := resp.Predictions[0]
-
-	if generated.SafetyAttributes.Blocked {
-		log.Printf("Detalhes: %#v", generated.SafetyAttributes)
+	if r.SafetyAttributes.Blocked {
+		log.Printf("Detalhes: %#v", r.SafetyAttributes)
 		log.Fatal("Esta resposta foi bloqueada.")
 	}
 
-	fmt.Println(disclaimer)
+	printDisclaimer()
+	printAnswer(r)
+	conv.add(prompt, r)
+	reviewCommands(ctx, r.Content, nil)
+}
+
+// beforeQuestion inserts the text in the prompt context before the
+// question. The text is escaped, since the context is a format string.
+func beforeQuestion(promptContext, text string) string {
+	return strings.Replace(promptContext, "Pergunta: %s", strings.ReplaceAll(text, "%", "%%")+"Pergunta: %s", 1)
+}
+
+// reply is the answer chosen among the candidates generated by the model.
+type reply struct {
+	text.Prediction
+	// Ranking is set when there is more than one candidate.
+	Ranking []rank.Candidate
+	Usage   text.TokenMetadata
+}
 
-	fmt.Println(generated.Content)
-	if len(generated.CitationMetadata.Citations) > 0 {
+// answer calls the model and selects the best answer among the candidates,
+// ranking them when there is more than one. The passages of the local
+// documentation used in the prompt are added to the citations. With
+// -probe, the model may inspect the system first, with the probes
+// confirmed using the editor.
+func answer(ctx context.Context, model *text.TextClient, promptContext, prompt string, params text.Parameters, editor *readline.Editor) (reply, error) {
+	promptContext, results := withDocs(ctx, model, promptContext, prompt)
+	var resp *text.Response
+	var usage text.TokenMetadata
+	if probe {
+		res, err := newAgent(model, editor).Run(ctx, promptContext, prompt, params)
+		if err != nil {
+			return reply{}, fmt.Errorf("agent.Run: %v", err)
+		}
+		resp, usage = res.Response, res.Usage
+	} else {
+		var err error
+		if resp, err = model.GenerateText(ctx, promptContext, prompt, params); err != nil {
+			return reply{}, fmt.Errorf("model.GenerateText: %v", err)
+		}
+		usage = resp.Metadata
+	}
+	if len(resp.Predictions) == 0 {
+		return reply{}, fmt.Errorf("o modelo não retornou nenhuma resposta")
+	}
+	r := reply{Prediction: resp.Predictions[0], Usage: usage}
+	if len(resp.Predictions) > 1 {
+		ranker, err := newRanker(model)
+		if err != nil {
+			return r, err
+		}
+		r.Ranking, err = ranker.Rank(ctx, prompt, resp.Predictions)
+		if err != nil {
+			return r, fmt.Errorf("ranker.Rank: %v", err)
+		}
+		r.Prediction = r.Ranking[0].Prediction
+	}
+	r.CitationMetadata.Citations = append(r.CitationMetadata.Citations, docs.Citations(r.Content, results)...)
+	return r, nil
+}
+
+// printAnswer prints the answer, its citations and the ranking.
+func printAnswer(r reply) {
+	fmt.Println(renderer.Render(r.Content))
+	if len(r.CitationMetadata.Citations) > 0 {
 		fmt.Println("\nReferences:")
-		for _, citation := range resp.Predictions[0].CitationMetadata.Citations {
-			fmt.Println(citation.Title, " ", citation.URL)
+		printed := make(map[text.Citation]bool)
+		for _, citation := range r.CitationMetadata.Citations {
+			// the same source may be cited in several parts of the answer
+			key := text.Citation{Title: citation.Title, URL: citation.URL}
+			if !printed[key] {
+				printed[key] = true
+				fmt.Println(citation.Title, " ", citation.URL)
+			}
 		}
 	}
+	if len(r.Ranking) > 1 {
+		printRanking(r.Ranking)
+	}
+}
+
+// newRanker returns the ranker configured with the -rank flag.
+func newRanker(model *text.TextClient) (*rank.Ranker, error) {
+	var scorers []rank.Scorer
+	for _, name := range strings.Split(rankers, ",") {
+		switch strings.TrimSpace(name) {
+		case "safety":
+			scorers = append(scorers, rank.Safety{})
+		case "length":
+			scorers = append(scorers, rank.Length{})
+		case "majority":
+			scorers = append(scorers, rank.Majority{})
+		case "centroid":
+			scorers = append(scorers, rank.Centroid{Embedder: model})
+		case "judge":
+			scorers = append(scorers, rank.Judge{Generator: model, Params: text.MoreDeterministic})
+		case "":
+		default:
+			return nil, fmt.Errorf("critério de classificação desconhecido: %q", name)
+		}
+	}
+	return rank.NewRanker(scorers...), nil
+}
+
+// printRanking shows how each candidate was scored.
+func printRanking(ranking []rank.Candidate) {
+	votes := 0
+	for _, c := range ranking {
+		if rank.Normalize(c.Prediction.Content) == rank.Normalize(ranking[0].Prediction.Content) {
+			votes++
+		}
+	}
+	fmt.Printf("\nConcordância: %d de %d respostas concordam com a resposta mais bem classificada.\n", votes, len(ranking))
+	fmt.Println("Classificação das respostas:")
+	for i, c := range ranking {
+		summary := strings.Join(strings.Fields(c.Prediction.Content), " ")
+		if r := []rune(summary); len(r) > 60 {
+			summary = string(r[:59]) + "…"
+		}
+		if c.Prediction.SafetyAttributes.Blocked {
+			summary = "(bloqueada)"
+		}
+		fmt.Printf("%d. [%.2f] #%d %s\n", i+1, c.Score, c.Index+1, summary)
+	}
+}
+
+// newClient returns the client of the model in the -project, using the
+// credentials and the -timeout selected by the command line flags.
+func newClient() *text.TextClient {
+	model := text.NewClient(projectID)
+	auth := text.Auth{CredentialsFile: credentialsFile, Impersonate: impersonate, QuotaProject: quotaProject}
+	if err := model.SetAuth(auth); err != nil {
+		log.Fatalf("Erro: %v", err)
+	}
+	calls := text.DefaultCallOptions
+	calls.Timeout = timeout
+	model.SetCallOptions(calls)
+	return model
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ronoaldo/genai-demos/pkg/agent"
+	"github.com/ronoaldo/genai-demos/pkg/readline"
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// newAgent returns the agent used with -probe. Each probe is shown and,
+// unless -yes is set, only runs after confirmation.
+func newAgent(model text.Generator, editor *readline.Editor) *agent.Agent {
+	a := agent.New(model)
+	a.MaxSteps = maxSteps
+	a.Timeout = probeBudget
+	if !autoApprove {
+		a.Approve = func(ctx context.Context, s agent.Step) (bool, error) {
+			return approveProbe(editor, s)
+		}
+	}
+	a.Observe = printStep
+	return a
+}
+
+// approveProbe asks whether the probe can be run. Ctrl-C cancels the
+// question, while the end of the input denies the probe.
+func approveProbe(editor *readline.Editor, s agent.Step) (bool, error) {
+	if editor == nil {
+		editor = readline.New(os.Stdin, os.Stdout)
+	}
+	fmt.Printf("\nO modelo pediu para consultar o sistema com a sonda %s.\n", s.Probe)
+	editor.Prompt = fmt.Sprintf("Executar %s? [s/N] ", strings.Join(s.Command, " "))
+	reply, err := editor.ReadLine()
+	if err == readline.ErrInterrupt {
+		return false, err
+	} else if err != nil {
+		return false, nil
+	}
+	reply = strings.ToLower(strings.TrimSpace(reply))
+	return reply == "s" || reply == "sim" || reply == "y" || reply == "yes", nil
+}
+
+// printStep shows the probe and its output.
+func printStep(s agent.Step) {
+	if s.Command == nil {
+		fmt.Printf("\nPedido de sonda inválido (%s %s): %v\n", s.Probe, strings.Join(s.Args, " "), s.Err)
+		return
+	}
+	if s.Denied {
+		fmt.Printf("Sonda %s não autorizada.\n", s.Probe)
+		return
+	}
+	fmt.Printf("\n$ %s\n", strings.Join(s.Command, " "))
+	if s.Output != "" {
+		fmt.Println(s.Output)
+	}
+	if s.Err != nil {
+		fmt.Printf("(erro: %v)\n", s.Err)
+	}
+}
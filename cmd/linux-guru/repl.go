@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ronoaldo/genai-demos/pkg/readline"
+	"github.com/ronoaldo/genai-demos/pkg/session"
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// replPrompt is shown before each question in interactive sessions.
+const replPrompt = "linux-guru> "
+
+// maxConversationChars limits the size of the previous turns included in
+// each prompt. Older turns are left out of the prompt when it is exceeded.
+const maxConversationChars = 6000
+
+// languages maps the codes accepted by /lang to the instruction added to
+// the prompt.
+var languages = map[string]string{
+	"pt": "Responda em Português.",
+	"en": "Responda em inglês.",
+	"es": "Responda em espanhol.",
+}
+
+var replHelp = `Comandos disponíveis:
+  /reset                  inicia uma nova conversa
+  /params                 mostra os parâmetros do modelo
+  /params NOME            usa os parâmetros predefinidos (%s)
+  /params CHAVE=VALOR...  altera temperature, topk, topp, max ou candidates
+  /save [ARQUIVO]         salva a conversa em Markdown
+  /lang [pt|en|es]        mostra ou altera o idioma das respostas
+  /help                   mostra esta ajuda
+  /exit                   encerra a sessão (ou Ctrl-D)
+
+Ctrl-C cancela a pergunta em andamento.
+`
+
+// turn is a question and its answer in the conversation.
+type turn struct {
+	Question, Answer string
+}
+
+// conversation keeps the state of an interactive session.
+type conversation struct {
+	turns  []turn
+	lang   string
+	params text.Parameters
+	// session, if set, saves each turn of the conversation.
+	session *session.Session
+}
+
+// resume continues the conversation saved in the session.
+func (c *conversation) resume(sess *session.Session) {
+	c.session = sess
+	for _, t := range sess.Turns {
+		c.turns = append(c.turns, turn{Question: t.Prompt, Answer: t.Response})
+	}
+}
+
+// add appends the answer to the conversation, saving it in the session.
+func (c *conversation) add(question string, r reply) {
+	c.turns = append(c.turns, turn{Question: question, Answer: r.Content})
+	if c.session == nil {
+		return
+	}
+	c.session.Add(session.Turn{Prompt: question, Response: r.Content, Parameters: c.params, Usage: r.Usage})
+	if err := session.NewStore(sessionsDir).Save(c.session); err != nil {
+		log.Printf("Aviso: não foi possível salvar a sessão: %v", err)
+	}
+}
+
+// context returns the prompt context with the language instruction and the
+// previous turns, newest last, inserted before the new question.
+func (c *conversation) context() string {
+	var b strings.Builder
+	if c.lang != "" {
+		b.WriteString(languages[c.lang] + "\n\n")
+	}
+	// Include the most recent turns that fit in the limit
+	first, size := len(c.turns), 0
+	for first > 0 {
+		t := c.turns[first-1]
+		size += len(t.Question) + len(t.Answer)
+		if size > maxConversationChars {
+			break
+		}
+		first--
+	}
+	for _, t := range c.turns[first:] {
+		fmt.Fprintf(&b, "Pergunta: %s\nResposta: %s\n\n", t.Question, t.Answer)
+	}
+	return beforeQuestion(promptContext, b.String())
+}
+
+// markdown returns the conversation formatted as Markdown.
+func (c *conversation) markdown() string {
+	var b strings.Builder
+	b.WriteString("# linux-guru\n")
+	for _, t := range c.turns {
+		fmt.Fprintf(&b, "\n## %s\n\n%s\n", t.Question, t.Answer)
+	}
+	return b.String()
+}
+
+// repl runs the interactive session, reading questions until the end of
+// the input or until the context is canceled.
+func repl(ctx context.Context, model *text.TextClient, conv *conversation) {
+	editor := readline.New(os.Stdin, os.Stdout)
+	editor.Prompt = replPrompt
+	history, err := readline.LoadHistory(historyFile, 1000)
+	if err != nil {
+		log.Printf("Aviso: não foi possível ler o histórico: %v", err)
+		history, _ = readline.LoadHistory("", 1000)
+	}
+	editor.History = history
+
+	if editor.Terminal() {
+		printDisclaimer()
+		if conv.session != nil && len(conv.turns) > 0 {
+			fmt.Printf("Sessão %s retomada com %d perguntas.\n", conv.session.Name, len(conv.turns))
+		}
+		fmt.Println("Digite sua pergunta, ou /help para ver os comandos.")
+	}
+	for {
+		line, err := editor.ReadLine()
+		if err == readline.ErrInterrupt {
+			continue
+		} else if err == io.EOF {
+			return
+		} else if err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := history.Add(line); err != nil {
+			log.Printf("Aviso: não foi possível salvar o histórico: %v", err)
+		}
+		if strings.HasPrefix(line, "/") {
+			if quit := command(conv, line); quit {
+				return
+			}
+			continue
+		}
+		ask(ctx, model, conv, line, editor)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// ask answers a question in the conversation. Ctrl-C cancels the request
+// without ending the session.
+func ask(ctx context.Context, model *text.TextClient, conv *conversation, question string, editor *readline.Editor) {
+	reqCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-reqCtx.Done():
+		}
+	}()
+
+	if refusal, ok := allowed(reqCtx, conv, question); !ok {
+		signal.Stop(interrupt)
+		fmt.Printf("%s\n\n", refusal)
+		return
+	}
+	r, err := answer(reqCtx, model, conv.context(), question, conv.params, editor)
+	signal.Stop(interrupt)
+	switch {
+	case reqCtx.Err() != nil:
+		fmt.Println("Pergunta cancelada.")
+		return
+	case err != nil:
+		log.Printf("Erro: %v", err)
+		return
+	case r.SafetyAttributes.Blocked:
+		log.Printf("Esta resposta foi bloqueada. Detalhes: %#v", r.SafetyAttributes)
+		return
+	}
+	printAnswer(r)
+	conv.add(question, r)
+	reviewCommands(ctx, r.Content, editor)
+	editor.Prompt = replPrompt
+	fmt.Println()
+}
+
+// command runs a slash command, returning true if the session should end.
+func command(conv *conversation, line string) bool {
+	args := strings.Fields(line)
+	switch args[0] {
+	case "/exit", "/quit":
+		return true
+	case "/help":
+		fmt.Printf(replHelp, strings.Join(text.PresetNames(), ", "))
+	case "/reset":
+		conv.turns = nil
+		fmt.Println("Nova conversa iniciada.")
+	case "/params":
+		if err := setParams(&conv.params, args[1:]); err != nil {
+			fmt.Printf("Erro: %v\n", err)
+			return false
+		}
+		p := conv.params
+		fmt.Printf("temperature=%g topk=%d topp=%g max=%d candidates=%d\n",
+			p.Temperature, p.TopK, p.TopP, p.MaxTokens, p.CandidateCount)
+	case "/save":
+		name := "linux-guru-" + time.Now().Format("20060102-150405") + ".md"
+		if len(args) > 1 {
+			name = args[1]
+		}
+		if err := os.WriteFile(name, []byte(conv.markdown()), 0644); err != nil {
+			fmt.Printf("Erro: %v\n", err)
+			return false
+		}
+		fmt.Printf("Conversa salva em %s.\n", name)
+	case "/lang":
+		if len(args) > 1 {
+			if _, ok := languages[args[1]]; !ok {
+				fmt.Printf("Erro: idioma desconhecido: %q\n", args[1])
+				return false
+			}
+			conv.lang = args[1]
+		}
+		lang := conv.lang
+		if lang == "" {
+			lang = "padrão"
+		}
+		fmt.Printf("Idioma das respostas: %s\n", lang)
+	default:
+		fmt.Printf("Comando desconhecido: %s. Digite /help para ver os comandos.\n", args[0])
+	}
+	return false
+}
+
+// setParams changes the parameters with a preset name or a list of
+// key=value pairs.
+func setParams(p *text.Parameters, args []string) error {
+	for _, arg := range args {
+		if preset, ok := text.Presets[arg]; ok {
+			preset.CandidateCount = p.CandidateCount
+			*p = preset
+			continue
+		}
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("parâmetro inválido: %q", arg)
+		}
+		var err error
+		switch strings.ToLower(key) {
+		case "temperature":
+			p.Temperature, err = strconv.ParseFloat(value, 64)
+		case "topk":
+			p.TopK, err = strconv.Atoi(value)
+		case "topp":
+			p.TopP, err = strconv.ParseFloat(value, 64)
+		case "max":
+			p.MaxTokens, err = strconv.Atoi(value)
+		case "candidates":
+			p.CandidateCount, err = strconv.Atoi(value)
+			if err == nil && p.CandidateCount < 1 {
+				err = errors.New("deve ser maior que zero")
+			}
+		default:
+			return fmt.Errorf("parâmetro desconhecido: %q", key)
+		}
+		if err != nil {
+			return fmt.Errorf("valor inválido para %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// defaultHistoryFile returns the path of the history file in the user
+// configuration directory.
+func defaultHistoryFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "linux-guru", "history")
+}
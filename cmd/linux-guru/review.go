@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+
+	"github.com/ronoaldo/genai-demos/pkg/readline"
+	"github.com/ronoaldo/genai-demos/pkg/shell"
+)
+
+// reviewCommands lists the commands found in the answer with their risk
+// and an explanation of each part. With -run, each command can be executed
+// after confirmation; high risk commands are refused unless -force is set.
+func reviewCommands(ctx context.Context, answer string, editor *readline.Editor) {
+	if !review && !run {
+		return
+	}
+	cmds := shell.Extract(answer)
+	if len(cmds) == 0 {
+		return
+	}
+	home, _ := os.UserHomeDir()
+
+	fmt.Println("\nComandos encontrados na resposta:")
+	type reviewed struct {
+		cmd        string
+		assessment shell.Assessment
+	}
+	var all []reviewed
+	for i, cmd := range cmds {
+		script, a := shell.AssessLine(cmd, home)
+		all = append(all, reviewed{cmd, a})
+		fmt.Printf("\n%d. %s\n   Risco: %s\n", i+1, strings.ReplaceAll(cmd, "\n", "\n   "), a.Risk)
+		for _, reason := range a.Reasons {
+			fmt.Printf("   - %s\n", reason)
+		}
+		if script != nil {
+			for _, p := range shell.Explain(script) {
+				fmt.Printf("     %s: %s\n", p.Text, p.Description)
+			}
+		}
+	}
+	if !run {
+		return
+	}
+
+	if editor == nil {
+		editor = readline.New(os.Stdin, os.Stdout)
+	}
+	for i, r := range all {
+		fmt.Println()
+		if r.assessment.Risk == shell.High && !force {
+			fmt.Printf("O comando %d tem risco alto e não será executado. Use -force para permitir.\n", i+1)
+			continue
+		}
+		summary, _, multiline := strings.Cut(r.cmd, "\n")
+		if multiline {
+			summary += " ..."
+		}
+		editor.Prompt = fmt.Sprintf("Executar o comando %d (%s)? [s/N] ", i+1, summary)
+		reply, err := editor.ReadLine()
+		if err != nil {
+			fmt.Println("Execução cancelada.")
+			return
+		}
+		if reply = strings.ToLower(strings.TrimSpace(reply)); reply != "s" && reply != "sim" && reply != "y" && reply != "yes" {
+			continue
+		}
+		execute(ctx, r.cmd)
+	}
+}
+
+// execute runs the command with the shell, connected to the terminal.
+// Ctrl-C interrupts the command, but not linux-guru.
+func execute(ctx context.Context, cmd string) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		log.Printf("Erro: o comando falhou: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ronoaldo/genai-demos/pkg/session"
+)
+
+// sessionCommands runs the -sessions, -delete-session and -export flags,
+// returning true if one of them was given and the program should exit.
+func sessionCommands() bool {
+	store := session.NewStore(sessionsDir)
+	switch {
+	case listSessions:
+		sessions, err := store.List()
+		if err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+		if len(sessions) == 0 {
+			fmt.Println("Nenhuma sessão salva.")
+		}
+		for _, s := range sessions {
+			u := s.Usage()
+			fmt.Printf("%-20s %s  %3d perguntas  %6d tokens\n", s.Name, s.Updated.Format("2006-01-02 15:04"),
+				len(s.Turns), u.InputTokenCount.TotalTokens+u.OutputTokenCount.TotalTokens)
+		}
+	case deleteSession != "":
+		if err := store.Delete(deleteSession); err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+		fmt.Printf("Sessão %s removida.\n", deleteSession)
+	case exportFormat != "":
+		if sessionName == "" {
+			log.Fatalf("Erro: -export requer -session.")
+		}
+		s, err := store.Load(sessionName)
+		if err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+		if err := session.Export(os.Stdout, s, exportFormat); err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// openSession returns the session set by -session, creating it if it does
+// not exist.
+func openSession() *session.Session {
+	s, err := session.NewStore(sessionsDir).Open(sessionName, "linux-guru")
+	if err != nil {
+		log.Fatalf("Erro: %v", err)
+	}
+	return s
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ronoaldo/genai-demos/pkg/injection"
+	"github.com/ronoaldo/genai-demos/pkg/logging"
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// follow reads log entries continuously from standard input, explaining
+// them each time the window closes, until the input ends or the context is
+// canceled. The warnings of the guard, if any, are
+// reported after each explanation.
+func follow(ctx context.Context, model text.Generator, params text.Parameters, guard *injection.Guard) {
+	w := logging.NewWindow(window, windowSize)
+	w.Cooldown = cooldown
+	if severity != "" {
+		w.Threshold = logging.SeverityLevel(severity)
+		if w.Threshold == 0 {
+			log.Fatalf("Erro: severidade inválida: %q", severity)
+		}
+	}
+
+	// The format is detected from the first line of the stream
+	r := bufio.NewReader(os.Stdin)
+	var sample string
+	if logFormat == "auto" {
+		sample, _ = r.ReadString('\n')
+		r = bufio.NewReader(io.MultiReader(strings.NewReader(sample), r))
+	}
+	f := selectFormat(sample)
+	inputContext := formatContext(f)
+
+	// Entries are read in background, so that the window can be closed
+	// by the timer even when no new entries arrive.
+	entries := make(chan logging.Entry, 100)
+	errc := make(chan error, 1)
+	go func() {
+		s := logging.NewScanner(r)
+		s.SetFormat(f)
+		for s.Scan() {
+			entries <- s.Entry()
+		}
+		errc <- s.Err()
+		close(entries)
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-entries:
+			if !ok {
+				if w.Len() > 0 {
+					explainWindow(ctx, model, guard, w, inputContext, params, "fim da entrada")
+				}
+				if err := <-errc; err != nil {
+					log.Fatalf("Erro: %v", err)
+				}
+				return
+			}
+			if w.Add(e, time.Now()) {
+				explainWindow(ctx, model, guard, w, inputContext, params, "severidade "+strings.ToUpper(e.Severity))
+			}
+		case now := <-ticker.C:
+			if w.Expired(now) {
+				explainWindow(ctx, model, guard, w, inputContext, params, "janela de "+window.String())
+			}
+		case <-ctx.Done():
+			if w.Len() > 0 {
+				log.Printf("Encerrando: %d entradas não foram analisadas.", w.Len())
+			}
+			return
+		}
+	}
+}
+
+// explainWindow flushes the window and prints the explanation of its
+// entries. Errors are logged without stopping the follow mode.
+func explainWindow(ctx context.Context, model text.Generator, guard *injection.Guard, w *logging.Window, inputContext string, params text.Parameters, reason string) {
+	entries, dropped := w.Flush()
+	d := logging.NewDigest(entries)
+	d.Dropped = dropped
+	d.MaxSamples = samples
+	digest := d.String()
+	if verbose {
+		log.Printf("Analisando log: %v", digest)
+	}
+
+	fmt.Printf("=== %s: %d entradas (%s) ===\n", time.Now().Format("15:04:05"), d.Total+dropped, reason)
+	resp, err := model.GenerateText(ctx, inputContext, digest, params)
+	if ctx.Err() != nil {
+		fmt.Println("Análise cancelada.")
+		return
+	} else if err != nil {
+		log.Printf("Erro: model.GenerateText: %v", err)
+		return
+	} else if len(resp.Predictions) == 0 {
+		log.Printf("Erro: o modelo não retornou nenhuma resposta para esta janela.")
+		return
+	}
+	generated := resp.Predictions[0]
+	if generated.SafetyAttributes.Blocked {
+		log.Printf("Esta resposta foi bloqueada. Detalhes: %#v", generated.SafetyAttributes)
+		return
+	}
+	fmt.Println(renderer.Render(generated.Content))
+	reportInjections(guard)
+	fmt.Println()
+}
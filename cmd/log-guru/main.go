@@ -7,12 +7,44 @@ import (
 	"io"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/ronoaldo/genai-demos/pkg/injection"
+	"github.com/ronoaldo/genai-demos/pkg/logging"
+	"github.com/ronoaldo/genai-demos/pkg/markdown"
+	"github.com/ronoaldo/genai-demos/pkg/redact"
+	"github.com/ronoaldo/genai-demos/pkg/session"
+	"github.com/ronoaldo/genai-demos/pkg/summarize"
 	"github.com/ronoaldo/genai-demos/pkg/text"
 )
 
 var projectID string
+var credentialsFile string
+var impersonate string
+var quotaProject string
+var timeout time.Duration
 var verbose bool
+var maxChars int
+var concurrency int
+var raw bool
+var samples int
+var followMode bool
+var window time.Duration
+var windowSize int
+var severity string
+var cooldown time.Duration
+var logFormat string
+var redactFlag bool
+var redactConfig string
+var sessionName string
+var sessionsDir string
+var listSessions bool
+var deleteSession string
+var exportFormat string
+var guardFlag bool
 var promptContext = `
 Você resume e interpreta a saída de logs estruturados do Google Cloud Logging.
 A resposta deve ser curta e objetiva.
@@ -21,34 +53,146 @@ Explique em Português o que está acontecendo com base no log em JSON abaixo:
 
 `
 
+// digestContext is used when the log is sent as a digest of the parsed
+// entries instead of the raw input. The %s is replaced by the guidance of
+// the log format.
+var digestContext = `
+Você resume e interpreta a saída de logs.
+%s
+A resposta deve ser curta e objetiva.
+
+Explique em Português o que está acontecendo com base no resumo do log abaixo.
+O resumo contém as contagens por severidade, recurso e método, os grupos de
+entradas semelhantes (com a quantidade e o período de cada grupo) e exemplos
+de entradas em JSON:
+
+`
+
+// chunkContext is used to summarize each part of large log inputs, that
+// are later merged with mergeContext.
+var chunkContext = `
+Você resume logs estruturados do Google Cloud Logging.
+Liste em tópicos curtos e em Português os eventos relevantes do trecho de log em JSON abaixo,
+mantendo severidades, recursos, mensagens de erro e horários:
+
+%s
+`
+
+var mergeContext = `
+Você resume e interpreta a saída de logs estruturados do Google Cloud Logging.
+A resposta deve ser curta e objetiva.
+
+Os tópicos abaixo resumem partes consecutivas de um log. Explique em Português
+o que está acontecendo, sem repetir informações:
+
+%s
+`
+
+// renderer formats the analysis as styled Markdown when the output is a
+// terminal.
+var renderer = markdown.NewRenderer(os.Stdout)
+
 func init() {
 	flag.StringVar(&projectID, "project",
 		os.Getenv("GOOGLE_CLOUD_PROJECT"), "The Google `PROJECT_ID` to be used.")
+	flag.StringVar(&credentialsFile, "credentials", "",
+		"Service account key or other credentials JSON `FILE`, used instead of the Application Default Credentials.")
+	flag.StringVar(&impersonate, "impersonate", "",
+		"Email of the service `ACCOUNT` to impersonate when calling the model.")
+	flag.StringVar(&quotaProject, "quota-project", "",
+		"The `PROJECT_ID` billed for the quota of the calls, instead of the project of the credentials.")
+	flag.DurationVar(&timeout, "timeout", text.DefaultCallOptions.Timeout,
+		"Maximum time of each call to the model, including retries. Zero disables it.")
 	flag.BoolVar(&verbose, "v", false, "If the output should be more verbose.")
+	flag.IntVar(&maxChars, "max-chars", 16000,
+		"Maximum log size sent in a single call. Larger logs are summarized in parts.")
+	flag.IntVar(&concurrency, "concurrency", 4, "Maximum number of concurrent calls when summarizing large logs.")
+	flag.BoolVar(&raw, "raw", false,
+		"Send the raw JSON to the model instead of a digest of the parsed log entries.")
+	flag.IntVar(&samples, "samples", 5, "Number of representative entries included in the digest.")
+	flag.BoolVar(&followMode, "follow", false,
+		"Read log entries continuously, explaining them periodically, like with `gcloud logging tail`.")
+	flag.DurationVar(&window, "window", time.Minute, "In follow mode, how long entries are accumulated before each explanation.")
+	flag.IntVar(&windowSize, "window-size", 500, "In follow mode, maximum number of entries kept in memory for each explanation.")
+	flag.StringVar(&severity, "severity", "ERROR",
+		"In follow mode, explain immediately when an entry with this `SEVERITY` or higher arrives. Empty disables it.")
+	flag.DurationVar(&cooldown, "cooldown", 30*time.Second,
+		"In follow mode, minimum time between explanations caused by the -severity threshold.")
+	flag.StringVar(&logFormat, "format", "auto",
+		"Format of the log `entries`: auto, gcl, journald, syslog, k8s, nginx, gopanic or text.")
+	flag.BoolVar(&redactFlag, "redact", true,
+		"Replace secrets and personal information with placeholders before sending the log, restoring them in the answer.")
+	flag.StringVar(&redactConfig, "redact-config", "",
+		"JSON `FILE` with additional redaction rules or default rules to disable.")
+	flag.StringVar(&sessionName, "session", "",
+		"Save the explanations in the session `NAME`, including the previous ones in the prompt when it is resumed.")
+	flag.StringVar(&sessionsDir, "sessions-dir", session.DefaultDir("log-guru"), "`DIR` where the sessions are saved.")
+	flag.BoolVar(&listSessions, "sessions", false, "List the saved sessions and exit.")
+	flag.StringVar(&deleteSession, "delete-session", "", "Delete the session `NAME` and exit.")
+	flag.StringVar(&exportFormat, "export", "",
+		"Print the -session in `FORMAT` and exit: "+strings.Join(session.Formats, " or ")+".")
+	flag.BoolVar(&guardFlag, "guard", true,
+		"Fence the log as untrusted data, warn about entries that look like prompt injection and check that the answer stays on task.")
 }
 
 func main() {
 	// Parse command line options
 	flag.Parse()
+	if sessionCommands() {
+		return
+	}
+	params := text.DefaultParameters
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	model := newClient()
+	if verbose {
+		model.Debug(true)
+	}
+	gen, redactor, guard := newGenerator(model)
+	// explain is used for the calls that explain the whole input, that are
+	// saved in the session, if any.
+	explain := gen
+	var sess *sessionGenerator
+	if sessionName != "" {
+		sess = newSessionGenerator(gen)
+		explain = sess
+	}
+	if followMode {
+		follow(ctx, explain, params, guard)
+		reportRedactions(redactor)
+		return
+	}
 
 	// Parse stdin as the prompt
 	b, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		log.Fatalf("Erro: %v", err)
 	}
-
 	jsonlog := string(b)
-	params := text.DefaultParameters
-	ctx := context.Background()
-	model := text.NewClient(projectID)
+
+	// Decode the log entries and build a digest to reduce the prompt size
+	input, inputContext := jsonlog, promptContext
+	if !raw {
+		f := selectFormat(jsonlog)
+		if digest, ok := digestLog(f, jsonlog); ok {
+			input, inputContext = digest, formatContext(f)
+		}
+	}
 
 	// Call the model to generate text
 	if verbose {
-		log.Printf("Analisando log: %v", jsonlog)
-		model.Debug(true)
+		log.Printf("Analisando log: %v", input)
 	}
-	resp, err := model.GenerateText(ctx, promptContext, jsonlog, params)
-	if err != nil {
+	if len(input) > maxChars {
+		summarizeLarge(ctx, gen, sess, input, params)
+		reportInjections(guard)
+		reportRedactions(redactor)
+		return
+	}
+	resp, err := explain.GenerateText(ctx, inputContext, input, params)
+	if ctx.Err() != nil {
+		log.Fatal("Análise cancelada.")
+	} else if err != nil {
 		log.Fatalf("Erro: model.GenerateText: %v", err.Error())
 	}
 
@@ -60,11 +204,178 @@ func main() {
 		log.Fatal("Esta resposta foi bloqueada.")
 	}
 
-	fmt.Println(generated.Content)
+	fmt.Println(renderer.Render(generated.Content))
 	if len(generated.CitationMetadata.Citations) > 0 {
 		fmt.Println("\nReferences:")
 		for _, citation := range resp.Predictions[0].CitationMetadata.Citations {
 			fmt.Println(citation.Title, " ", citation.URL)
 		}
 	}
+	reportInjections(guard)
+	reportRedactions(redactor)
+}
+
+// newGenerator returns the generator used to explain the logs, that
+// fences the log as untrusted data unless disabled with -guard=false and
+// redacts the prompts unless disabled with -redact=false. The guard and
+// the redactor are nil when disabled. The log is fenced after the
+// redaction, so the guard sees the prompt as sent to the model.
+func newGenerator(model *text.TextClient) (text.Generator, *redact.Redactor, *injection.Guard) {
+	var gen text.Generator = model
+	var guard *injection.Guard
+	if guardFlag {
+		guard = injection.NewGuard()
+		gen = injection.Generator{Generator: gen, Guard: guard}
+	}
+	if !redactFlag {
+		return gen, nil, guard
+	}
+	config := &redact.Config{}
+	if redactConfig != "" {
+		var err error
+		if config, err = redact.LoadConfig(redactConfig); err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+	}
+	r, err := redact.New(config.RuleSet())
+	if err != nil {
+		log.Fatalf("Erro: %v", err)
+	}
+	return redact.Generator{Generator: gen, Redactor: r}, r, guard
+}
+
+// reportInjections warns on standard error about the log entries that look
+// like prompt injection attempts and the answers that may have been
+// affected by them, since the last report.
+func reportInjections(g *injection.Guard) {
+	if g == nil {
+		return
+	}
+	report := g.Flush()
+	if len(report.Findings) > 0 {
+		log.Printf("Aviso: %d trechos do log parecem tentativas de injeção de prompt e foram tratados apenas como dados:", len(report.Findings))
+		for _, f := range report.Findings {
+			log.Printf("  - %s: %s", f.Rule, f.Excerpt)
+		}
+	}
+	for _, w := range injection.Warnings {
+		if n := report.Warnings[w]; n > 0 {
+			log.Printf("Aviso: %s (%d respostas); ela pode ter sido manipulada pelo conteúdo do log.", w, n)
+		}
+	}
+}
+
+// reportRedactions prints to standard error how many values of each kind
+// were replaced before sending the log. The values themselves are only
+// listed in verbose mode.
+func reportRedactions(r *redact.Redactor) {
+	if r == nil {
+		return
+	}
+	report := r.Report()
+	if len(report) == 0 {
+		return
+	}
+	var rules []string
+	counts := make(map[string]int)
+	for _, f := range report {
+		if counts[f.Rule] == 0 {
+			rules = append(rules, f.Rule)
+		}
+		counts[f.Rule] += f.Count
+		if verbose {
+			log.Printf("Removido: %s (%d ocorrências)", f.Placeholder, f.Count)
+		}
+	}
+	var summary []string
+	for _, rule := range rules {
+		summary = append(summary, fmt.Sprintf("%s=%d", rule, counts[rule]))
+	}
+	log.Printf("Dados sensíveis substituídos antes do envio: %s", strings.Join(summary, ", "))
+}
+
+// selectFormat returns the format set with -format, or detects it from
+// the sample when it is "auto".
+func selectFormat(sample string) *logging.Format {
+	if logFormat == "auto" {
+		f := logging.DetectFormat(sample)
+		if verbose {
+			log.Printf("Formato do log detectado: %s", f.Name)
+		}
+		return f
+	}
+	f, err := logging.LookupFormat(logFormat)
+	if err != nil {
+		log.Fatalf("Erro: %v", err)
+	}
+	return f
+}
+
+// formatContext returns the digest prompt context for the log format.
+func formatContext(f *logging.Format) string {
+	return fmt.Sprintf(digestContext, f.Guidance)
+}
+
+// digestLog parses the log entries in the given format and returns their
+// digest. It returns false if the input could not be parsed.
+func digestLog(f *logging.Format, jsonlog string) (string, bool) {
+	entries, err := f.Parse(strings.NewReader(jsonlog))
+	if err != nil || len(entries) == 0 {
+		log.Printf("Aviso: o log não foi reconhecido no formato %s e será enviado sem alterações (%v).", f.Name, err)
+		return "", false
+	}
+	d := logging.NewDigest(entries)
+	d.MaxSamples = samples
+	digest := d.String()
+	if verbose {
+		log.Printf("Resumo: %d entradas em %d grupos; %d caracteres faturáveis (log original: %d)",
+			d.Total, len(d.Groups), text.BillableCharacters(digest), text.BillableCharacters(jsonlog))
+	}
+	return digest, true
+}
+
+// summarizeLarge explains logs that don't fit in a single prompt by
+// summarizing them in parts and merging the partial summaries. Only the
+// final explanation is saved in the session, if any.
+func summarizeLarge(ctx context.Context, model text.Generator, sess *sessionGenerator, jsonlog string, params text.Parameters) {
+	s := summarize.New(model)
+	s.Params = params
+	s.ChunkContext = chunkContext
+	s.MergeContext = mergeContext
+	s.MaxChars = maxChars
+	s.Concurrency = concurrency
+	res, err := s.Summarize(ctx, jsonlog)
+	if ctx.Err() != nil {
+		log.Fatal("Análise cancelada.")
+	} else if err != nil {
+		log.Fatalf("Erro: summarize: %v", err)
+	}
+	if verbose {
+		log.Printf("Log dividido em %d partes", len(res.Chunks))
+		for _, c := range res.Chunks {
+			log.Printf("Parte %d: bytes %d-%d, %d entradas", c.Index+1, c.Start, c.End, c.Units)
+		}
+		for _, p := range res.Partial {
+			log.Printf("Resumo (nível %d, partes %s):\n%s", p.Level, summarize.FormatSources(p.Sources), p.Text)
+		}
+	}
+	fmt.Println(renderer.Render(res.Summary.Text))
+	if sess != nil {
+		sess.record(jsonlog, res.Summary.Text, params, text.TokenMetadata{})
+	}
+	fmt.Printf("\nFontes: partes %s de %d do log.\n", summarize.FormatSources(res.Summary.Sources), len(res.Chunks))
+}
+
+// newClient returns the client of the model in the -project, using the
+// credentials and the -timeout selected by the command line flags.
+func newClient() *text.TextClient {
+	model := text.NewClient(projectID)
+	auth := text.Auth{CredentialsFile: credentialsFile, Impersonate: impersonate, QuotaProject: quotaProject}
+	if err := model.SetAuth(auth); err != nil {
+		log.Fatalf("Erro: %v", err)
+	}
+	calls := text.DefaultCallOptions
+	calls.Timeout = timeout
+	model.SetCallOptions(calls)
+	return model
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/ronoaldo/genai-demos/pkg/session"
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// maxHistoryChars limits the size of the previous explanations included
+// in the prompt when a session is resumed. Older ones are left out.
+const maxHistoryChars = 4000
+
+// sessionCommands runs the -sessions, -delete-session and -export flags,
+// returning true if one of them was given and the program should exit.
+func sessionCommands() bool {
+	store := session.NewStore(sessionsDir)
+	switch {
+	case listSessions:
+		sessions, err := store.List()
+		if err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+		if len(sessions) == 0 {
+			fmt.Println("Nenhuma sessão salva.")
+		}
+		for _, s := range sessions {
+			u := s.Usage()
+			fmt.Printf("%-20s %s  %3d análises  %6d tokens\n", s.Name, s.Updated.Format("2006-01-02 15:04"),
+				len(s.Turns), u.InputTokenCount.TotalTokens+u.OutputTokenCount.TotalTokens)
+		}
+	case deleteSession != "":
+		if err := store.Delete(deleteSession); err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+		fmt.Printf("Sessão %s removida.\n", deleteSession)
+	case exportFormat != "":
+		if sessionName == "" {
+			log.Fatalf("Erro: -export requer -session.")
+		}
+		s, err := store.Load(sessionName)
+		if err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+		if err := session.Export(os.Stdout, s, exportFormat); err != nil {
+			log.Fatalf("Erro: %v", err)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// sessionGenerator is a text.Generator that includes the previous
+// explanations of the session in the prompt context and saves each new
+// one in the session.
+type sessionGenerator struct {
+	text.Generator
+	session *session.Session
+	store   *session.Store
+}
+
+// newSessionGenerator wraps the generator with the session set by
+// -session, creating it if it does not exist.
+func newSessionGenerator(gen text.Generator) *sessionGenerator {
+	store := session.NewStore(sessionsDir)
+	s, err := store.Open(sessionName, "log-guru")
+	if err != nil {
+		log.Fatalf("Erro: %v", err)
+	}
+	if verbose && len(s.Turns) > 0 {
+		log.Printf("Sessão %s retomada com %d análises.", s.Name, len(s.Turns))
+	}
+	return &sessionGenerator{Generator: gen, session: s, store: store}
+}
+
+func (g *sessionGenerator) GenerateText(ctx context.Context, promptContext, prompt string, params text.Parameters) (*text.Response, error) {
+	resp, err := g.Generator.GenerateText(ctx, g.withHistory(promptContext), prompt, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Predictions) == 0 {
+		return nil, text.ErrNoPredictions
+	}
+	if generated := resp.Predictions[0]; !generated.SafetyAttributes.Blocked {
+		g.record(prompt, generated.Content, params, resp.Metadata)
+	}
+	return resp, nil
+}
+
+// record saves the explanation of the prompt in the session.
+func (g *sessionGenerator) record(prompt, response string, params text.Parameters, usage text.TokenMetadata) {
+	g.session.Add(session.Turn{Prompt: prompt, Response: response, Parameters: params, Usage: usage})
+	if err := g.store.Save(g.session); err != nil {
+		log.Printf("Aviso: não foi possível salvar a sessão: %v", err)
+	}
+}
+
+// withHistory adds the most recent explanations of the session that fit
+// in maxHistoryChars before the prompt context.
+func (g *sessionGenerator) withHistory(promptContext string) string {
+	turns := g.session.Turns
+	first, size := len(turns), 0
+	for first > 0 && size+len(turns[first-1].Response) <= maxHistoryChars {
+		first--
+		size += len(turns[first].Response)
+	}
+	if first == len(turns) {
+		return promptContext
+	}
+	var b strings.Builder
+	b.WriteString("Explicações anteriores deste log, da mais antiga para a mais recente:\n\n")
+	for _, t := range turns[first:] {
+		fmt.Fprintf(&b, "%s:\n%s\n\n", t.Time.Format("2006-01-02 15:04:05"), strings.TrimSpace(t.Response))
+	}
+	// The context is a format string only if it has a %s, so it is
+	// converted to one in case the history has a %s of its own.
+	if !strings.Contains(promptContext, "%s") {
+		promptContext = strings.ReplaceAll(promptContext, "%", "%%") + " %s"
+	}
+	return strings.ReplaceAll(b.String(), "%", "%%") + promptContext
+}
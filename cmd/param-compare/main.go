@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ronoaldo/genai-demos/pkg/compare"
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+var (
+	projectID     string
+	promptsFile   string
+	promptContext string
+	presets       string
+	temperatures  string
+	topKs         string
+	topPs         string
+	repeat        int
+	output        string
+	showDiff      bool
+	diffWidth     int
+)
+
+func init() {
+	flag.StringVar(&projectID, "project",
+		os.Getenv("GOOGLE_CLOUD_PROJECT"), "The Google `PROJECT_ID` to be used.")
+	flag.StringVar(&promptsFile, "prompts", "",
+		"Read the prompts from `FILE`, one per line. Use - to read from standard input.")
+	flag.StringVar(&promptContext, "context", "%s",
+		"The prompt context used as template for each prompt.")
+	flag.StringVar(&presets, "presets", strings.Join(text.PresetNames(), ","),
+		"Comma separated list of parameter presets to compare.")
+	flag.StringVar(&temperatures, "temperature", "",
+		"Comma separated list of temperatures to add as a parameter grid.")
+	flag.StringVar(&topKs, "topk", "",
+		"Comma separated list of top-k values to add as a parameter grid.")
+	flag.StringVar(&topPs, "topp", "",
+		"Comma separated list of top-p values to add as a parameter grid.")
+	flag.IntVar(&repeat, "n", 3, "Number of times each prompt is generated with each parameter set.")
+	flag.StringVar(&output, "o", "text", "Output format: text or json.")
+	flag.BoolVar(&showDiff, "diff", true, "Show side-by-side diffs of the first output of each parameter set.")
+	flag.IntVar(&diffWidth, "width", 60, "Column width used by the side-by-side diffs.")
+}
+
+func main() {
+	// Parse command line options
+	flag.Parse()
+	prompts, err := readPrompts()
+	if err != nil {
+		log.Fatalf("error reading prompts: %v", err)
+	}
+	if len(prompts) < 1 {
+		log.Fatalf("Please provide prompts in the command line or with -prompts.")
+	}
+	configs, err := buildConfigs()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(configs) < 1 {
+		log.Fatalf("Please provide at least one preset or parameter grid.")
+	}
+
+	// Run the comparison
+	ctx := context.Background()
+	runner := compare.Runner{
+		Generator:     text.NewClient(projectID),
+		PromptContext: promptContext,
+		Progress: func(prompt string, config compare.Config, attempt int, err error) {
+			if err != nil {
+				log.Printf("%s #%d: %v", config.Name, attempt+1, err)
+			}
+		},
+	}
+	log.Printf("Running %d prompts with %d parameter sets, %d times each", len(prompts), len(configs), repeat)
+	report, err := runner.Run(ctx, prompts, configs, repeat)
+	if err != nil {
+		log.Fatalf("error running comparison: %v", err)
+	}
+
+	switch output {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(report); err != nil {
+			log.Fatalf("error formatting the output: %v", err.Error())
+		}
+	case "text":
+		printReport(os.Stdout, report)
+	default:
+		log.Fatalf("invalid output format: %q", output)
+	}
+}
+
+// readPrompts returns the prompts from the -prompts file, or the command
+// line arguments as a single prompt.
+func readPrompts() ([]string, error) {
+	if promptsFile == "" {
+		if len(flag.Args()) == 0 {
+			return nil, nil
+		}
+		return []string{strings.Join(flag.Args(), " ")}, nil
+	}
+	var r io.Reader = os.Stdin
+	if promptsFile != "-" {
+		f, err := os.Open(promptsFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var prompts []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			prompts = append(prompts, line)
+		}
+	}
+	return prompts, s.Err()
+}
+
+// buildConfigs returns the selected presets followed by the parameter grid,
+// if any grid values were provided.
+func buildConfigs() ([]compare.Config, error) {
+	var configs []compare.Config
+	if presets != "" {
+		c, err := compare.Presets(splitList(presets)...)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, c...)
+	}
+	if temperatures == "" && topKs == "" && topPs == "" {
+		return configs, nil
+	}
+	temps, err := parseFloats(temperatures)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -temperature: %v", err)
+	}
+	ks, err := parseInts(topKs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -topk: %v", err)
+	}
+	ps, err := parseFloats(topPs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -topp: %v", err)
+	}
+	return append(configs, compare.Grid(text.DefaultParameters, temps, ks, ps)...), nil
+}
+
+func printReport(w io.Writer, report *compare.Report) {
+	for i, prompt := range report.Prompts {
+		fmt.Fprintf(w, "== Prompt %d: %s\n\n", i+1, prompt)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "PARAMETERS\tRUNS\tERRORS\tDISTINCT\tVARIANCE\tLENGTH\tSTDDEV\tIN TOKENS\tOUT TOKENS")
+		for _, config := range report.Configs {
+			s := report.Result(prompt, config.Name).Stats
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.2f\t%.0f\t%.1f\t%.1f\t%.1f\n",
+				config.Name, s.Runs, s.Errors, s.Distinct, s.Variance,
+				s.MeanLength, s.StdDevLength, s.MeanInputTokens, s.MeanOutputTokens)
+		}
+		tw.Flush()
+		fmt.Fprintln(w)
+
+		if !showDiff || len(report.Configs) < 2 {
+			continue
+		}
+		base := report.Configs[0].Name
+		for _, config := range report.Configs[1:] {
+			fmt.Fprintf(w, "-- %s vs %s\n", base, config.Name)
+			fmt.Fprint(w, compare.SideBySide(
+				first(report.Result(prompt, base)),
+				first(report.Result(prompt, config.Name)), diffWidth))
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// first returns the first successful output of a result.
+func first(r *compare.Result) string {
+	for _, o := range r.Outputs {
+		if o.Error == "" {
+			return o.Content
+		}
+	}
+	return ""
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseFloats(s string) ([]float64, error) {
+	var values []float64
+	for _, item := range splitList(s) {
+		v, err := strconv.ParseFloat(item, 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func parseInts(s string) ([]int, error) {
+	var values []int
+	for _, item := range splitList(s) {
+		v, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
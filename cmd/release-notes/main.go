@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ronoaldo/genai-demos/pkg/releasenotes"
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+var projectID string
+var products string
+var since string
+var until string
+var output string
+var title string
+var preset string
+var site string
+var stateFile string
+var baseURL string
+var maxEntries int
+
+func init() {
+	flag.StringVar(&projectID, "project",
+		os.Getenv("GOOGLE_CLOUD_PROJECT"), "The Google `PROJECT_ID` to be used.")
+	flag.StringVar(&products, "products", "",
+		"Comma separated `LIST` of products to include, matched as part of the product name. All products are included if empty.")
+	flag.StringVar(&since, "since", time.Now().AddDate(0, 0, -30).Format("2006-01-02"),
+		"The first `DATE` to include, as YYYY-MM-DD.")
+	flag.StringVar(&until, "until", "",
+		"The last `DATE` to include, as YYYY-MM-DD. Defaults to no limit.")
+	flag.StringVar(&output, "o", "", "Write the digest to `FILE` instead of the standard output.")
+	flag.StringVar(&title, "title", "", "The title of the digest.")
+	flag.StringVar(&preset, "preset", "default",
+		"The parameter preset to use: "+strings.Join(text.PresetNames(), ", ")+".")
+	flag.StringVar(&site, "site", "",
+		"Write an HTML page for the run, an index and the Atom and RSS feeds to `DIR`.")
+	flag.StringVar(&stateFile, "state", "",
+		"Keep the notes already summarized in `FILE`, so that only new notes are summarized. Defaults to state.json in the -site directory.")
+	flag.StringVar(&baseURL, "base-url", "", "The `URL` where the -site directory is published, used in the feeds.")
+	flag.IntVar(&maxEntries, "max-entries", 50, "The maximum number of entries kept in the feeds.")
+}
+
+func main() {
+	flag.Usage = func() {
+		w := flag.CommandLine.Output()
+		io.WriteString(w, "Usage: release-notes [options] [FILE]\n\n"+
+			"Summarizes the Google Cloud release notes in FILE, or in the standard input,\n"+
+			"exported from BigQuery as JSON or newline delimited JSON.\n\nOptions:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	params, ok := text.Presets[preset]
+	if !ok {
+		log.Fatalf("invalid -preset %q, use one of %s", preset, strings.Join(text.PresetNames(), ", "))
+	}
+	filter := releasenotes.Filter{Since: parseDate("since", since), Until: parseDate("until", until)}
+	if products != "" {
+		filter.Products = strings.Split(products, ",")
+	}
+
+	in := os.Stdin
+	if name := flag.Arg(0); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatalf("error reading the release notes: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	notes, err := releasenotes.Read(in)
+	if err != nil {
+		log.Fatalf("error reading the release notes: %v", err)
+	}
+	notes = filter.Apply(notes)
+	if len(notes) == 0 {
+		log.Fatalf("No release notes found with the given filters.")
+	}
+	if stateFile == "" && site != "" {
+		stateFile = filepath.Join(site, "state.json")
+	}
+	var state *releasenotes.State
+	if stateFile != "" {
+		if state, err = releasenotes.LoadState(stateFile); err != nil {
+			log.Fatalf("error reading the state: %v", err)
+		}
+		if !filter.Since.IsZero() {
+			state.Forget(filter.Since)
+		}
+		if notes = state.Unseen(notes); len(notes) == 0 {
+			log.Printf("No new release notes since the last run.")
+			return
+		}
+	}
+	log.Printf("Summarizing %d release notes", len(notes))
+
+	d := &releasenotes.Digest{
+		Title:    title,
+		Since:    filter.Since,
+		Until:    filter.Until,
+		Products: releasenotes.GroupNotes(notes),
+	}
+	digester := releasenotes.NewDigester(text.NewClient(projectID))
+	digester.Summarizer.Params = params
+	if err := digester.Summarize(context.Background(), d.Products); err != nil {
+		log.Fatalf("error summarizing the release notes: %v", err)
+	}
+
+	now := time.Now()
+	if state != nil {
+		page := ""
+		if site != "" {
+			page = "digest-" + now.Format("20060102-150405") + ".html"
+		}
+		entries := releasenotes.Entries(d, now, page)
+		state.Add(releasenotes.Run{Time: now, Page: page}, notes, entries, maxEntries)
+		if site != "" {
+			if err := writeSite(d, state, entries, page, now); err != nil {
+				log.Fatalf("error writing the site: %v", err)
+			}
+		}
+		if err := state.Save(stateFile); err != nil {
+			log.Fatalf("error writing the state: %v", err)
+		}
+	}
+	if site != "" && output == "" {
+		return
+	}
+
+	out := os.Stdout
+	if output != "" {
+		if out, err = os.Create(output); err != nil {
+			log.Fatalf("error writing the digest: %v", err)
+		}
+		defer out.Close()
+	}
+	if err := releasenotes.WriteMarkdown(out, d); err != nil {
+		log.Fatalf("error writing the digest: %v", err)
+	}
+}
+
+// writeSite writes the page of the run, the index with the recent entries
+// and the feeds to the site directory.
+func writeSite(d *releasenotes.Digest, state *releasenotes.State, entries []releasenotes.Entry, page string, now time.Time) error {
+	if err := os.MkdirAll(site, 0755); err != nil {
+		return err
+	}
+	title := d.Title
+	if title == "" {
+		title = releasenotes.DefaultTitle
+	}
+	feed := releasenotes.Feed{Title: title, BaseURL: baseURL, Updated: now, Entries: state.Entries}
+	files := map[string]func(io.Writer) error{
+		page: func(w io.Writer) error {
+			return releasenotes.WriteHTML(w, releasenotes.Page{
+				Title: title, Subtitle: d.Description(), Feed: "atom.xml", Entries: entries})
+		},
+		"index.html": func(w io.Writer) error {
+			return releasenotes.WriteHTML(w, releasenotes.Page{
+				Title: title, Subtitle: "Updated at " + now.Format("2006-01-02 15:04") + ".",
+				Feed: "atom.xml", Entries: state.Entries, Runs: state.Runs})
+		},
+		"atom.xml": func(w io.Writer) error { return releasenotes.WriteAtom(w, feed) },
+		"rss.xml":  func(w io.Writer) error { return releasenotes.WriteRSS(w, feed) },
+	}
+	for name, write := range files {
+		f, err := os.Create(filepath.Join(site, name))
+		if err != nil {
+			return err
+		}
+		if err = write(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err = f.Close(); err != nil {
+			return err
+		}
+	}
+	log.Printf("Site written to %s", site)
+	return nil
+}
+
+// parseDate parses the value of a date flag, that may be empty.
+func parseDate(name, value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		log.Fatalf("invalid -%s: %v", name, err)
+	}
+	return t
+}
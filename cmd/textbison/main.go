@@ -4,45 +4,301 @@ import (
 	"context"
 	"encoding/json"
 	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	tmpl "text/template"
+	"time"
 
+	"github.com/ronoaldo/genai-demos/pkg/readline"
 	"github.com/ronoaldo/genai-demos/pkg/text"
 )
 
 var projectID string
+var credentialsFile string
+var impersonate string
+var quotaProject string
+var timeout time.Duration
+var stream bool
+var truncate string
+var estimate bool
+
+var promptFile string
+var contextFile string
+var preset string
+var output string
+var template string
+
+var (
+	temperature float64
+	topP        float64
+	topK        int
+	maxTokens   int
+	candidates  int
+)
 
 func init() {
 	flag.StringVar(&projectID, "project",
 		os.Getenv("GOOGLE_CLOUD_PROJECT"), "The Google `PROJECT_ID` to be used.")
+	flag.StringVar(&credentialsFile, "credentials", "",
+		"Service account key or other credentials JSON `FILE`, used instead of the Application Default Credentials.")
+	flag.StringVar(&impersonate, "impersonate", "",
+		"Email of the service `ACCOUNT` to impersonate when calling the model.")
+	flag.StringVar(&quotaProject, "quota-project", "",
+		"The `PROJECT_ID` billed for the quota of the calls, instead of the project of the credentials.")
+	flag.DurationVar(&timeout, "timeout", text.DefaultCallOptions.Timeout,
+		"Maximum time of the call to the model, including retries. Zero disables it.")
+	flag.BoolVar(&stream, "stream", false,
+		"Print the generated text as it arrives, ignoring -o. When interrupted, the partial text is kept.")
+	flag.StringVar(&truncate, "truncate", string(text.Reject),
+		"How to handle prompts larger than the model limit: none, reject, head, tail or middle-out.")
+	flag.BoolVar(&estimate, "estimate", false,
+		"Print the estimated tokens and billable characters of the prompt without calling the model.")
+
+	flag.StringVar(&promptFile, "f", "",
+		"Read the prompt from `FILE`. Use - to read from standard input.")
+	flag.StringVar(&contextFile, "context", "",
+		"Read the prompt context from `FILE`. A %s in the file is replaced by the prompt, otherwise the prompt is appended.")
+	flag.StringVar(&preset, "preset", "default",
+		"The parameter preset to use: "+strings.Join(text.PresetNames(), ", ")+". Other parameter flags override it.")
+	flag.Float64Var(&temperature, "temperature", 0, "The sampling temperature. Overrides the preset.")
+	flag.Float64Var(&topP, "top-p", 0, "The top-p sampling parameter. Overrides the preset.")
+	flag.IntVar(&topK, "top-k", 0, "The top-k sampling parameter. Overrides the preset.")
+	flag.IntVar(&maxTokens, "max-tokens", 0, "The maximum number of output tokens. Overrides the preset.")
+	flag.IntVar(&candidates, "candidates", 0, "The number of candidate responses. Overrides the preset.")
+	flag.StringVar(&output, "o", "json",
+		"The output format: json, text, ndjson or template.")
+	flag.StringVar(&template, "template", "{{.Content}}\n",
+		"The Go `TEMPLATE` executed for each prediction when using -o template.")
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+
 	// Parse command line options
 	flag.Parse()
-	if len(flag.Args()) < 1 {
-		log.Fatalf("Please provide a prompt in the command line.")
+	prompt, err := readPrompt()
+	if err != nil {
+		log.Fatalf("error reading the prompt: %v", err)
+	}
+	if strings.TrimSpace(prompt) == "" {
+		log.Fatalf("Please provide a prompt in the command line, with -f or in the standard input.")
+	}
+	promptContext := "%s"
+	if contextFile != "" {
+		b, err := os.ReadFile(contextFile)
+		if err != nil {
+			log.Fatalf("error reading the context: %v", err)
+		}
+		promptContext = string(b)
+	}
+	params, err := parseParameters()
+	if err != nil {
+		log.Fatalf("invalid parameters: %v", err)
+	}
+	strategy, err := text.ParseStrategy(truncate)
+	if err != nil {
+		log.Fatalf("invalid -truncate: %v", err)
+	}
+	printer, err := newPrinter(output, template)
+	if err != nil {
+		log.Fatalf("invalid -o: %v", err)
+	}
+	if estimate {
+		printEstimate(text.CompilePrompt(promptContext, prompt))
+		return
 	}
-	prompt := strings.Join(flag.Args(), " ")
-	params := text.DefaultParameters
 
 	// Print the request attributes used
 	log.Printf("Prompt: %#v", prompt)
 	log.Printf("Params: %#v", params)
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Call the model to generate text
 	model := text.NewClient(projectID)
-	resp, err := model.GenerateText(ctx, "%s", prompt, params)
-	if err != nil {
+	model.SetStrategy(strategy)
+	if err := model.SetAuth(text.Auth{CredentialsFile: credentialsFile, Impersonate: impersonate, QuotaProject: quotaProject}); err != nil {
+		log.Fatalf("invalid credentials: %v", err)
+	}
+	calls := text.DefaultCallOptions
+	calls.Timeout = timeout
+	model.SetCallOptions(calls)
+	if stream {
+		streamText(ctx, model, promptContext, prompt, params)
+		return
+	}
+	resp, err := model.GenerateText(ctx, promptContext, prompt, params)
+	if ctx.Err() != nil {
+		log.Fatalf("interrupted: %v", ctx.Err())
+	} else if err != nil {
 		log.Fatalf("error invoking model.GenerateText: %v", err.Error())
 	}
 
-	// Print the full response as JSON to standard output
+	// Print the response to standard output
+	if err = printer(os.Stdout, resp); err != nil {
+		log.Fatalf("error formatting the output: %v", err.Error())
+	}
+	if resp.Truncation != nil {
+		log.Printf("Prompt truncated: %v", resp.Truncation)
+	}
+}
+
+// streamText prints each chunk of the generated text as it arrives. When
+// the call is interrupted or times out, the text received so far is kept
+// in the output.
+func streamText(ctx context.Context, model text.Streamer, promptContext, prompt string, params text.Parameters) {
+	if params.CandidateCount > 1 {
+		log.Fatalf("-stream supports a single candidate")
+	}
+	received := false
+	err := model.GenerateTextStream(ctx, promptContext, prompt, params, func(p text.Prediction) error {
+		received = received || p.Content != ""
+		_, err := fmt.Print(p.Content)
+		return err
+	})
+	if received {
+		fmt.Println()
+	}
+	switch {
+	case ctx.Err() != nil && received:
+		log.Fatalf("interrupted: the response is partial")
+	case ctx.Err() != nil:
+		log.Fatalf("interrupted: %v", ctx.Err())
+	case err != nil && received:
+		log.Fatalf("the response is partial: %v", err)
+	case err != nil:
+		log.Fatalf("error invoking model.GenerateTextStream: %v", err)
+	}
+}
+
+// readPrompt returns the prompt from the -f file, the command line
+// arguments or the standard input, in this order. The standard input is
+// used when the only argument is - or when there are no arguments and it
+// is not a terminal.
+func readPrompt() (string, error) {
+	args := flag.Args()
+	switch {
+	case promptFile == "-":
+		return readAll(os.Stdin)
+	case promptFile != "":
+		b, err := os.ReadFile(promptFile)
+		return string(b), err
+	case len(args) == 1 && args[0] == "-":
+		return readAll(os.Stdin)
+	case len(args) > 0:
+		return strings.Join(args, " "), nil
+	case !readline.IsTerminal(int(os.Stdin.Fd())):
+		return readAll(os.Stdin)
+	}
+	return "", nil
+}
+
+func readAll(r io.Reader) (string, error) {
+	b, err := io.ReadAll(r)
+	return string(b), err
+}
+
+// parseParameters returns the -preset parameters with the values of the
+// parameter flags set in the command line.
+func parseParameters() (text.Parameters, error) {
+	params, ok := text.Presets[preset]
+	if !ok {
+		return params, fmt.Errorf("unknown preset %q, use one of %s",
+			preset, strings.Join(text.PresetNames(), ", "))
+	}
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "temperature":
+			params.Temperature = temperature
+		case "top-p":
+			params.TopP = topP
+		case "top-k":
+			params.TopK = topK
+		case "max-tokens":
+			params.MaxTokens = maxTokens
+		case "candidates":
+			if candidates < 1 {
+				err = fmt.Errorf("-candidates must be greater than zero")
+			}
+			params.CandidateCount = candidates
+		}
+	})
+	return params, err
+}
+
+// printer writes the response to the output.
+type printer func(w io.Writer, resp *text.Response) error
+
+// newPrinter returns the printer for the output format. The template is
+// only used by the template format.
+func newPrinter(format, template string) (printer, error) {
+	switch format {
+	case "json":
+		return func(w io.Writer, resp *text.Response) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(resp)
+		}, nil
+	case "ndjson":
+		return func(w io.Writer, resp *text.Response) error {
+			enc := json.NewEncoder(w)
+			for _, p := range resp.Predictions {
+				if err := enc.Encode(p); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	case "text":
+		return func(w io.Writer, resp *text.Response) error {
+			for i, p := range resp.Predictions {
+				if i > 0 {
+					fmt.Fprintln(w)
+				}
+				if _, err := fmt.Fprintln(w, strings.TrimSpace(p.Content)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	case "template":
+		t, err := tmpl.New("output").Parse(template)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, resp *text.Response) error {
+			for _, p := range resp.Predictions {
+				if err := t.Execute(w, p); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, use json, text, ndjson or template", format)
+}
+
+// printEstimate prints the offline estimate of the prompt size as JSON.
+func printEstimate(prompt string) {
+	limits := text.ModelLimits[text.ModelVersion]
+	estimated := map[string]interface{}{
+		"model":                   text.ModelVersion,
+		"estimatedTokens":         text.EstimateTokens(prompt),
+		"totalBillableCharacters": text.BillableCharacters(prompt),
+		"limits":                  limits,
+	}
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "  ")
-	if err = enc.Encode(resp); err != nil {
+	if err := enc.Encode(estimated); err != nil {
 		log.Fatalf("error formatting the output: %v", err.Error())
 	}
 }
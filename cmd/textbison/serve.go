@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ronoaldo/genai-demos/pkg/server"
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// serve runs the HTTP API until SIGINT or SIGTERM is received, then
+// waits for the requests in progress to finish.
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", defaultAddr(),
+		"The `ADDRESS` to listen on. The API calls the model with your credentials and quota, so listen on "+
+			"all interfaces, like :8080, only with a -token or behind an authenticating proxy.")
+	token := fs.String("token", os.Getenv("TEXTBISON_TOKEN"),
+		"Bearer `TOKEN` required by the API requests, also used as the OpenAI API key. Defaults to $TEXTBISON_TOKEN.")
+	project := fs.String("project", os.Getenv("GOOGLE_CLOUD_PROJECT"), "The Google `PROJECT_ID` to be used.")
+	credentials := fs.String("credentials", "",
+		"Service account key or other credentials JSON `FILE`, used instead of the Application Default Credentials.")
+	impersonate := fs.String("impersonate", "", "Email of the service `ACCOUNT` to impersonate when calling the model.")
+	quotaProject := fs.String("quota-project", "",
+		"The `PROJECT_ID` billed for the quota of the calls, instead of the project of the credentials.")
+	truncate := fs.String("truncate", string(text.Reject),
+		"How to handle prompts larger than the model limit: none, reject, head, tail or middle-out.")
+	timeout := fs.Duration("timeout", text.DefaultCallOptions.Timeout,
+		"Maximum time of each call to the model, including retries. Zero disables it.")
+	maxBody := fs.Int64("max-body", server.DefaultMaxBodyBytes, "The maximum request body size, in bytes.")
+	openAI := fs.Bool("openai", true, "Serve the OpenAI compatible /v1/completions and /v1/chat/completions endpoints.")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 30*time.Second,
+		"How long to wait for the requests in progress when shutting down.")
+	fs.Parse(args)
+
+	strategy, err := text.ParseStrategy(*truncate)
+	if err != nil {
+		log.Fatalf("invalid -truncate: %v", err)
+	}
+	model := text.NewClient(*project)
+	model.SetStrategy(strategy)
+	if err := model.SetAuth(text.Auth{CredentialsFile: *credentials, Impersonate: *impersonate, QuotaProject: *quotaProject}); err != nil {
+		log.Fatalf("invalid credentials: %v", err)
+	}
+	calls := text.DefaultCallOptions
+	calls.Timeout = *timeout
+	model.SetCallOptions(calls)
+
+	s := server.New(model)
+	s.Logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	s.MaxBodyBytes = *maxBody
+	s.Token = *token
+	if s.Token == "" && !loopback(*addr) {
+		s.Logger.Warn("listening on other interfaces without -token; anyone who can reach the server uses your credentials", "addr", *addr)
+	}
+	if *openAI {
+		s.HandleOpenAI()
+	}
+	httpServer := &http.Server{Addr: *addr, Handler: s}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		s.SetReady(false)
+		s.Logger.Info("shutting down", "timeout", *shutdownTimeout)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			s.Logger.Error("shutdown", "error", err)
+		}
+	}()
+
+	s.Logger.Info("listening", "addr", *addr, "model", text.ModelVersion)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("error serving: %v", err)
+	}
+	<-done
+}
+
+// defaultAddr listens on the loopback interface, on $PORT when set. Use
+// -addr to listen on all interfaces, as in Cloud Run.
+func defaultAddr() string {
+	if port := os.Getenv("PORT"); port != "" {
+		return "localhost:" + port
+	}
+	return "localhost:8080"
+}
+
+// loopback reports if the address only accepts local connections.
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
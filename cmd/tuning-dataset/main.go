@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ronoaldo/genai-demos/pkg/session"
+	"github.com/ronoaldo/genai-demos/pkg/text"
+	"github.com/ronoaldo/genai-demos/pkg/tuning"
+)
+
+var (
+	outDir       string
+	evalFraction float64
+	seed         string
+	model        string
+	checkOnly    bool
+	sessionNames string
+	sessionsDir  string
+	output       string
+)
+
+func init() {
+	flag.StringVar(&outDir, "out", "tuning", "`DIR` where train.jsonl and eval.jsonl are written.")
+	flag.Float64Var(&evalFraction, "eval", 0.1, "Fraction of the examples in the evaluation set. Zero disables it.")
+	flag.StringVar(&seed, "seed", "", "Changes which examples are selected for evaluation. The same seed gives the same split.")
+	flag.StringVar(&model, "model", text.ModelVersion, "The `MODEL` to be tuned, whose token limits are checked.")
+	flag.BoolVar(&checkOnly, "check", false, "Only validate the examples and print the statistics, without writing the datasets.")
+	flag.StringVar(&sessionNames, "sessions", "", "Comma separated `NAMES` of saved sessions to read the examples from.")
+	flag.StringVar(&sessionsDir, "sessions-dir", session.DefaultDir("linux-guru"), "`DIR` where the sessions are saved.")
+	flag.StringVar(&output, "o", "text", "Output format of the report: text or json.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] FILE...\n", os.Args[0])
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s submit [flags]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "Files can be .csv, .md, .jsonl or saved sessions in .json.")
+		flag.PrintDefaults()
+	}
+}
+
+// report is the result of building the datasets.
+type report struct {
+	Read       int            `json:"read"`
+	Duplicates int            `json:"duplicatesRemoved"`
+	Issues     []tuning.Issue `json:"issues,omitempty"`
+	Train      tuning.Stats   `json:"train"`
+	Eval       tuning.Stats   `json:"eval"`
+	Files      []string       `json:"files,omitempty"`
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "submit" {
+		submit(os.Args[2:])
+		return
+	}
+
+	// Parse command line options
+	flag.Parse()
+	limits, ok := text.ModelLimits[model]
+	if !ok {
+		log.Fatalf("unknown model %q", model)
+	}
+	if evalFraction < 0 || evalFraction >= 1 {
+		log.Fatalf("invalid -eval %v: use a fraction from 0 to less than 1", evalFraction)
+	}
+	if output != "text" && output != "json" {
+		log.Fatalf("invalid output format: %q", output)
+	}
+	examples, err := readExamples()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(examples) == 0 {
+		log.Fatalf("Please provide the files or the -sessions with the examples.")
+	}
+
+	// Validate the unique examples, then split them
+	r := report{Read: len(examples)}
+	examples, r.Duplicates = tuning.Dedupe(examples)
+	r.Issues = tuning.Validate(examples, limits)
+	train, eval := tuning.Split(examples, evalFraction, seed)
+	r.Train, r.Eval = tuning.NewStats(train), tuning.NewStats(eval)
+	failed := tuning.HasErrors(r.Issues)
+	if !checkOnly && !failed {
+		if r.Files, err = writeDatasets(train, eval); err != nil {
+			log.Fatalf("error writing the datasets: %v", err)
+		}
+	}
+
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(r); err != nil {
+			log.Fatalf("error formatting the output: %v", err.Error())
+		}
+	} else {
+		printReport(os.Stdout, r)
+	}
+	if failed {
+		log.Fatalf("The examples have errors; no dataset was written.")
+	}
+}
+
+// readExamples reads the files in the command line arguments and the
+// -sessions.
+func readExamples() ([]tuning.Example, error) {
+	var examples []tuning.Example
+	for _, name := range flag.Args() {
+		e, err := tuning.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		examples = append(examples, e...)
+	}
+	store := session.NewStore(sessionsDir)
+	for _, name := range strings.Split(sessionNames, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		s, err := store.Load(name)
+		if err != nil {
+			return nil, fmt.Errorf("session %s: %v", name, err)
+		}
+		examples = append(examples, tuning.FromSession(s, "session "+name)...)
+	}
+	return examples, nil
+}
+
+// writeDatasets writes the training and evaluation sets in -out,
+// returning the files written.
+func writeDatasets(train, eval []tuning.Example) ([]string, error) {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, set := range []struct {
+		name     string
+		examples []tuning.Example
+	}{{"train.jsonl", train}, {"eval.jsonl", eval}} {
+		if len(set.examples) == 0 {
+			continue
+		}
+		name := filepath.Join(outDir, set.name)
+		f, err := os.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if err = tuning.WriteJSONL(f, set.examples); err != nil {
+			f.Close()
+			return nil, err
+		}
+		if err = f.Close(); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+func printReport(w io.Writer, r report) {
+	for _, issue := range r.Issues {
+		fmt.Fprintln(w, issue)
+	}
+	if len(r.Issues) > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d examples read, %d duplicates removed.\n\n", r.Read, r.Duplicates)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SET\tEXAMPLES\tIN TOKENS (MIN/P50/P95/MAX)\tOUT TOKENS (MIN/P50/P95/MAX)\tTOTAL TOKENS\tCHARACTERS")
+	for _, set := range []struct {
+		name  string
+		stats tuning.Stats
+	}{{"train", r.Train}, {"eval", r.Eval}} {
+		in, out := set.stats.InputTokens, set.stats.OutputTokens
+		fmt.Fprintf(tw, "%s\t%d\t%d/%d/%d/%d\t%d/%d/%d/%d\t%d\t%d\n", set.name, set.stats.Examples,
+			in.Min, in.P50, in.P95, in.Max, out.Min, out.P50, out.P95, out.Max,
+			in.Total+out.Total, set.stats.BillableCharacters)
+	}
+	tw.Flush()
+	if len(r.Files) > 0 {
+		fmt.Fprintf(w, "\nWrote %s.\n", strings.Join(r.Files, " and "))
+	}
+}
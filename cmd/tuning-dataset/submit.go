@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+	"github.com/ronoaldo/genai-demos/pkg/tuning"
+)
+
+// submit starts the tuning pipeline with the datasets already uploaded to
+// Cloud Storage.
+func submit(args []string) {
+	fs := flag.NewFlagSet("submit", flag.ExitOnError)
+	var job tuning.Job
+	fs.StringVar(&job.Project, "project", os.Getenv("GOOGLE_CLOUD_PROJECT"), "The Google `PROJECT_ID` to be used.")
+	fs.StringVar(&job.Location, "location", tuning.DefaultLocation, "The `REGION` where the pipeline runs.")
+	fs.StringVar(&job.Model, "model", text.ModelVersion, "The `MODEL` to be tuned.")
+	fs.StringVar(&job.DisplayName, "name", "", "The `NAME` of the tuned model.")
+	fs.StringVar(&job.TrainingData, "train", "", "The gs:// `URI` of the training dataset.")
+	fs.StringVar(&job.EvaluationData, "eval", "", "The gs:// `URI` of the evaluation dataset, if any.")
+	fs.StringVar(&job.PipelineRoot, "pipeline-root", "", "The gs:// `URI` of the directory where the pipeline saves its artifacts.")
+	fs.IntVar(&job.TrainSteps, "train-steps", 0, "Number of tuning steps. Zero uses the pipeline default.")
+	fs.Float64Var(&job.LearningRateMultiplier, "learning-rate-multiplier", 0,
+		"Multiplier of the recommended learning rate. Zero uses the pipeline default.")
+	fs.StringVar(&job.ServiceAccount, "service-account", "", "Email of the service `ACCOUNT` that runs the pipeline.")
+	var auth text.Auth
+	fs.StringVar(&auth.CredentialsFile, "credentials", "",
+		"Service account key or other credentials JSON `FILE`, used instead of the Application Default Credentials.")
+	fs.StringVar(&auth.Impersonate, "impersonate", "", "Email of the service `ACCOUNT` to impersonate when calling the API.")
+	fs.StringVar(&auth.QuotaProject, "quota-project", "",
+		"The `PROJECT_ID` billed for the quota of the calls, instead of the project of the credentials.")
+	fs.Parse(args)
+
+	if err := auth.Validate(); err != nil {
+		log.Fatalf("invalid credentials: %v", err)
+	}
+	if _, err := job.Request(); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client, err := tuning.NewPipelineClient(ctx, job.Location, auth.ClientOptions()...)
+	if err != nil {
+		log.Fatalf("error connecting to the pipeline API: %v", err)
+	}
+	defer client.Close()
+	created, err := tuning.Submit(ctx, client, job)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Created %s (%s).\n", created.Name, created.State)
+	fmt.Printf("Follow it at https://console.cloud.google.com/vertex-ai/locations/%s/pipelines/runs/%s?project=%s\n",
+		job.Location, baseName(created.Name), job.Project)
+}
+
+// baseName returns the last part of a resource name.
+func baseName(name string) string {
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '/' {
+			return name[i+1:]
+		}
+	}
+	return name
+}
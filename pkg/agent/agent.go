@@ -0,0 +1,322 @@
+// Package agent lets the model gather information about the system
+// before answering, by requesting read-only probes like df or du.
+//
+// The text models have no native tool calling, so the model is instructed
+// to reply with a line like "PROBE: du /var" when it needs information.
+// The probe is shown to the user and, once approved, its output is added
+// to the prompt and the model is called again, until it answers or the
+// step and time budget is exhausted.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// DefaultInstructions are added before the prompt context to describe the
+// probes. The %s is replaced by the list of probes.
+var DefaultInstructions = `Você pode consultar este sistema antes de responder, usando as sondas abaixo,
+que apenas leem informações:
+
+%s
+Para usar uma sonda, responda somente com uma linha no formato:
+PROBE: <sonda> <argumentos>
+
+O resultado será enviado a você. Quando tiver informações suficientes,
+responda normalmente, sem a linha PROBE.
+
+`
+
+// Messages added to the prompt after each step.
+var (
+	// DeniedMessage is shown to the model when the user does not approve
+	// a probe.
+	DeniedMessage = "o usuário não autorizou esta sonda"
+	// BudgetMessage asks the model to answer when the budget is exhausted.
+	BudgetMessage = "Limite de sondas atingido. Responda agora com as informações coletadas, sem a linha PROBE."
+)
+
+const (
+	// DefaultMaxSteps is the default limit of probes for each question.
+	DefaultMaxSteps = 5
+	// DefaultTimeout is the default time budget to run the probes.
+	DefaultTimeout = 2 * time.Minute
+	// DefaultProbeTimeout is the default time limit of each probe.
+	DefaultProbeTimeout = 10 * time.Second
+	// maxOutputChars limits the output of each probe included in the
+	// prompt. Longer outputs keep their beginning and end.
+	maxOutputChars = 2000
+)
+
+// Step is a probe requested by the model.
+type Step struct {
+	Probe string
+	Args  []string
+	// Command is the command line run, empty if the request is invalid.
+	Command []string
+	Output  string
+	// Denied is set when the user did not approve the probe.
+	Denied bool
+	// Err is set if the request is invalid or the command failed.
+	Err error
+}
+
+// String formats the step as it is added to the prompt.
+func (s Step) String() string {
+	request := strings.TrimSpace("PROBE: " + s.Probe + " " + strings.Join(s.Args, " "))
+	switch {
+	case s.Denied:
+		return fmt.Sprintf("%s\nResultado: %s\n", request, DeniedMessage)
+	case s.Err != nil && s.Output == "":
+		return fmt.Sprintf("%s\nResultado: erro: %v\n", request, s.Err)
+	case s.Err != nil:
+		return fmt.Sprintf("%s\nResultado (erro: %v):\n```\n%s\n```\n", request, s.Err, s.Output)
+	}
+	return fmt.Sprintf("%s\nResultado:\n```\n%s\n```\n", request, s.Output)
+}
+
+// Result is the answer of the model and the probes used to produce it.
+type Result struct {
+	Response *text.Response
+	Steps    []Step
+	// Usage is the total of tokens used by all calls to the model.
+	Usage text.TokenMetadata
+	// Exhausted is set when the model was asked to answer because the
+	// step or time budget was exhausted.
+	Exhausted bool
+}
+
+// Agent calls the model in a loop, running the probes it requests.
+type Agent struct {
+	Generator text.Generator
+	Probes    []Probe
+	// Instructions describe the probes, in the format of
+	// DefaultInstructions.
+	Instructions string
+	// MaxSteps limits the number of probes for each question.
+	MaxSteps int
+	// Timeout limits the time spent running probes. The final answer is
+	// requested once it is exceeded.
+	Timeout time.Duration
+	// ProbeTimeout limits the time of each probe.
+	ProbeTimeout time.Duration
+	// Approve is called before each probe, which is skipped if it returns
+	// false. All probes are run if it is nil.
+	Approve func(ctx context.Context, s Step) (bool, error)
+	// Observe, if set, is called after each step, so that it can be
+	// shown to the user.
+	Observe func(s Step)
+	// Exec runs a command and returns its combined output. It defaults to
+	// running the command with os/exec and is replaced in tests.
+	Exec func(ctx context.Context, command []string) (string, error)
+}
+
+// New returns an Agent with the default probes and budget.
+func New(gen text.Generator) *Agent {
+	return &Agent{
+		Generator:    gen,
+		Probes:       DefaultProbes,
+		Instructions: DefaultInstructions,
+		MaxSteps:     DefaultMaxSteps,
+		Timeout:      DefaultTimeout,
+		ProbeTimeout: DefaultProbeTimeout,
+	}
+}
+
+var probePattern = regexp.MustCompile(`(?m)^\s*(?:\x60)?PROBE:\s*(\S+)([^\n\x60]*)`)
+
+// request returns the probe requested in the content, if any.
+func request(content string) (name string, args []string, ok bool) {
+	m := probePattern.FindStringSubmatch(content)
+	if m == nil {
+		return "", nil, false
+	}
+	return m[1], strings.Fields(m[2]), true
+}
+
+// Run answers the prompt, running the probes requested by the model. The
+// prompt context is used like in text.Generator, with the instructions
+// added before it and the steps added after the prompt.
+func (a *Agent) Run(ctx context.Context, promptContext, prompt string, params text.Parameters) (*Result, error) {
+	promptContext = a.instructions(promptContext)
+	res := &Result{}
+	start := time.Now()
+	var transcript strings.Builder
+	for {
+		full := prompt
+		if transcript.Len() > 0 {
+			full += "\n\n" + transcript.String()
+		}
+		resp, err := a.Generator.GenerateText(ctx, promptContext, full, params)
+		if err != nil {
+			return res, err
+		}
+		addUsage(&res.Usage, resp.Metadata)
+		res.Response = resp
+		if len(resp.Predictions) == 0 {
+			return res, fmt.Errorf("o modelo não retornou nenhuma resposta")
+		}
+		first := resp.Predictions[0]
+		name, args, ok := request(first.Content)
+		if !ok || first.SafetyAttributes.Blocked {
+			return res, nil
+		}
+		if res.Exhausted {
+			// the model insisted on a probe after the budget
+			stripRequests(resp)
+			return res, nil
+		}
+		if len(res.Steps) >= a.MaxSteps || time.Since(start) >= a.Timeout {
+			res.Exhausted = true
+			transcript.WriteString(BudgetMessage + "\n")
+			continue
+		}
+
+		step, err := a.step(ctx, name, args)
+		if err != nil {
+			return res, err
+		}
+		res.Steps = append(res.Steps, step)
+		transcript.WriteString(step.String() + "\n")
+	}
+}
+
+// step validates, approves and runs a probe. Observe is called for every
+// step, including the invalid and denied ones.
+func (a *Agent) step(ctx context.Context, name string, args []string) (Step, error) {
+	s := Step{Probe: name, Args: args}
+	if err := a.run(ctx, &s); err != nil {
+		return s, err
+	}
+	if a.Observe != nil {
+		a.Observe(s)
+	}
+	return s, nil
+}
+
+func (a *Agent) run(ctx context.Context, s *Step) error {
+	probe, ok := a.probe(s.Probe)
+	if !ok {
+		s.Err = fmt.Errorf("sonda desconhecida; use uma de: %s", strings.Join(a.names(), ", "))
+		return nil
+	}
+	if s.Command, s.Err = probe.Command(s.Args); s.Err != nil {
+		s.Command = nil
+		return nil
+	}
+	if a.Approve != nil {
+		approved, err := a.Approve(ctx, *s)
+		if err != nil {
+			return err
+		}
+		if s.Denied = !approved; s.Denied {
+			return nil
+		}
+	}
+	s.Output, s.Err = a.exec(ctx, s.Command)
+	return nil
+}
+
+func (a *Agent) probe(name string) (Probe, bool) {
+	for _, p := range a.Probes {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Probe{}, false
+}
+
+func (a *Agent) names() []string {
+	var names []string
+	for _, p := range a.Probes {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// instructions adds the list of probes before the prompt context.
+func (a *Agent) instructions(promptContext string) string {
+	var list strings.Builder
+	for _, p := range a.Probes {
+		fmt.Fprintf(&list, "- %s: %s\n", p.Usage, p.Description)
+	}
+	instructions := a.Instructions
+	if instructions == "" {
+		instructions = DefaultInstructions
+	}
+	instructions = fmt.Sprintf(instructions, list.String())
+	if !strings.Contains(promptContext, "%s") {
+		return instructions + promptContext
+	}
+	return strings.ReplaceAll(instructions, "%", "%%") + promptContext
+}
+
+// exec runs the command with the probe time limit, truncating its output.
+func (a *Agent) exec(ctx context.Context, command []string) (string, error) {
+	timeout := a.ProbeTimeout
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	run := a.Exec
+	if run == nil {
+		run = execCommand
+	}
+	out, err := run(ctx, command)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("tempo limite de %v excedido", timeout)
+	}
+	return truncate(strings.TrimSpace(out), maxOutputChars), err
+}
+
+func execCommand(ctx context.Context, command []string) (string, error) {
+	out, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput()
+	return string(out), err
+}
+
+// truncate keeps the beginning and the end of long outputs, cutting at
+// line or rune boundaries.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut, from := max/2, len(s)-max/2
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	for from < len(s) && !utf8.RuneStart(s[from]) {
+		from++
+	}
+	head, tail := s[:cut], s[from:]
+	if i := strings.LastIndex(head, "\n"); i > 0 {
+		head = head[:i]
+	}
+	if i := strings.Index(tail, "\n"); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return head + "\n[...]\n" + tail
+}
+
+// stripRequests removes the probe requests from the predictions.
+func stripRequests(resp *text.Response) {
+	for i := range resp.Predictions {
+		p := &resp.Predictions[i]
+		p.Content = strings.TrimSpace(probePattern.ReplaceAllString(p.Content, ""))
+	}
+}
+
+func addUsage(total *text.TokenMetadata, u text.TokenMetadata) {
+	total.InputTokenCount.TotalTokens += u.InputTokenCount.TotalTokens
+	total.InputTokenCount.TotalBillableCharacters += u.InputTokenCount.TotalBillableCharacters
+	total.OutputTokenCount.TotalTokens += u.OutputTokenCount.TotalTokens
+	total.OutputTokenCount.TotalBillableCharacters += u.OutputTokenCount.TotalBillableCharacters
+}
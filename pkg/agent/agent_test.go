@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+	"github.com/ronoaldo/genai-demos/pkg/text/texttest"
+)
+
+// fakeExec records the commands and returns their name as output.
+type fakeExec struct {
+	commands [][]string
+}
+
+func (f *fakeExec) exec(ctx context.Context, command []string) (string, error) {
+	f.commands = append(f.commands, command)
+	return "output of " + command[0] + "\n", nil
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		replies  []string
+		approve  bool
+		maxSteps int
+		commands [][]string
+		steps    int
+		answer   string
+		// last is a text expected in the last prompt
+		last      string
+		exhausted bool
+	}{
+		{
+			name:    "no probes",
+			replies: []string{"Use o comando df -h."},
+			approve: true, maxSteps: 5,
+			answer: "Use o comando df -h.",
+		},
+		{
+			name:    "probes",
+			replies: []string{"PROBE: df", "PROBE: du /var", "O diretório /var/log ocupa mais espaço."},
+			approve: true, maxSteps: 5,
+			commands: [][]string{{"df", "-h"}, {"du", "-h", "-x", "--max-depth=1", "--", "/var"}},
+			steps:    2,
+			answer:   "O diretório /var/log ocupa mais espaço.",
+			last:     "PROBE: du /var\nResultado:\n```\noutput of du\n```",
+		},
+		{
+			name:    "denied",
+			replies: []string{"PROBE: ss", "Não foi possível verificar as portas."},
+			approve: false, maxSteps: 5,
+			steps:  1,
+			answer: "Não foi possível verificar as portas.",
+			last:   DeniedMessage,
+		},
+		{
+			name:    "invalid",
+			replies: []string{"PROBE: rm -rf /", "PROBE: du --files0-from=/etc/shadow", "Desculpe."},
+			approve: true, maxSteps: 5,
+			steps:  2,
+			answer: "Desculpe.",
+			last:   "PROBE: rm -rf /\nResultado: erro: sonda desconhecida",
+		},
+		{
+			name:    "budget",
+			replies: []string{"Vou verificar.\nPROBE: df"},
+			approve: true, maxSteps: 2,
+			commands:  [][]string{{"df", "-h"}, {"df", "-h"}},
+			steps:     2,
+			answer:    "Vou verificar.",
+			last:      BudgetMessage,
+			exhausted: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gen := &texttest.Generator{Replies: tc.replies}
+			gen.Metadata.OutputTokenCount.TotalTokens = 10
+			run := &fakeExec{}
+			a := New(gen)
+			a.MaxSteps = tc.maxSteps
+			a.Exec = run.exec
+			a.Approve = func(ctx context.Context, s Step) (bool, error) {
+				return tc.approve, nil
+			}
+			var observed int
+			a.Observe = func(s Step) { observed++ }
+
+			res, err := a.Run(context.Background(), "Pergunta: %s\nResposta: ", "Por que o disco está cheio?", text.DefaultParameters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.Response.Predictions[0].Content; got != tc.answer {
+				t.Errorf("answer = %q, want %q", got, tc.answer)
+			}
+			if !reflect.DeepEqual(run.commands, tc.commands) {
+				t.Errorf("commands = %q, want %q", run.commands, tc.commands)
+			}
+			if len(res.Steps) != tc.steps || observed != tc.steps {
+				t.Errorf("got %d steps and %d observed, want %d", len(res.Steps), observed, tc.steps)
+			}
+			if res.Exhausted != tc.exhausted {
+				t.Errorf("Exhausted = %v, want %v", res.Exhausted, tc.exhausted)
+			}
+			if want := 10 * len(gen.Calls()); res.Usage.OutputTokenCount.TotalTokens != want {
+				t.Errorf("usage = %d tokens, want %d", res.Usage.OutputTokenCount.TotalTokens, want)
+			}
+			last := gen.Last()
+			if !strings.HasPrefix(last.Prompt, "Por que o disco está cheio?") || !strings.Contains(last.Prompt, tc.last) {
+				t.Errorf("last prompt = %q, want it to contain %q", last.Prompt, tc.last)
+			}
+			if !strings.Contains(last.PromptContext, "- du PATH: ") || !strings.HasSuffix(last.PromptContext, "Pergunta: %s\nResposta: ") {
+				t.Errorf("prompt context without the probes: %q", last.PromptContext)
+			}
+		})
+	}
+}
+
+func TestRunApproveError(t *testing.T) {
+	gen := &texttest.Generator{Replies: []string{"PROBE: df"}}
+	run := &fakeExec{}
+	a := New(gen)
+	a.Exec = run.exec
+	errCanceled := errors.New("canceled")
+	a.Approve = func(ctx context.Context, s Step) (bool, error) {
+		return false, errCanceled
+	}
+	if _, err := a.Run(context.Background(), "", "Quais portas estão abertas?", text.DefaultParameters); !errors.Is(err, errCanceled) {
+		t.Errorf("Run() error = %v, want %v", err, errCanceled)
+	}
+	if len(run.commands) != 0 {
+		t.Errorf("commands run without approval: %q", run.commands)
+	}
+}
+
+func TestRunNoPredictions(t *testing.T) {
+	a := New(&texttest.Generator{})
+	if _, err := a.Run(context.Background(), "", "Quais portas estão abertas?", text.DefaultParameters); err == nil {
+		t.Errorf("Run() without predictions returned no error")
+	}
+}
+
+func TestProbeTimeout(t *testing.T) {
+	a := New(nil)
+	a.ProbeTimeout = 10 * time.Millisecond
+	a.Exec = func(ctx context.Context, command []string) (string, error) {
+		<-ctx.Done()
+		return "partial", ctx.Err()
+	}
+	out, err := a.exec(context.Background(), []string{"du", "/"})
+	if out != "partial" || err == nil || !strings.Contains(err.Error(), "tempo limite") {
+		t.Errorf("exec() = %q, %v", out, err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	long := strings.Repeat("linha do início\n", 100) + strings.Repeat("linha do fim\n", 100)
+	got := truncate(long, 200)
+	if len(got) > 200+len("\n[...]\n") {
+		t.Errorf("truncate() returned %d bytes", len(got))
+	}
+	if !strings.HasPrefix(got, "linha do início\n") || !strings.HasSuffix(got, "linha do fim\n") || !strings.Contains(got, "[...]") {
+		t.Errorf("truncate() = %q", got)
+	}
+	if got := truncate(strings.Repeat("ção", 100), 101); !utf8.ValidString(got) {
+		t.Errorf("truncate() split a character: %q", got)
+	}
+	if got := truncate("curta", 200); got != "curta" {
+		t.Errorf("truncate() = %q, want %q", got, "curta")
+	}
+}
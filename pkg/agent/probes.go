@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Probe is a read-only command that the model may request to learn about
+// the system.
+type Probe struct {
+	Name string
+	// Usage shows the arguments accepted, like "du PATH".
+	Usage       string
+	Description string
+	// Command validates the arguments requested by the model and returns
+	// the command line to run. It is never run by a shell.
+	Command func(args []string) ([]string, error)
+}
+
+// ErrInvalidArgs is returned when the arguments of a probe are not
+// accepted.
+var ErrInvalidArgs = errors.New("agent: invalid arguments")
+
+var unitPattern = regexp.MustCompile(`^[\w@.:\\-]+$`)
+
+// checkPath accepts a single path that is not an option and has no
+// control characters.
+func checkPath(p string) error {
+	if p == "" || strings.HasPrefix(p, "-") || strings.ContainsAny(p, "\x00\n\r") {
+		return fmt.Errorf("%w: invalid path %q", ErrInvalidArgs, p)
+	}
+	return nil
+}
+
+// DefaultProbes are the probes available to the model: they only read
+// information about disks, services and network ports.
+var DefaultProbes = []Probe{
+	{
+		Name:        "df",
+		Usage:       "df [PATH]",
+		Description: "espaço livre e usado dos sistemas de arquivos, ou do que contém PATH",
+		Command: func(args []string) ([]string, error) {
+			switch len(args) {
+			case 0:
+				return []string{"df", "-h"}, nil
+			case 1:
+				if err := checkPath(args[0]); err != nil {
+					return nil, err
+				}
+				return []string{"df", "-h", "--", args[0]}, nil
+			}
+			return nil, fmt.Errorf("%w: use no arguments or a single path", ErrInvalidArgs)
+		},
+	},
+	{
+		Name:        "du",
+		Usage:       "du PATH",
+		Description: "tamanho de PATH e dos seus subdiretórios imediatos",
+		Command: func(args []string) ([]string, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("%w: use a single path", ErrInvalidArgs)
+			}
+			if err := checkPath(args[0]); err != nil {
+				return nil, err
+			}
+			return []string{"du", "-h", "-x", "--max-depth=1", "--", args[0]}, nil
+		},
+	},
+	{
+		Name:        "systemctl-status",
+		Usage:       "systemctl-status UNIT",
+		Description: "estado e últimas mensagens de um serviço do systemd",
+		Command: func(args []string) ([]string, error) {
+			if len(args) != 1 || !unitPattern.MatchString(args[0]) || strings.HasPrefix(args[0], "-") {
+				return nil, fmt.Errorf("%w: use a single unit name", ErrInvalidArgs)
+			}
+			return []string{"systemctl", "status", "--no-pager", "--lines=20", "--", args[0]}, nil
+		},
+	},
+	{
+		Name:        "ss",
+		Usage:       "ss",
+		Description: "portas TCP abertas para conexões e os processos que as usam",
+		Command: func(args []string) ([]string, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("%w: no arguments are accepted", ErrInvalidArgs)
+			}
+			return []string{"ss", "-tlnp"}, nil
+		},
+	},
+}
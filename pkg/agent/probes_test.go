@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDefaultProbes(t *testing.T) {
+	tests := []struct {
+		probe string
+		args  []string
+		want  []string
+	}{
+		{"df", nil, []string{"df", "-h"}},
+		{"df", []string{"/home"}, []string{"df", "-h", "--", "/home"}},
+		{"df", []string{"-a"}, nil},
+		{"df", []string{"/", "/home"}, nil},
+		{"du", []string{"/var"}, []string{"du", "-h", "-x", "--max-depth=1", "--", "/var"}},
+		{"du", nil, nil},
+		{"du", []string{"--files0-from=/etc/shadow"}, nil},
+		{"systemctl-status", []string{"nginx.service"}, []string{"systemctl", "status", "--no-pager", "--lines=20", "--", "nginx.service"}},
+		{"systemctl-status", []string{"getty@tty1.service"}, []string{"systemctl", "status", "--no-pager", "--lines=20", "--", "getty@tty1.service"}},
+		{"systemctl-status", []string{"--force"}, nil},
+		{"systemctl-status", []string{"nginx;reboot"}, nil},
+		{"ss", nil, []string{"ss", "-tlnp"}},
+		{"ss", []string{"-K"}, nil},
+	}
+	a := New(nil)
+	for _, tc := range tests {
+		p, ok := a.probe(tc.probe)
+		if !ok {
+			t.Fatalf("probe %q not found", tc.probe)
+		}
+		got, err := p.Command(tc.args)
+		if tc.want == nil {
+			if !errors.Is(err, ErrInvalidArgs) {
+				t.Errorf("%s %q: error = %v, want %v", tc.probe, tc.args, err, ErrInvalidArgs)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s %q = %q, %v, want %q", tc.probe, tc.args, got, err, tc.want)
+		}
+	}
+}
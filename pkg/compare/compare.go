@@ -0,0 +1,247 @@
+// Package compare runs the same set of prompts under different model
+// parameters and reports how the generated outputs differ, helping to
+// choose parameter presets with evidence instead of intuition.
+package compare
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// Config is a named set of parameters to be compared.
+type Config struct {
+	Name   string          `json:"name"`
+	Params text.Parameters `json:"params"`
+}
+
+// Presets returns one Config for each of the named presets in text.Presets.
+func Presets(names ...string) ([]Config, error) {
+	configs := make([]Config, 0, len(names))
+	for _, name := range names {
+		params, ok := text.Presets[name]
+		if !ok {
+			return nil, fmt.Errorf("compare: unknown preset %q (available: %s)",
+				name, strings.Join(text.PresetNames(), ", "))
+		}
+		configs = append(configs, Config{Name: name, Params: params})
+	}
+	return configs, nil
+}
+
+// Grid expands all combinations of the provided temperatures, top-k and
+// top-p values into a list of Configs, using base for the remaining
+// parameters. Empty slices keep the value from base.
+func Grid(base text.Parameters, temperatures []float64, topKs []int, topPs []float64) []Config {
+	if len(temperatures) == 0 {
+		temperatures = []float64{base.Temperature}
+	}
+	if len(topKs) == 0 {
+		topKs = []int{base.TopK}
+	}
+	if len(topPs) == 0 {
+		topPs = []float64{base.TopP}
+	}
+	var configs []Config
+	for _, temp := range temperatures {
+		for _, topK := range topKs {
+			for _, topP := range topPs {
+				p := base
+				p.Temperature, p.TopK, p.TopP = temp, topK, topP
+				configs = append(configs, Config{
+					Name:   fmt.Sprintf("t=%g,k=%d,p=%g", temp, topK, topP),
+					Params: p,
+				})
+			}
+		}
+	}
+	return configs
+}
+
+// Output is a single generated output for a prompt and Config.
+type Output struct {
+	Content      string `json:"content"`
+	InputTokens  int    `json:"inputTokens"`
+	OutputTokens int    `json:"outputTokens"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Stats summarizes the outputs generated for a prompt and Config.
+type Stats struct {
+	Runs             int     `json:"runs"`
+	Errors           int     `json:"errors"`
+	Distinct         int     `json:"distinct"`
+	Variance         float64 `json:"variance"`
+	MeanLength       float64 `json:"meanLength"`
+	StdDevLength     float64 `json:"stdDevLength"`
+	MeanInputTokens  float64 `json:"meanInputTokens"`
+	MeanOutputTokens float64 `json:"meanOutputTokens"`
+}
+
+// Result holds all the outputs of a prompt and Config, and their Stats.
+type Result struct {
+	Prompt  string   `json:"prompt"`
+	Config  string   `json:"config"`
+	Outputs []Output `json:"outputs"`
+	Stats   Stats    `json:"stats"`
+}
+
+// Report is the result of a comparison run.
+type Report struct {
+	Prompts []string `json:"prompts"`
+	Configs []Config `json:"configs"`
+	Repeat  int      `json:"repeat"`
+	Results []Result `json:"results"`
+}
+
+// Result returns the result for the given prompt and config name, or nil
+// if it was not part of the report.
+func (r *Report) Result(prompt, config string) *Result {
+	for i := range r.Results {
+		if r.Results[i].Prompt == prompt && r.Results[i].Config == config {
+			return &r.Results[i]
+		}
+	}
+	return nil
+}
+
+// Runner executes comparisons using a text.Generator.
+type Runner struct {
+	Generator     text.Generator
+	PromptContext string
+
+	// Progress, if not nil, is called after each generation call.
+	Progress func(prompt string, config Config, attempt int, err error)
+}
+
+// Run generates `repeat` outputs for each prompt under each Config.
+// Errors in individual calls are recorded in the outputs instead of
+// aborting the run, unless the context is canceled.
+func (r *Runner) Run(ctx context.Context, prompts []string, configs []Config, repeat int) (*Report, error) {
+	if repeat < 1 {
+		repeat = 1
+	}
+	report := &Report{Prompts: prompts, Configs: configs, Repeat: repeat}
+	for _, prompt := range prompts {
+		for _, config := range configs {
+			res := Result{Prompt: prompt, Config: config.Name}
+			for i := 0; i < repeat; i++ {
+				if err := ctx.Err(); err != nil {
+					return report, err
+				}
+				out := Output{}
+				resp, err := r.Generator.GenerateText(ctx, r.PromptContext, prompt, config.Params)
+				switch {
+				case err != nil:
+					out.Error = err.Error()
+				case len(resp.Predictions) == 0:
+					out.Error = "no predictions returned"
+				default:
+					out.Content = resp.Predictions[0].Content
+					out.InputTokens = resp.Metadata.InputTokenCount.TotalTokens
+					out.OutputTokens = resp.Metadata.OutputTokenCount.TotalTokens
+				}
+				if r.Progress != nil {
+					r.Progress(prompt, config, i, err)
+				}
+				res.Outputs = append(res.Outputs, out)
+			}
+			res.Stats = Summarize(res.Outputs)
+			report.Results = append(report.Results, res)
+		}
+	}
+	return report, nil
+}
+
+// Summarize computes the Stats for a list of outputs. Failed outputs are
+// only counted as errors.
+func Summarize(outputs []Output) Stats {
+	s := Stats{Runs: len(outputs)}
+	var contents []string
+	var inTokens, outTokens float64
+	for _, o := range outputs {
+		if o.Error != "" {
+			s.Errors++
+			continue
+		}
+		contents = append(contents, o.Content)
+		inTokens += float64(o.InputTokens)
+		outTokens += float64(o.OutputTokens)
+	}
+	n := float64(len(contents))
+	if n == 0 {
+		return s
+	}
+	s.MeanInputTokens = inTokens / n
+	s.MeanOutputTokens = outTokens / n
+
+	distinct := make(map[string]bool)
+	var sum float64
+	for _, c := range contents {
+		distinct[normalize(c)] = true
+		sum += float64(len(c))
+	}
+	s.Distinct = len(distinct)
+	s.MeanLength = sum / n
+	var sq float64
+	for _, c := range contents {
+		d := float64(len(c)) - s.MeanLength
+		sq += d * d
+	}
+	s.StdDevLength = math.Sqrt(sq / n)
+	s.Variance = Variance(contents)
+	return s
+}
+
+// Variance measures how different the outputs are from each other, as the
+// mean pairwise Jaccard distance between their sets of words. It is zero
+// when all outputs use the same words and one when they share none.
+func Variance(outputs []string) float64 {
+	if len(outputs) < 2 {
+		return 0
+	}
+	sets := make([]map[string]bool, len(outputs))
+	for i, o := range outputs {
+		sets[i] = wordSet(o)
+	}
+	var sum float64
+	var pairs int
+	for i := 0; i < len(sets); i++ {
+		for j := i + 1; j < len(sets); j++ {
+			sum += 1 - jaccard(sets[i], sets[j])
+			pairs++
+		}
+	}
+	return sum / float64(pairs)
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+func wordSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(strings.ToLower(s)) {
+		w = strings.Trim(w, ".,;:!?\"'()[]{}*`")
+		if w != "" {
+			set[w] = true
+		}
+	}
+	return set
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	var inter int
+	for w := range a {
+		if b[w] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
@@ -0,0 +1,141 @@
+package compare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+	"github.com/ronoaldo/genai-demos/pkg/text/texttest"
+)
+
+// byTemperature returns a Generator that replies with the outputs in
+// sequence for each temperature.
+func byTemperature(outputs map[float64][]string) *texttest.Generator {
+	g := &texttest.Generator{}
+	g.Respond = func(c texttest.Call) (*text.Response, error) {
+		i := -1
+		for _, prev := range g.Calls() {
+			if prev.Params.Temperature == c.Params.Temperature {
+				i++
+			}
+		}
+		temperatureOutputs := outputs[c.Params.Temperature]
+		if i >= len(temperatureOutputs) {
+			return nil, errors.New("no more outputs")
+		}
+		resp := texttest.Reply(temperatureOutputs[i])
+		resp.Metadata.InputTokenCount.TotalTokens = len(c.Prompt)
+		resp.Metadata.OutputTokenCount.TotalTokens = len(temperatureOutputs[i])
+		return resp, nil
+	}
+	return g
+}
+
+func TestRunner(t *testing.T) {
+	gen := byTemperature(map[float64][]string{
+		0.0: {"same answer", "same answer", "same answer"},
+		1.0: {"one answer", "another reply", "one answer"},
+	})
+	r := Runner{Generator: gen}
+	configs := []Config{
+		{Name: "cold", Params: text.Parameters{Temperature: 0.0}},
+		{Name: "hot", Params: text.Parameters{Temperature: 1.0}},
+	}
+	report, err := r.Run(context.Background(), []string{"prompt"}, configs, 3)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	tests := []struct {
+		config       string
+		wantDistinct int
+		wantVariance bool
+		wantTokens   float64
+	}{
+		{"cold", 1, false, 11},
+		{"hot", 2, true, (10 + 13 + 10) / 3.0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.config, func(t *testing.T) {
+			res := report.Result("prompt", tc.config)
+			if res == nil {
+				t.Fatalf("missing result for %v", tc.config)
+			}
+			if res.Stats.Runs != 3 || res.Stats.Errors != 0 {
+				t.Errorf("got %d runs and %d errors, want 3 and 0", res.Stats.Runs, res.Stats.Errors)
+			}
+			if res.Stats.Distinct != tc.wantDistinct {
+				t.Errorf("got %d distinct outputs, want %d", res.Stats.Distinct, tc.wantDistinct)
+			}
+			if (res.Stats.Variance > 0) != tc.wantVariance {
+				t.Errorf("got variance %v, want non-zero %v", res.Stats.Variance, tc.wantVariance)
+			}
+			if res.Stats.MeanOutputTokens != tc.wantTokens {
+				t.Errorf("got %v mean output tokens, want %v", res.Stats.MeanOutputTokens, tc.wantTokens)
+			}
+		})
+	}
+}
+
+func TestRunnerErrors(t *testing.T) {
+	gen := byTemperature(map[float64][]string{0.0: {"only one"}})
+	r := Runner{Generator: gen}
+	report, err := r.Run(context.Background(), []string{"p"}, []Config{{Name: "c"}}, 2)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := report.Results[0].Stats.Errors; got != 1 {
+		t.Errorf("got %d errors, want 1", got)
+	}
+}
+
+func TestGrid(t *testing.T) {
+	configs := Grid(text.DefaultParameters, []float64{0, 0.5, 1}, []int{1, 40}, nil)
+	if len(configs) != 6 {
+		t.Fatalf("got %d configs, want 6", len(configs))
+	}
+	for _, c := range configs {
+		if c.Params.TopP != text.DefaultParameters.TopP {
+			t.Errorf("%v: got topP %v, want %v", c.Name, c.Params.TopP, text.DefaultParameters.TopP)
+		}
+	}
+	if got, want := configs[1].Name, "t=0,k=40,p=0.8"; got != want {
+		t.Errorf("got name %q, want %q", got, want)
+	}
+}
+
+func TestPresets(t *testing.T) {
+	if _, err := Presets("default", "creative"); err != nil {
+		t.Errorf("Presets() error = %v", err)
+	}
+	if _, err := Presets("unknown"); err == nil {
+		t.Errorf("Presets(unknown) expected error")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb", "a\nb", " a b"},
+		{"insert", "a\nc", "a\nb\nc", " a+b c"},
+		{"delete", "a\nb\nc", "a\nc", " a-b c"},
+		{"change", "a\nb", "a\nx", " a-b+x"},
+		{"empty", "", "a", "+a"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ""
+			for _, l := range Diff(tc.a, tc.b) {
+				got += fmt.Sprintf("%c%s", l.Op, l.Text)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
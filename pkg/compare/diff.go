@@ -0,0 +1,137 @@
+package compare
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// DiffOp identifies the kind of change in a DiffLine.
+type DiffOp byte
+
+// Kinds of lines returned by Diff.
+const (
+	Equal  DiffOp = ' '
+	Delete DiffOp = '-'
+	Insert DiffOp = '+'
+)
+
+// DiffLine is a single line of a line-based diff.
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// Diff returns the line-based difference between a and b, computed from
+// their longest common subsequence of lines.
+func Diff(a, b string) []DiffLine {
+	x, y := splitLines(a), splitLines(b)
+	// lcs[i][j] is the length of the LCS between x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var diff []DiffLine
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			diff = append(diff, DiffLine{Equal, x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Delete, x[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Insert, y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		diff = append(diff, DiffLine{Delete, x[i]})
+	}
+	for ; j < len(y); j++ {
+		diff = append(diff, DiffLine{Insert, y[j]})
+	}
+	return diff
+}
+
+// SideBySide renders a and b in two columns of the given width, marking
+// the lines that differ between them. Long lines are wrapped at word
+// boundaries before comparing.
+func SideBySide(a, b string, width int) string {
+	if width < 10 {
+		width = 10
+	}
+	var sb strings.Builder
+	row := func(l, mark, r string) {
+		l = truncate(l, width)
+		pad := strings.Repeat(" ", width-utf8.RuneCountInString(l))
+		fmt.Fprintf(&sb, "%s%s %s %s\n", l, pad, mark, truncate(r, width))
+	}
+	diff := Diff(wrap(a, width), wrap(b, width))
+	for k := 0; k < len(diff); k++ {
+		d := diff[k]
+		switch d.Op {
+		case Equal:
+			row(d.Text, "|", d.Text)
+		case Delete:
+			// Pair a deletion with the following insertion as a change.
+			if k+1 < len(diff) && diff[k+1].Op == Insert {
+				row(d.Text, "*", diff[k+1].Text)
+				k++
+			} else {
+				row(d.Text, "<", "")
+			}
+		case Insert:
+			row("", ">", d.Text)
+		}
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func wrap(s string, width int) string {
+	var sb strings.Builder
+	for _, line := range splitLines(s) {
+		n := 0
+		for i, w := range strings.Fields(line) {
+			l := utf8.RuneCountInString(w)
+			if i > 0 && n+1+l > width {
+				sb.WriteString("\n")
+				n = 0
+			} else if i > 0 {
+				sb.WriteString(" ")
+				n++
+			}
+			sb.WriteString(w)
+			n += l
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	return string(r[:width-1]) + "…"
+}
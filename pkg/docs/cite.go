@@ -0,0 +1,85 @@
+package docs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// FormatPassages formats the results to be included in a prompt, each one
+// numbered from 1 and followed by its title, so that the answer can cite
+// them with markers like [1].
+func FormatPassages(results []Result) string {
+	var b strings.Builder
+	for i, r := range results {
+		fmt.Fprintf(&b, "[%d] %s\n%s\n\n", i+1, r.Title, r.Text)
+	}
+	return b.String()
+}
+
+var markerPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// Citations maps the [n] markers in the answer to the results included in
+// the prompt with FormatPassages. The StartIndex and EndIndex of each
+// citation are the byte offsets of the sentence or line that ends with the
+// marker.
+//
+// Answers without markers are mapped to the results about the commands
+// they use, like the man page of tar for a line with "tar -czf", citing
+// the first line where each command appears.
+func Citations(answer string, results []Result) []text.Citation {
+	var citations []text.Citation
+	for _, m := range markerPattern.FindAllStringSubmatchIndex(answer, -1) {
+		n, _ := strconv.Atoi(answer[m[2]:m[3]])
+		if n < 1 || n > len(results) {
+			continue
+		}
+		citations = append(citations, citation(results[n-1], n, sentenceStart(answer, m[0]), m[1]))
+	}
+	if len(citations) > 0 {
+		return citations
+	}
+
+	cited := make(map[string]bool)
+	for i, r := range results {
+		if r.Name == "" || cited[r.Name] {
+			continue
+		}
+		pattern := regexp.MustCompile(`(?m)(?:^|[\s` + "`" + `$(|;&])(` + regexp.QuoteMeta(r.Name) + `)(?:[\s.,;:)!?]|$)`)
+		m := pattern.FindStringSubmatchIndex(answer)
+		if m == nil {
+			continue
+		}
+		cited[r.Name] = true
+		start, end := strings.LastIndex(answer[:m[2]], "\n")+1, len(answer)
+		if nl := strings.Index(answer[m[2]:], "\n"); nl >= 0 {
+			end = m[2] + nl
+		}
+		citations = append(citations, citation(r, i+1, start, end))
+	}
+	return citations
+}
+
+func citation(r Result, n, start, end int) text.Citation {
+	return text.Citation{
+		StartIndex: start,
+		EndIndex:   end,
+		URL:        r.URL,
+		Title:      fmt.Sprintf("[%d] %s", n, r.Title),
+	}
+}
+
+// sentenceStart returns the offset of the start of the sentence or line
+// that contains the offset.
+func sentenceStart(s string, offset int) int {
+	start := 0
+	for _, sep := range []string{". ", "\n", "? ", "! ", "] "} {
+		if i := strings.LastIndex(s[:offset], sep); i >= 0 && i+len(sep) > start {
+			start = i + len(sep)
+		}
+	}
+	return start
+}
@@ -0,0 +1,54 @@
+package docs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+var citeResults = []Result{
+	{Passage: Passage{Name: "tar", Title: "tar(1), OPTIONS", URL: "man:tar(1)", Text: "-z, --gzip\nfilter through gzip"}},
+	{Passage: Passage{Name: "gzip", Title: "gzip(1), NAME", URL: "man:gzip(1)", Text: "gzip - compress files"}},
+}
+
+func TestFormatPassages(t *testing.T) {
+	want := "[1] tar(1), OPTIONS\n-z, --gzip\nfilter through gzip\n\n[2] gzip(1), NAME\ngzip - compress files\n\n"
+	if got := FormatPassages(citeResults); got != want {
+		t.Errorf("FormatPassages() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestCitations(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer string
+		want   []text.Citation
+	}{
+		{
+			"markers",
+			"Use a opção -z [1]. O gzip compacta arquivos [2] [7].",
+			[]text.Citation{
+				{StartIndex: 0, EndIndex: 20, URL: "man:tar(1)", Title: "[1] tar(1), OPTIONS"},
+				{StartIndex: 22, EndIndex: 50, URL: "man:gzip(1)", Title: "[2] gzip(1), NAME"},
+			},
+		},
+		{
+			"commands",
+			"Para compactar:\n\n    tar -czf backup.tar.gz ~\n\nO tar usa o gzip.",
+			[]text.Citation{
+				{StartIndex: 17, EndIndex: 45, URL: "man:tar(1)", Title: "[1] tar(1), OPTIONS"},
+				{StartIndex: 47, EndIndex: 64, URL: "man:gzip(1)", Title: "[2] gzip(1), NAME"},
+			},
+		},
+		{"none", "Não sei sobre este tema.", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Citations(tc.answer, citeResults)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Citations() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Package docs indexes the documentation available locally, like the man
+// pages, /usr/share/doc and directories of Markdown files, so that the
+// passages relevant to a question can be included in the prompt and cited
+// in the answer.
+//
+// Documents are split in passages of a few paragraphs, and each passage
+// is stored in the index with its embedding. Questions are answered by
+// the passages with the most similar embeddings.
+package docs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Document is a documentation file split in sections.
+type Document struct {
+	// Path is the file the document was read from.
+	Path string
+	// Name is the command or package the document is about, like "tar",
+	// used to favor its passages when the name is in the question.
+	Name string
+	// Title identifies the document in the citations, like "tar(1)".
+	Title string
+	// URL is the link to the document, like "man:tar(1)".
+	URL      string
+	Sections []Section
+}
+
+// Section is a part of the document under a heading.
+type Section struct {
+	Heading string
+	// Paragraphs are separated by blank lines.
+	Text string
+}
+
+// Passage is a part of a document that is indexed and included in the
+// prompts.
+type Passage struct {
+	Path  string
+	Name  string
+	Title string
+	URL   string
+	Text  string
+	// Vector is the normalized embedding of the passage.
+	Vector []float32
+}
+
+// manPattern matches the file names of man pages, like tar.1.gz.
+var manPattern = regexp.MustCompile(`^(.+)\.(\d\w*)(\.gz)?$`)
+
+// Read parses the file as a man page, if it is in a man directory, as
+// Markdown, if it has the .md extension, or as plain text otherwise.
+// Files compressed with gzip are decompressed.
+func Read(path string) (*Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = bufio.NewReader(f)
+	base := filepath.Base(path)
+	if strings.HasSuffix(base, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+		base = strings.TrimSuffix(base, ".gz")
+	}
+	b, err := io.ReadAll(io.LimitReader(r, maxFileSize))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Path: path, URL: "file://" + path}
+	ext := strings.ToLower(filepath.Ext(base))
+	switch {
+	case isManPage(path):
+		m := manPattern.FindStringSubmatch(filepath.Base(path))
+		doc.Name, doc.Title, doc.URL = m[1], m[1]+"("+m[2]+")", "man:"+m[1]+"("+m[2]+")"
+		doc.Sections = parseRoff(string(b))
+	case ext == ".md" || ext == ".markdown":
+		doc.Name, doc.Title = docName(path), strings.TrimSuffix(base, filepath.Ext(base))
+		doc.Sections = parseMarkdown(string(b))
+	default:
+		doc.Name, doc.Title = docName(path), base
+		doc.Sections = []Section{{Text: string(b)}}
+	}
+	if pkg := docPackage(path); pkg != "" && !isManPage(path) {
+		doc.Title = pkg + "/" + doc.Title
+	}
+	return doc, nil
+}
+
+// isManPage reports whether the file is in a man section directory, like
+// /usr/share/man/man1.
+func isManPage(path string) bool {
+	return strings.HasPrefix(filepath.Base(filepath.Dir(path)), "man") && manPattern.MatchString(filepath.Base(path))
+}
+
+// docPackage returns the package of files in /usr/share/doc.
+func docPackage(path string) string {
+	rel, err := filepath.Rel(DocDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	pkg, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	return pkg
+}
+
+// docName returns the name used to favor the document: the package of
+// files in /usr/share/doc, or the file name without extensions.
+func docName(path string) string {
+	if pkg := docPackage(path); pkg != "" {
+		return pkg
+	}
+	name := filepath.Base(path)
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i]
+	}
+	return strings.ToLower(name)
+}
+
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+
+// parseMarkdown splits the Markdown in sections at each heading.
+func parseMarkdown(md string) []Section {
+	var sections []Section
+	cur := Section{}
+	var text []string
+	inCode := false
+	for _, line := range strings.Split(md, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+		}
+		if m := headingPattern.FindStringSubmatch(line); m != nil && !inCode {
+			cur.Text = strings.Join(text, "\n")
+			sections = append(sections, cur)
+			cur, text = Section{Heading: m[2]}, nil
+			continue
+		}
+		text = append(text, line)
+	}
+	cur.Text = strings.Join(text, "\n")
+	return append(sections, cur)
+}
+
+// Split breaks the document in passages of at most maxChars, joining the
+// paragraphs of each section. Longer paragraphs are split between lines
+// or words.
+func Split(doc *Document, maxChars int) []Passage {
+	var passages []Passage
+	for _, s := range doc.Sections {
+		title := doc.Title
+		if s.Heading != "" {
+			title += ", " + s.Heading
+		}
+		var b strings.Builder
+		flush := func() {
+			if text := strings.TrimSpace(b.String()); text != "" {
+				passages = append(passages, Passage{Path: doc.Path, Name: doc.Name, Title: title, URL: doc.URL, Text: text})
+			}
+			b.Reset()
+		}
+		for _, para := range paragraphs(s.Text, maxChars) {
+			if b.Len() > 0 && b.Len()+2+len(para) > maxChars {
+				flush()
+			}
+			if b.Len() > 0 {
+				b.WriteString("\n\n")
+			}
+			b.WriteString(para)
+		}
+		flush()
+	}
+	return passages
+}
+
+var blankLinePattern = regexp.MustCompile(`\n\s*\n`)
+
+// paragraphs returns the non empty paragraphs of the text, splitting the
+// ones longer than maxChars.
+func paragraphs(text string, maxChars int) []string {
+	var paras []string
+	for _, p := range blankLinePattern.Split(text, -1) {
+		p = strings.TrimRight(strings.TrimLeft(p, "\n"), " \t\n")
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		for len(p) > maxChars {
+			cut := strings.LastIndex(p[:maxChars], "\n")
+			if cut <= 0 {
+				cut = strings.LastIndex(p[:maxChars], " ")
+			}
+			if cut <= 0 {
+				for cut = maxChars; cut > 0 && !utf8.RuneStart(p[cut]); cut-- {
+				}
+			}
+			paras = append(paras, strings.TrimSpace(p[:cut]))
+			p = strings.TrimSpace(p[cut:])
+		}
+		if p != "" {
+			paras = append(paras, p)
+		}
+	}
+	return paras
+}
@@ -0,0 +1,203 @@
+package docs
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const tarPage = `.\" Manual page for tar
+.TH TAR 1 "2023" "GNU" "User Commands"
+.SH NAME
+tar \- an archiving utility
+.SH SYNOPSIS
+\fBtar\fR \-c [\fB\-f\fR \fIARCHIVE\fR] [\fIFILE\fR...]
+.SH OPTIONS
+.SS Compression options
+.TP
+\fB\-z\fR, \fB\-\-gzip\fR
+Filter the archive through
+.BR gzip (1).
+.TP
+\fB\-j\fR, \fB\-\-bzip2\fR
+Filter the archive through \(lqbzip2\(rq.
+.SH EXAMPLES
+.nf
+tar \-czf backup.tar.gz ~
+tar \-xf backup.tar.gz
+.fi
+`
+
+const sshPage = `.Dd $Mdocdate: November 28 2022 $
+.Dt SSH 1
+.Os
+.Sh NAME
+.Nm ssh
+.Nd OpenSSH remote login client
+.Sh SYNOPSIS
+.Nm ssh
+.Op Fl 46
+.Op Fl p Ar port
+.Ar destination
+.Sh DESCRIPTION
+.Bl -tag -width Ds
+.It Fl p Ar port
+Port to connect to on the remote host.
+.El
+`
+
+func TestParseRoff(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []Section
+	}{
+		{"man", tarPage, []Section{
+			{Heading: "", Text: ""},
+			{Heading: "NAME", Text: "tar - an archiving utility"},
+			{Heading: "SYNOPSIS", Text: "tar -c [-f ARCHIVE] [FILE...]"},
+			{Heading: "OPTIONS", Text: "Compression options:\n\n" +
+				"-z, --gzip\nFilter the archive through gzip(1).\n\n" +
+				"-j, --bzip2\nFilter the archive through “bzip2”."},
+			{Heading: "EXAMPLES", Text: "tar -czf backup.tar.gz ~\ntar -xf backup.tar.gz\n\n"},
+		}},
+		{"mdoc", sshPage, []Section{
+			{Heading: "", Text: ""},
+			{Heading: "NAME", Text: "ssh - OpenSSH remote login client"},
+			{Heading: "SYNOPSIS", Text: "ssh -46 -p port destination"},
+			{Heading: "DESCRIPTION", Text: "-p port\nPort to connect to on the remote host.\n\n"},
+		}},
+		{"link", ".so man1/gtar.1\n", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseRoff(tc.src); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseRoff() =\n%#v\nwant\n%#v", got, tc.want)
+			}
+		})
+	}
+}
+
+// writeFile writes the file in the directory, compressing it if the name
+// ends with .gz.
+func writeFile(t *testing.T, path, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if strings.HasSuffix(path, ".gz") {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		gz.Write([]byte(content))
+		return path
+	}
+	f.WriteString(content)
+	return path
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name      string
+		path      string
+		content   string
+		wantName  string
+		wantTitle string
+		wantURL   string
+		sections  int
+	}{
+		{"man page", filepath.Join(dir, "man", "man1", "tar.1.gz"), tarPage, "tar", "tar(1)", "man:tar(1)", 5},
+		{"markdown", filepath.Join(dir, "notes", "Backup.md"), "Intro\n\n# Daily\n\nUse rsync.\n\n```\n# not a heading\n```\n",
+			"backup", "Backup", "file://" + filepath.Join(dir, "notes", "Backup.md"), 2},
+		{"text", filepath.Join(dir, "notes", "README.txt.gz"), "Some notes.", "readme", "README.txt",
+			"file://" + filepath.Join(dir, "notes", "README.txt.gz"), 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := Read(writeFile(t, tc.path, tc.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Name != tc.wantName || doc.Title != tc.wantTitle || doc.URL != tc.wantURL {
+				t.Errorf("Read() = %q, %q, %q, want %q, %q, %q", doc.Name, doc.Title, doc.URL, tc.wantName, tc.wantTitle, tc.wantURL)
+			}
+			if len(doc.Sections) != tc.sections {
+				t.Errorf("Read() has %d sections, want %d: %#v", len(doc.Sections), tc.sections, doc.Sections)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	doc := &Document{Name: "tar", Title: "tar(1)", URL: "man:tar(1)", Sections: []Section{
+		{Heading: "NAME", Text: "tar - an archiving utility"},
+		{Heading: "OPTIONS", Text: "-c\ncreate a new archive\n\n-x\nextract files from an archive\n\n" +
+			strings.Repeat("very long paragraph ", 5)},
+	}}
+	got := Split(doc, 40)
+	var want = []struct{ title, text string }{
+		{"tar(1), NAME", "tar - an archiving utility"},
+		{"tar(1), OPTIONS", "-c\ncreate a new archive"},
+		{"tar(1), OPTIONS", "-x\nextract files from an archive"},
+		{"tar(1), OPTIONS", "very long paragraph very long paragraph"},
+		{"tar(1), OPTIONS", "very long paragraph very long paragraph"},
+		{"tar(1), OPTIONS", "very long paragraph"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Split() returned %d passages, want %d: %#v", len(got), len(want), got)
+	}
+	for i, p := range got {
+		if p.Title != want[i].title || p.Text != want[i].text || p.Name != "tar" || p.URL != "man:tar(1)" {
+			t.Errorf("passage %d = %q, %q, want %q, %q", i, p.Title, p.Text, want[i].title, want[i].text)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"man/man1/tar.1.gz", "man/man1/ls.1", "man/man5/fstab.5.gz", "local/man1/tar.1",
+		"doc/rsync/README.gz", "doc/rsync/copyright", "doc/rsync/changelog.Debian.gz", "doc/rsync/tips.md",
+		"doc/.hidden/README", "notes/a.md", "notes/sub/b.markdown", "notes/c.txt",
+	} {
+		writeFile(t, filepath.Join(dir, name), "x")
+	}
+	rel := func(paths []string) []string {
+		var out []string
+		for _, p := range paths {
+			r, _ := filepath.Rel(dir, p)
+			out = append(out, filepath.ToSlash(r))
+		}
+		return out
+	}
+
+	pages, err := ManPages([]string{filepath.Join(dir, "local"), filepath.Join(dir, "man")}, []string{"1", "8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"local/man1/tar.1", "man/man1/ls.1"}; !reflect.DeepEqual(rel(pages), want) {
+		t.Errorf("ManPages() = %v, want %v", rel(pages), want)
+	}
+	docs, err := DocFiles(filepath.Join(dir, "doc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"doc/rsync/README.gz", "doc/rsync/tips.md"}; !reflect.DeepEqual(rel(docs), want) {
+		t.Errorf("DocFiles() = %v, want %v", rel(docs), want)
+	}
+	md, err := MarkdownFiles(filepath.Join(dir, "notes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"notes/a.md", "notes/sub/b.markdown"}; !reflect.DeepEqual(rel(md), want) {
+		t.Errorf("MarkdownFiles() = %v, want %v", rel(md), want)
+	}
+}
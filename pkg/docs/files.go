@@ -0,0 +1,102 @@
+package docs
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DocDir is where the packages install their documentation.
+const DocDir = "/usr/share/doc"
+
+// maxFileSize limits how much of each file is indexed.
+const maxFileSize = 512 << 10
+
+// ManDirs returns the man directories listed in the MANPATH environment
+// variable, or the default ones.
+func ManDirs() []string {
+	if manpath := os.Getenv("MANPATH"); manpath != "" {
+		var dirs []string
+		for _, dir := range filepath.SplitList(manpath) {
+			if dir != "" {
+				dirs = append(dirs, dir)
+			}
+		}
+		return dirs
+	}
+	return []string{"/usr/local/share/man", "/usr/share/man"}
+}
+
+// ManPages returns the man pages of the sections, like "1" and "8", in
+// the directories. Missing directories are ignored, and a page found in
+// more than one directory is returned only from the first one.
+func ManPages(dirs, sections []string) ([]string, error) {
+	var pages []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		for _, section := range sections {
+			files, err := os.ReadDir(filepath.Join(dir, "man"+section))
+			if err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			for _, f := range files {
+				name := strings.TrimSuffix(f.Name(), ".gz")
+				if f.IsDir() || seen[name] || !manPattern.MatchString(f.Name()) {
+					continue
+				}
+				seen[name] = true
+				pages = append(pages, filepath.Join(dir, "man"+section, f.Name()))
+			}
+		}
+	}
+	return pages, nil
+}
+
+// DocFiles returns the README, Markdown and text files of the packages in
+// the directory, usually DocDir. Copyright notices and change logs are
+// left out.
+func DocFiles(dir string) ([]string, error) {
+	return walk(dir, func(name string) bool {
+		name = strings.ToLower(strings.TrimSuffix(name, ".gz"))
+		switch {
+		case name == "copyright", strings.HasPrefix(name, "changelog"), strings.HasPrefix(name, "news"):
+			return false
+		case strings.HasPrefix(name, "readme"):
+			return true
+		}
+		ext := filepath.Ext(name)
+		return ext == ".md" || ext == ".markdown" || ext == ".txt"
+	})
+}
+
+// MarkdownFiles returns the Markdown files in the directory and its
+// subdirectories.
+func MarkdownFiles(dir string) ([]string, error) {
+	return walk(dir, func(name string) bool {
+		ext := strings.ToLower(filepath.Ext(name))
+		return ext == ".md" || ext == ".markdown"
+	})
+}
+
+// walk returns the regular files in the directory tree accepted by match,
+// skipping hidden directories.
+func walk(dir string, match func(name string) bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil && path == dir:
+			return err
+		case err != nil:
+			return nil // unreadable subdirectories are skipped
+		case d.IsDir() && path != dir && strings.HasPrefix(d.Name(), "."):
+			return filepath.SkipDir
+		case d.Type().IsRegular() && match(d.Name()):
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
@@ -0,0 +1,317 @@
+package docs
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// DefaultMaxChars is the default size of the passages.
+const DefaultMaxChars = 1200
+
+// DefaultMaxPassages is the default limit of passages of each file, since
+// a few man pages, like bash(1), are very long.
+const DefaultMaxPassages = 60
+
+// FileInfo identifies the version of an indexed file.
+type FileInfo struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// Index is the list of passages of the indexed files with their
+// embeddings. It is saved with encoding/gob, which is much more compact
+// than JSON for the vectors.
+type Index struct {
+	// Model is the embedding model used to compute the vectors. The index
+	// is rebuilt if it changes.
+	Model string
+	// Files maps the indexed files to their version, so that only the
+	// files that changed are indexed again.
+	Files    map[string]FileInfo
+	Passages []Passage
+}
+
+// NewIndex returns an empty index.
+func NewIndex() *Index {
+	return &Index{Model: text.EmbeddingModelVersion, Files: make(map[string]FileInfo)}
+}
+
+// DefaultPath returns the path of the index of the tool in the user cache
+// directory.
+func DefaultPath(tool string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, tool, "docs.index")
+}
+
+// Load reads the index from the file. The error wraps fs.ErrNotExist if
+// the file does not exist.
+func Load(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ix := &Index{}
+	if err := gob.NewDecoder(f).Decode(ix); err != nil {
+		return nil, fmt.Errorf("docs: reading %s: %w", path, err)
+	}
+	if ix.Files == nil {
+		ix.Files = make(map[string]FileInfo)
+	}
+	return ix, nil
+}
+
+// Save writes the index to the file, replacing it only after the new
+// content is completely written.
+func (ix *Index) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err = gob.NewEncoder(tmp).Encode(ix); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// remove deletes the passages of the file from the index.
+func (ix *Index) remove(path string) {
+	passages := ix.Passages[:0]
+	for _, p := range ix.Passages {
+		if p.Path != path {
+			passages = append(passages, p)
+		}
+	}
+	ix.Passages = passages
+	delete(ix.Files, path)
+}
+
+// Stats counts the files processed by Update.
+type Stats struct {
+	Indexed, Unchanged, Removed int
+	// Failed are the files that could not be read.
+	Failed int
+}
+
+// Indexer adds the passages of documentation files to an index.
+type Indexer struct {
+	Embedder text.Embedder
+	// MaxChars is the maximum size of the passages.
+	MaxChars int
+	// MaxPassages limits the passages of each file. Zero means no limit.
+	MaxPassages int
+	// Progress, if set, is called after each file is processed.
+	Progress func(path string, done, total int)
+}
+
+// NewIndexer returns an Indexer with the default limits that uses the
+// embedder to compute the vectors.
+func NewIndexer(e text.Embedder) *Indexer {
+	return &Indexer{Embedder: e, MaxChars: DefaultMaxChars, MaxPassages: DefaultMaxPassages}
+}
+
+// Update indexes the files that are new or changed since they were added
+// to the index, and removes the files that are not in the list. Files that
+// can not be read are skipped. If the embeddings fail or the context is
+// canceled, the index keeps the files processed so far and can be saved
+// and updated later.
+func (ixr *Indexer) Update(ctx context.Context, ix *Index, paths []string) (Stats, error) {
+	var stats Stats
+	if ix.Model != text.EmbeddingModelVersion {
+		*ix = *NewIndex()
+	}
+	wanted := make(map[string]bool)
+	for _, path := range paths {
+		wanted[path] = true
+	}
+	for path := range ix.Files {
+		if !wanted[path] {
+			ix.remove(path)
+			stats.Removed++
+		}
+	}
+
+	for i, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if err := ixr.index(ctx, ix, path, &stats); err != nil {
+			return stats, fmt.Errorf("docs: indexing %s: %w", path, err)
+		}
+		if ixr.Progress != nil {
+			ixr.Progress(path, i+1, len(paths))
+		}
+	}
+	return stats, nil
+}
+
+// index adds the file to the index if it is new or changed, counting it
+// in the stats.
+func (ixr *Indexer) index(ctx context.Context, ix *Index, path string, stats *Stats) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		stats.Failed++
+		return nil
+	}
+	info := FileInfo{ModTime: fi.ModTime(), Size: fi.Size()}
+	if old, ok := ix.Files[path]; ok && old.ModTime.Equal(info.ModTime) && old.Size == info.Size {
+		stats.Unchanged++
+		return nil
+	}
+	doc, err := Read(path)
+	if err != nil {
+		stats.Failed++
+		return nil
+	}
+	maxChars := ixr.MaxChars
+	if maxChars <= 0 {
+		maxChars = DefaultMaxChars
+	}
+	passages := Split(doc, maxChars)
+	if ixr.MaxPassages > 0 && len(passages) > ixr.MaxPassages {
+		passages = passages[:ixr.MaxPassages]
+	}
+	if err := ixr.embed(ctx, passages); err != nil {
+		return err
+	}
+	ix.remove(path)
+	ix.Passages = append(ix.Passages, passages...)
+	ix.Files[path] = info
+	stats.Indexed++
+	return nil
+}
+
+// embed computes the vectors of the passages, including their titles.
+func (ixr *Indexer) embed(ctx context.Context, passages []Passage) error {
+	if len(passages) == 0 {
+		return nil
+	}
+	texts := make([]string, len(passages))
+	for i, p := range passages {
+		texts[i] = p.Title + "\n\n" + p.Text
+	}
+	vectors, err := ixr.Embedder.EmbedTexts(ctx, texts)
+	if err != nil {
+		return err
+	}
+	if len(vectors) != len(passages) {
+		return fmt.Errorf("got %d embeddings for %d passages", len(vectors), len(passages))
+	}
+	for i := range passages {
+		passages[i].Vector = normalize(vectors[i])
+	}
+	return nil
+}
+
+// normalize returns the vector with unit length, so that the similarity
+// of two vectors is their dot product.
+func normalize(v []float64) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	norm = math.Sqrt(norm)
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	for i, x := range v {
+		out[i] = float32(x / norm)
+	}
+	return out
+}
+
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// Result is a passage found by Search.
+type Result struct {
+	Passage
+	// Score is the cosine similarity of the passage and the question,
+	// plus nameBonus if the passage is about a command in the question.
+	Score float64
+}
+
+// nameBonus favors the passages of the documents about the commands named
+// in the question, like the man page of tar for "how to use tar?".
+const nameBonus = 0.05
+
+// maxPerFile limits the passages of the same file in the results, so that
+// other sources are also included.
+const maxPerFile = 2
+
+// ErrEmptyIndex is returned when searching an index without passages.
+var ErrEmptyIndex = errors.New("docs: the index is empty")
+
+var wordPattern = regexp.MustCompile(`[\pL\pN._+-]+`)
+
+// Search returns up to k passages most similar to the query.
+func (ix *Index) Search(ctx context.Context, e text.Embedder, query string, k int) ([]Result, error) {
+	if len(ix.Passages) == 0 {
+		return nil, ErrEmptyIndex
+	}
+	vectors, err := e.EmbedTexts(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("docs: got %d embeddings for the query", len(vectors))
+	}
+	q := normalize(vectors[0])
+	names := make(map[string]bool)
+	for _, w := range wordPattern.FindAllString(strings.ToLower(query), -1) {
+		names[strings.TrimRight(w, ".")] = true
+	}
+
+	results := make([]Result, len(ix.Passages))
+	for i, p := range ix.Passages {
+		results[i] = Result{Passage: p, Score: dot(q, p.Vector)}
+		if names[p.Name] {
+			results[i].Score += nameBonus
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	var top []Result
+	perFile := make(map[string]int)
+	for _, r := range results {
+		if len(top) == k {
+			break
+		}
+		if perFile[r.Path] == maxPerFile {
+			continue
+		}
+		perFile[r.Path]++
+		top = append(top, r)
+	}
+	return top, nil
+}
@@ -0,0 +1,162 @@
+package docs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEmbedder counts the words of a small vocabulary in each text, so
+// that texts about the same subject have similar vectors.
+type fakeEmbedder struct {
+	calls, texts int
+	err          error
+}
+
+var vocabulary = []string{"archive", "compress", "remote", "port", "disk", "space"}
+
+func (e *fakeEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]float64, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	e.calls++
+	e.texts += len(texts)
+	var vectors [][]float64
+	for _, t := range texts {
+		v := make([]float64, len(vocabulary)+1)
+		v[len(vocabulary)] = 0.1
+		for i, w := range vocabulary {
+			v[i] = float64(strings.Count(strings.ToLower(t), w))
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
+
+const dfPage = `.TH DF 1
+.SH NAME
+df \- report file system disk space usage
+.SH OPTIONS
+.TP
+\-h
+print disk space in powers of 1024
+`
+
+const sshShortPage = `.TH SSH 1
+.SH NAME
+ssh \- remote login client
+.SH OPTIONS
+.TP
+\-p \fIport\fR
+the port to connect to on the remote host
+`
+
+func TestIndex(t *testing.T) {
+	dir := t.TempDir()
+	tar := writeFile(t, filepath.Join(dir, "man1", "tar.1.gz"), tarPage+".SH MORE\nArchive and compress files.\n")
+	df := writeFile(t, filepath.Join(dir, "man1", "df.1"), dfPage)
+	ssh := writeFile(t, filepath.Join(dir, "man1", "ssh.1"), sshShortPage)
+	missing := filepath.Join(dir, "man1", "missing.1")
+
+	e := &fakeEmbedder{}
+	ixr := NewIndexer(e)
+	var progress []int
+	ixr.Progress = func(path string, done, total int) { progress = append(progress, done) }
+	ix := NewIndex()
+	stats, err := ixr.Update(context.Background(), ix, []string{tar, df, ssh, missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Stats{Indexed: 3, Failed: 1}); stats != want {
+		t.Errorf("Update() stats = %+v, want %+v", stats, want)
+	}
+	if len(progress) != 4 || progress[3] != 4 {
+		t.Errorf("Progress called with %v", progress)
+	}
+	if e.texts != len(ix.Passages) {
+		t.Errorf("embedded %d texts for %d passages", e.texts, len(ix.Passages))
+	}
+
+	path := filepath.Join(dir, "cache", "docs.index")
+	if err := ix.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	ix, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the changed file is indexed again, and removed files are
+	// dropped from the index.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(df, later, later); err != nil {
+		t.Fatal(err)
+	}
+	e.texts = 0
+	stats, err = ixr.Update(context.Background(), ix, []string{df, ssh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Stats{Indexed: 1, Unchanged: 1, Removed: 1}); stats != want {
+		t.Errorf("second Update() stats = %+v, want %+v", stats, want)
+	}
+	for _, p := range ix.Passages {
+		if p.Path == tar {
+			t.Errorf("passage of a removed file: %+v", p)
+		}
+	}
+	if e.texts != 2 {
+		t.Errorf("second Update() embedded %d texts, want the 2 passages of df", e.texts)
+	}
+
+	results, err := ix.Search(context.Background(), e, "how much disk space is free?", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Name != "df" || results[1].Name != "df" {
+		t.Fatalf("Search() = %+v, want the 2 df passages", results)
+	}
+	if results[0].Score < results[1].Score {
+		t.Errorf("results are not sorted by score: %v, %v", results[0].Score, results[1].Score)
+	}
+
+	// The name of the command favors its passages and at most maxPerFile
+	// passages of each file are returned.
+	results, err = ix.Search(context.Background(), e, "ssh", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[0].Name != "ssh" || results[1].Name != "ssh" || results[2].Name != "df" {
+		t.Errorf("Search() = %+v, want 2 ssh passages and one df", results)
+	}
+
+	e.err = errors.New("quota exceeded")
+	if _, err := ix.Search(context.Background(), e, "ssh", 3); err == nil {
+		t.Errorf("Search() did not return the embedding error")
+	}
+	if _, err := NewIndex().Search(context.Background(), e, "ssh", 3); !errors.Is(err, ErrEmptyIndex) {
+		t.Errorf("Search() on an empty index error = %v, want ErrEmptyIndex", err)
+	}
+}
+
+func TestUpdateCanceled(t *testing.T) {
+	dir := t.TempDir()
+	df := writeFile(t, filepath.Join(dir, "man1", "df.1"), dfPage)
+	ssh := writeFile(t, filepath.Join(dir, "man1", "ssh.1"), sshShortPage)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ixr := NewIndexer(&fakeEmbedder{})
+	ixr.Progress = func(path string, done, total int) { cancel() }
+	ix := NewIndex()
+	stats, err := ixr.Update(ctx, ix, []string{df, ssh})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Update() error = %v, want context.Canceled", err)
+	}
+	if stats.Indexed != 1 || len(ix.Files) != 1 {
+		t.Errorf("Update() kept %d files, want the first one", len(ix.Files))
+	}
+}
@@ -0,0 +1,242 @@
+package docs
+
+import (
+	"regexp"
+	"strings"
+)
+
+// roffEscapes maps the special characters of roff to text.
+var roffEscapes = map[string]string{
+	"em": "—", "en": "–", "hy": "-", "mi": "-", "aq": "'", "dq": `"`,
+	"lq": "“", "rq": "”", "oq": "‘", "cq": "’", "bu": "•", "co": "©",
+	"rg": "®", "tm": "™", "ga": "`", "ti": "~", "ha": "^", "rs": `\`,
+	"Fo": "«", "Fc": "»", ">=": "≥", "<=": "≤", "->": "→", "<-": "←",
+}
+
+var escapePattern = regexp.MustCompile(`\\(\(..|\[[^\]]*\]|\*\(..|\*\[[^\]]*\]|\*.|f\(..|f\[[^\]]*\]|f.|s[-+]?\d|.)`)
+
+// roffText replaces the escape sequences of the roff text.
+func roffText(s string) string {
+	if i := strings.Index(s, `\"`); i >= 0 {
+		s = s[:i]
+	}
+	return escapePattern.ReplaceAllStringFunc(s, func(esc string) string {
+		switch {
+		case strings.HasPrefix(esc, `\(`):
+			return roffEscapes[esc[2:]]
+		case strings.HasPrefix(esc, `\[`):
+			return roffEscapes[esc[2:len(esc)-1]]
+		case strings.HasPrefix(esc, `\*(`):
+			return roffEscapes[esc[3:]]
+		}
+		switch esc {
+		case `\-`:
+			return "-"
+		case `\e`, `\\`:
+			return `\`
+		case `\ `, `\~`:
+			return " "
+		case `\.`:
+			return "."
+		case `\'`:
+			return "'"
+		case "\\`":
+			return "`"
+		}
+		return "" // fonts, sizes, strings and spacing
+	})
+}
+
+// roffArgs splits the arguments of a request, keeping quoted ones.
+func roffArgs(s string) []string {
+	var args []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				end = len(s) - 1
+			}
+			args = append(args, s[1:end+1])
+			s = s[min(end+2, len(s)):]
+			continue
+		}
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		args = append(args, s[:end])
+		s = s[end:]
+	}
+	return args
+}
+
+// mdocMacros are the callable macros of mdoc, the BSD man page format,
+// that are removed from the text of the lines.
+var mdocMacros = map[string]bool{
+	"Ad": true, "An": true, "Ar": true, "Cm": true, "Dl": true, "Dq": true, "Dv": true,
+	"Em": true, "Er": true, "Ev": true, "Fa": true, "Fn": true, "Ic": true, "Li": true,
+	"Ms": true, "Nm": true, "No": true, "Ns": true, "Oc": true, "Oo": true, "Op": true,
+	"Pa": true, "Pf": true, "Ql": true, "Qq": true, "Sq": true, "Sx": true, "Sy": true,
+	"Tn": true, "Va": true, "Xc": true, "Xo": true, "Xr": true, "Aq": true, "Bq": true,
+	"Brq": true, "Pq": true, "Lk": true, "Mt": true,
+}
+
+// mdocText returns the text of a mdoc line, like ".Op Fl v Ar file",
+// formatting flags and dropping the other macros.
+func mdocText(args []string, name string) string {
+	var words []string
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == "Fl":
+			flag := "-"
+			if i+1 < len(args) && !mdocMacros[args[i+1]] && args[i+1] != "Fl" {
+				i++
+				flag += args[i]
+			}
+			words = append(words, flag)
+		case a == "Nm":
+			words = append(words, name)
+		case a == "Ux":
+			words = append(words, "UNIX")
+		case mdocMacros[a]:
+		default:
+			words = append(words, a)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// continuationPattern matches the lines that end with a backslash, that
+// continue in the next line.
+var continuationPattern = regexp.MustCompile(`(^|[^\\])\\\n`)
+
+// parseRoff converts a man page written with the man or mdoc macros to
+// text, split in sections at each .SH or .Sh heading.
+func parseRoff(src string) []Section {
+	var sections []Section
+	cur := Section{}
+	var b strings.Builder
+	name := ""
+	noFill := false
+	// tag is set after .TP, whose next line is the tag of the paragraph,
+	// like an option, that is kept in its own line.
+	tag := false
+	flushSection := func() {
+		cur.Text = b.String()
+		sections = append(sections, cur)
+		b.Reset()
+	}
+	paragraph := func() {
+		if b.Len() > 0 {
+			text := strings.TrimRight(b.String(), "\n")
+			b.Reset()
+			b.WriteString(text + "\n\n")
+		}
+	}
+	write := func(text string) {
+		if text == "" {
+			return
+		}
+		if s := b.String(); b.Len() > 0 && !strings.HasSuffix(s, "\n") {
+			if noFill {
+				b.WriteString("\n")
+			} else {
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(text)
+		if tag {
+			b.WriteString("\n")
+			tag = false
+		}
+	}
+
+	src = continuationPattern.ReplaceAllString(strings.ReplaceAll(src, "\r\n", "\n"), "$1")
+	lines := strings.Split(src, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" {
+			if !noFill {
+				paragraph()
+			} else {
+				b.WriteString("\n")
+			}
+			continue
+		}
+		if line[0] != '.' && line[0] != '\'' {
+			write(strings.TrimSpace(roffText(line)))
+			continue
+		}
+		request, rest, _ := strings.Cut(strings.TrimSpace(line[1:]), " ")
+		args := roffArgs(roffText(rest))
+		switch request {
+		case "\\\"", "", "Dd", "Os", "Sm", "Bk", "Ek":
+			// comments, empty requests and mdoc requests without text
+		case "so":
+			// links to other pages have no content of their own
+			return nil
+		case "de", "ig", "am":
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != ".."; i++ {
+			}
+		case "TH", "Dt":
+			if len(args) > 0 {
+				name = strings.ToLower(args[0])
+			}
+		case "Nm":
+			if name == "" && len(args) > 0 {
+				name = args[0]
+			}
+			if len(args) == 0 {
+				write(name)
+			} else {
+				write(mdocText(args, name))
+			}
+		case "SH", "Sh":
+			flushSection()
+			cur = Section{Heading: strings.Join(args, " ")}
+		case "SS", "Ss":
+			paragraph()
+			write(strings.Join(args, " ") + ":")
+			paragraph()
+		case "TP":
+			paragraph()
+			tag = true
+		case "PP", "P", "LP", "Pp", "sp", "HP", "Bl", "El", "Bd", "Ed", "RS", "RE":
+			paragraph()
+		case "IP", "It":
+			paragraph()
+			tag = true
+			if request == "It" {
+				write(mdocText(args, name))
+			} else if len(args) > 0 {
+				write(args[0])
+			}
+			tag = false
+		case "br":
+			b.WriteString("\n")
+		case "nf", "EX":
+			paragraph()
+			noFill = true
+		case "fi", "EE":
+			noFill = false
+			paragraph()
+		case "B", "I", "SM", "SB":
+			write(strings.Join(args, " "))
+		case "BR", "RB", "IR", "RI", "BI", "IB":
+			write(strings.Join(args, ""))
+		case "Nd":
+			write("- " + strings.Join(args, " "))
+		case "UR", "MT":
+			if len(args) > 0 {
+				write("<" + args[0] + ">")
+			}
+		default:
+			if len(request) == 2 && request[0] >= 'A' && request[0] <= 'Z' && request[1] >= 'a' && request[1] <= 'z' {
+				// other mdoc macros, like .Op, .Fl or .Xr
+				write(mdocText(append([]string{request}, args...), name))
+			}
+		}
+	}
+	flushSection()
+	return sections
+}
@@ -0,0 +1,109 @@
+package injection
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Warnings reported by CheckAnswer.
+const (
+	WarnFenceLeak = "a resposta reproduz os delimitadores dos dados"
+	WarnInjection = "a resposta contém texto semelhante a uma tentativa de injeção"
+	WarnOffTask   = "a resposta não menciona nenhum termo dos dados analisados"
+)
+
+// Warnings lists the warnings of CheckAnswer in the order they are
+// checked.
+var Warnings = []string{WarnFenceLeak, WarnInjection, WarnOffTask}
+
+// minTermLength is the minimum length of the terms compared by CheckAnswer,
+// to skip short words common to any text.
+const minTermLength = 3
+
+// CheckAnswer verifies that the answer about the fenced data stays on
+// task, returning the warnings found. Answers that repeat the delimiters,
+// that have injection-like instructions not quoted from the data, or that
+// share no terms with the data, like "OK" or a joke requested by an
+// injected instruction, are reported.
+func CheckAnswer(answer string, f Fence, data string) []string {
+	if strings.TrimSpace(answer) == "" {
+		return nil
+	}
+	var warnings []string
+	if id := nonce(f); id != "" && strings.Contains(answer, id) {
+		warnings = append(warnings, WarnFenceLeak)
+	}
+	if injected(answer, data) {
+		warnings = append(warnings, WarnInjection)
+	}
+	if !sharesTerms(answer, data) {
+		warnings = append(warnings, WarnOffTask)
+	}
+	return warnings
+}
+
+// injected reports whether a line of the answer matches the Rules with a
+// text that is not in the data, since answers may quote the attempts
+// they report.
+func injected(answer, data string) bool {
+	normalized := strings.ToLower(normalize(data))
+	for _, line := range strings.Split(answer, "\n") {
+		line = normalize(line)
+		if _, m := match(line); m != nil && !strings.Contains(normalized, strings.ToLower(line[m[0]:m[1]])) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonce returns the random part of the fence delimiters.
+func nonce(f Fence) string {
+	fields := strings.Fields(strings.Trim(f.Begin, "<>"))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
+
+// sharesTerms reports whether the answer mentions at least one of the
+// terms of the data, like a resource name, a method or an error code.
+func sharesTerms(answer, data string) bool {
+	terms := make(map[string]bool)
+	for _, t := range splitTerms(data) {
+		terms[t] = true
+	}
+	for _, t := range splitTerms(answer) {
+		if terms[t] {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTerms splits the text in lower case terms of at least minTermLength
+// characters, keeping the dots, dashes, underscores and slashes of
+// identifiers and paths like compute.instances.insert or /var/log.
+func splitTerms(s string) []string {
+	var terms []string
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(identifierChars, r)
+	})
+	for _, f := range fields {
+		f = strings.Trim(f, identifierChars)
+		if len([]rune(f)) < minTermLength {
+			continue
+		}
+		terms = append(terms, f)
+		// identifiers also match their parts, like "instances" or "log"
+		if strings.ContainsAny(f, identifierChars) {
+			for _, part := range strings.FieldsFunc(f, func(r rune) bool { return strings.ContainsRune(identifierChars, r) }) {
+				if len([]rune(part)) >= minTermLength {
+					terms = append(terms, part)
+				}
+			}
+		}
+	}
+	return terms
+}
+
+const identifierChars = "._-/"
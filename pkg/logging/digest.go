@@ -0,0 +1,269 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Group is a set of similar log entries, with the same severity,
+// resource type, method and message pattern.
+type Group struct {
+	Severity string    `json:"severity"`
+	Resource string    `json:"resource"`
+	Method   string    `json:"method,omitempty"`
+	Pattern  string    `json:"pattern"`
+	Count    int       `json:"count"`
+	First    time.Time `json:"first"`
+	Last     time.Time `json:"last"`
+	// Sample is the first entry seen in the group.
+	Sample Entry `json:"sample"`
+}
+
+// Digest is a compact summary of a list of log entries.
+type Digest struct {
+	Total int `json:"total"`
+	// Dropped is the number of entries that were discarded before the
+	// digest was computed, like in a bounded Window.
+	Dropped    int            `json:"dropped,omitempty"`
+	First      time.Time      `json:"first"`
+	Last       time.Time      `json:"last"`
+	BySeverity map[string]int `json:"bySeverity"`
+	ByResource map[string]int `json:"byResource"`
+	ByMethod   map[string]int `json:"byMethod"`
+	// Groups are the deduplicated entries, sorted by decreasing severity
+	// and count.
+	Groups []Group `json:"groups"`
+
+	// MaxGroups and MaxSamples limit how many groups and sample entries
+	// are included when the digest is formatted as text.
+	MaxGroups  int `json:"-"`
+	MaxSamples int `json:"-"`
+
+	// index maps the group keys to their position in Groups.
+	index map[string]int
+}
+
+// variable matches the parts of log messages that usually change between
+// similar entries, like numbers, hexadecimal identifiers and UUIDs.
+var variable = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|\b0x[0-9a-fA-F]+\b|\b[0-9a-fA-F]*[0-9][0-9a-fA-F]*\b`)
+
+// Pattern returns the message with variable parts replaced by '#', so that
+// similar messages can be grouped together. Numbers with less than four
+// digits, like HTTP status codes, are kept as they are usually meaningful.
+func Pattern(message string) string {
+	message = strings.Join(strings.Fields(message), " ")
+	if len(message) > 200 {
+		cut := 200
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		message = message[:cut]
+	}
+	return variable.ReplaceAllStringFunc(message, func(v string) string {
+		if len(v) < 4 {
+			return v
+		}
+		return "#"
+	})
+}
+
+// NewDigest groups and counts the entries.
+func NewDigest(entries []Entry) *Digest {
+	d := &Digest{
+		BySeverity: make(map[string]int),
+		ByResource: make(map[string]int),
+		ByMethod:   make(map[string]int),
+		MaxGroups:  30,
+		MaxSamples: 5,
+		index:      make(map[string]int),
+	}
+	for _, e := range entries {
+		d.add(e)
+	}
+	sort.SliceStable(d.Groups, func(i, j int) bool {
+		li, lj := SeverityLevel(d.Groups[i].Severity), SeverityLevel(d.Groups[j].Severity)
+		if li != lj {
+			return li > lj
+		}
+		return d.Groups[i].Count > d.Groups[j].Count
+	})
+	return d
+}
+
+// add includes the entry in the digest counters and groups.
+func (d *Digest) add(e Entry) {
+	severity := e.Severity
+	if severity == "" {
+		severity = "DEFAULT"
+	}
+	d.Total++
+	d.BySeverity[severity]++
+	d.ByResource[e.Resource.Type]++
+	method := e.Method()
+	if method != "" {
+		d.ByMethod[method]++
+	}
+	if !e.Timestamp.IsZero() {
+		if d.First.IsZero() || e.Timestamp.Before(d.First) {
+			d.First = e.Timestamp
+		}
+		if e.Timestamp.After(d.Last) {
+			d.Last = e.Timestamp
+		}
+	}
+
+	pattern := Pattern(e.Message())
+	key := strings.Join([]string{severity, e.Resource.Type, method, pattern}, "\x00")
+	i, ok := d.index[key]
+	if !ok {
+		i = len(d.Groups)
+		d.index[key] = i
+		d.Groups = append(d.Groups, Group{
+			Severity: severity,
+			Resource: e.Resource.Type,
+			Method:   method,
+			Pattern:  pattern,
+			Sample:   e,
+			First:    e.Timestamp,
+			Last:     e.Timestamp,
+		})
+	}
+	g := &d.Groups[i]
+	g.Count++
+	if !e.Timestamp.IsZero() {
+		if g.First.IsZero() || e.Timestamp.Before(g.First) {
+			g.First = e.Timestamp
+		}
+		if e.Timestamp.After(g.Last) {
+			g.Last = e.Timestamp
+		}
+	}
+}
+
+// String formats the digest as compact text to be used in prompts,
+// including the counters, the most relevant groups and a few sample
+// entries.
+func (d *Digest) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Entries: %d", d.Total)
+	if !d.First.IsZero() {
+		fmt.Fprintf(&sb, " from %s to %s", d.First.Format(time.RFC3339), d.Last.Format(time.RFC3339))
+	}
+	if d.Dropped > 0 {
+		fmt.Fprintf(&sb, " (%d older entries discarded)", d.Dropped)
+	}
+	sb.WriteString("\n")
+	fmt.Fprintf(&sb, "By severity: %s\n", counts(d.BySeverity, 0))
+	fmt.Fprintf(&sb, "By resource: %s\n", counts(d.ByResource, 10))
+	if len(d.ByMethod) > 0 {
+		fmt.Fprintf(&sb, "By method: %s\n", counts(d.ByMethod, 10))
+	}
+
+	fmt.Fprintf(&sb, "\nGroups of similar entries (%d):\n", len(d.Groups))
+	for i, g := range d.Groups {
+		if d.MaxGroups > 0 && i >= d.MaxGroups {
+			rest := 0
+			for _, g := range d.Groups[i:] {
+				rest += g.Count
+			}
+			fmt.Fprintf(&sb, "- ... %d other groups with %d entries\n", len(d.Groups)-i, rest)
+			break
+		}
+		fmt.Fprintf(&sb, "- %s x%d %s", g.Severity, g.Count, g.Resource)
+		if g.Method != "" {
+			fmt.Fprintf(&sb, " %s", g.Method)
+		}
+		if g.Pattern != "" {
+			fmt.Fprintf(&sb, " %q", g.Pattern)
+		}
+		if !g.First.IsZero() {
+			if g.Count > 1 && !g.First.Equal(g.Last) {
+				fmt.Fprintf(&sb, " (%s - %s)", g.First.Format(time.RFC3339), g.Last.Format(time.RFC3339))
+			} else {
+				fmt.Fprintf(&sb, " (%s)", g.First.Format(time.RFC3339))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	if d.MaxSamples > 0 && len(d.Groups) > 0 {
+		sb.WriteString("\nSample entries:\n")
+		for i, g := range d.Groups {
+			if i >= d.MaxSamples {
+				break
+			}
+			b, err := json.Marshal(compact(g.Sample))
+			if err != nil {
+				continue
+			}
+			sb.Write(b)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// compact returns the sample entry as a map, without empty fields and
+// without the fields that rarely help to understand it.
+func compact(e Entry) map[string]interface{} {
+	m := make(map[string]interface{})
+	set := func(key string, v interface{}, empty bool) {
+		if !empty {
+			m[key] = v
+		}
+	}
+	set("timestamp", e.Timestamp, e.Timestamp.IsZero())
+	set("severity", e.Severity, e.Severity == "")
+	set("logName", e.LogName, e.LogName == "")
+	set("resource", e.Resource, e.Resource.Type == "" && len(e.Resource.Labels) == 0)
+	set("labels", e.Labels, len(e.Labels) == 0)
+	set("textPayload", e.TextPayload, e.TextPayload == "")
+	set("jsonPayload", e.JSONPayload, len(e.JSONPayload) == 0)
+	set("httpRequest", e.HTTPRequest, e.HTTPRequest == nil)
+	set("trace", e.Trace, e.Trace == "")
+	if len(e.ProtoPayload) > 0 {
+		p := make(map[string]interface{}, len(e.ProtoPayload))
+		for k, v := range e.ProtoPayload {
+			switch k {
+			case "@type", "requestMetadata", "authorizationInfo", "metadata":
+				continue
+			}
+			p[k] = v
+		}
+		m["protoPayload"] = p
+	}
+	return m
+}
+
+// counts formats the map as "key=count" items sorted by decreasing count,
+// showing at most limit items if limit is positive.
+func counts(m map[string]int, limit int) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	var items []string
+	for i, k := range keys {
+		if limit > 0 && i >= limit {
+			items = append(items, fmt.Sprintf("... %d more", len(keys)-i))
+			break
+		}
+		label := k
+		if label == "" {
+			label = "(none)"
+		}
+		items = append(items, fmt.Sprintf("%s=%d", label, m[k]))
+	}
+	return strings.Join(items, ", ")
+}
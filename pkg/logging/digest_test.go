@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func parseTestdata(t *testing.T, name string) []Entry {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return entries
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantCount int
+		wantErr   bool
+	}{
+		{"array", `[{"severity":"INFO"},{"severity":"ERROR"}]`, 2, false},
+		{"ndjson", "{\"severity\":\"INFO\"}\n{\"severity\":\"ERROR\"}\n", 2, false},
+		{"empty", "", 0, false},
+		{"invalid", `{"severity":`, 0, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := Parse(strings.NewReader(tc.input))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if len(entries) != tc.wantCount {
+				t.Errorf("got %d entries, want %d", len(entries), tc.wantCount)
+			}
+		})
+	}
+}
+
+func TestEntryFields(t *testing.T) {
+	entries := parseTestdata(t, "gcloud-logging-read.json")
+	tests := []struct {
+		index       int
+		wantMethod  string
+		wantMessage string
+	}{
+		{0, "v1.compute.instances.insert", "Permission denied on resource project demo-project."},
+		{2, "GET /api/items", "HTTP 200"},
+		{4, "", "connection to database timed out after 30s"},
+		{5, "", "Container started"},
+	}
+	for _, tc := range tests {
+		e := entries[tc.index]
+		if got := e.Method(); got != tc.wantMethod {
+			t.Errorf("entry %d: got method %q, want %q", tc.index, got, tc.wantMethod)
+		}
+		if got := e.Message(); got != tc.wantMessage {
+			t.Errorf("entry %d: got message %q, want %q", tc.index, got, tc.wantMessage)
+		}
+	}
+	if got := entries[0].Principal(); got != "alice@example.com" {
+		t.Errorf("got principal %q", got)
+	}
+	if entries[0].Timestamp.IsZero() || entries[0].Resource.Labels["zone"] != "us-central1-a" {
+		t.Errorf("entry not fully decoded: %+v", entries[0])
+	}
+}
+
+func TestDigest(t *testing.T) {
+	entries := parseTestdata(t, "gcloud-logging-read.json")
+	d := NewDigest(entries)
+	if d.Total != 6 {
+		t.Errorf("got %d entries, want 6", d.Total)
+	}
+	if got := len(d.Groups); got != 4 {
+		t.Errorf("got %d groups, want 4: %+v", got, d.Groups)
+	}
+	if g := d.Groups[0]; g.Severity != "ERROR" || g.Count != 2 {
+		t.Errorf("got first group %s x%d, want ERROR x2", g.Severity, g.Count)
+	}
+	if got := d.BySeverity["DEFAULT"]; got != 1 {
+		t.Errorf("got %d DEFAULT entries, want 1", got)
+	}
+	if got := d.ByMethod["GET /api/items"]; got != 2 {
+		t.Errorf("got %d GET requests, want 2", got)
+	}
+	if got := d.First.Format("15:04"); got != "11:59" {
+		t.Errorf("got first timestamp %v", got)
+	}
+
+	// Repeated entries must not increase the digest size significantly.
+	digest := d.String()
+	var repeated []Entry
+	for i := 0; i < 100; i++ {
+		repeated = append(repeated, entries...)
+	}
+	if got := len(NewDigest(repeated).String()); got > len(digest)+100 {
+		t.Errorf("digest of repeated entries has %d chars, want about %d", got, len(digest))
+	}
+	for _, want := range []string{"ERROR x2 gce_instance v1.compute.instances.insert", "By severity: ERROR=2, INFO=2"} {
+		if !strings.Contains(digest, want) {
+			t.Errorf("digest does not contain %q:\n%s", want, digest)
+		}
+	}
+	if strings.Contains(digest, "requestMetadata") || strings.Contains(digest, "insertId") {
+		t.Errorf("digest contains noisy fields:\n%s", digest)
+	}
+}
+
+func TestPattern(t *testing.T) {
+	tests := []struct {
+		message, want string
+	}{
+		{"timed out after 30s", "timed out after 30s"},
+		{"request 1234 failed", "request # failed"},
+		{"trace 0123456789abcdef  done", "trace # done"},
+		{"id 123e4567-e89b-12d3-a456-426614174000", "id #"},
+		{"v1.compute", "v1.compute"},
+		{"HTTP 503", "HTTP 503"},
+		{strings.Repeat("x", 199) + "ção", strings.Repeat("x", 199)},
+	}
+	for _, tc := range tests {
+		if got := Pattern(tc.message); got != tc.want {
+			t.Errorf("Pattern(%q) = %q, want %q", tc.message, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package logging decodes Google Cloud Logging entries and summarizes them
+// into compact digests that are cheaper to send to the text models than
+// the raw JSON exported by `gcloud logging read`. Other formats, like
+// journald, syslog, Kubernetes and nginx logs, are normalized into the same
+// Entry type.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Resource is the monitored resource that produced a log entry.
+type Resource struct {
+	Type   string            `json:"type,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// HTTPRequest holds the information about an HTTP request associated with
+// a log entry.
+type HTTPRequest struct {
+	RequestMethod string `json:"requestMethod,omitempty"`
+	RequestURL    string `json:"requestUrl,omitempty"`
+	Status        int    `json:"status,omitempty"`
+	Latency       string `json:"latency,omitempty"`
+	RemoteIP      string `json:"remoteIp,omitempty"`
+	UserAgent     string `json:"userAgent,omitempty"`
+	ResponseSize  string `json:"responseSize,omitempty"`
+}
+
+// Entry is a Cloud Logging LogEntry, as exported in JSON by
+// `gcloud logging read --format=json`.
+type Entry struct {
+	LogName          string                 `json:"logName,omitempty"`
+	InsertID         string                 `json:"insertId,omitempty"`
+	Timestamp        time.Time              `json:"timestamp,omitempty"`
+	ReceiveTimestamp time.Time              `json:"receiveTimestamp,omitempty"`
+	Severity         string                 `json:"severity,omitempty"`
+	Resource         Resource               `json:"resource,omitempty"`
+	Labels           map[string]string      `json:"labels,omitempty"`
+	TextPayload      string                 `json:"textPayload,omitempty"`
+	JSONPayload      map[string]interface{} `json:"jsonPayload,omitempty"`
+	ProtoPayload     map[string]interface{} `json:"protoPayload,omitempty"`
+	HTTPRequest      *HTTPRequest           `json:"httpRequest,omitempty"`
+	Trace            string                 `json:"trace,omitempty"`
+	SpanID           string                 `json:"spanId,omitempty"`
+}
+
+// severities are the LogSeverity values in increasing order.
+var severities = []string{"DEFAULT", "DEBUG", "INFO", "NOTICE", "WARNING", "ERROR", "CRITICAL", "ALERT", "EMERGENCY"}
+
+// SeverityLevel returns the numeric level of a severity name, following
+// the LogSeverity enum (DEFAULT=0, DEBUG=100, ..., EMERGENCY=800). Unknown
+// severities are treated as DEFAULT.
+func SeverityLevel(severity string) int {
+	severity = strings.ToUpper(severity)
+	for i, s := range severities {
+		if s == severity {
+			return i * 100
+		}
+	}
+	return 0
+}
+
+// Level returns the numeric severity level of the entry.
+func (e Entry) Level() int {
+	return SeverityLevel(e.Severity)
+}
+
+// Method returns the operation that generated the entry: the API method of
+// audit logs, or the HTTP method and path of request logs.
+func (e Entry) Method() string {
+	if m := str(e.ProtoPayload, "methodName"); m != "" {
+		return m
+	}
+	if e.HTTPRequest != nil && e.HTTPRequest.RequestMethod != "" {
+		path := e.HTTPRequest.RequestURL
+		if i := strings.Index(path, "://"); i >= 0 {
+			path = path[i+3:]
+			if j := strings.Index(path, "/"); j >= 0 {
+				path = path[j:]
+			}
+		}
+		if i := strings.IndexAny(path, "?#"); i >= 0 {
+			path = path[:i]
+		}
+		return e.HTTPRequest.RequestMethod + " " + path
+	}
+	return ""
+}
+
+// Message returns the human readable message of the entry, looking at the
+// text payload, the common message fields of the JSON payload and the
+// status of audit logs.
+func (e Entry) Message() string {
+	if e.TextPayload != "" {
+		return e.TextPayload
+	}
+	for _, key := range []string{"message", "msg", "error", "textPayload"} {
+		if m := str(e.JSONPayload, key); m != "" {
+			return m
+		}
+	}
+	if status, ok := e.ProtoPayload["status"].(map[string]interface{}); ok {
+		if m := str(status, "message"); m != "" {
+			return m
+		}
+	}
+	if e.HTTPRequest != nil && e.HTTPRequest.Status != 0 {
+		return fmt.Sprintf("HTTP %d", e.HTTPRequest.Status)
+	}
+	if m := str(e.ProtoPayload, "resourceName"); m != "" {
+		return m
+	}
+	return ""
+}
+
+// Principal returns the authenticated user or service account of audit
+// log entries.
+func (e Entry) Principal() string {
+	if auth, ok := e.ProtoPayload["authenticationInfo"].(map[string]interface{}); ok {
+		return str(auth, "principalEmail")
+	}
+	return ""
+}
+
+func str(m map[string]interface{}, key string) string {
+	if m == nil {
+		return ""
+	}
+	s, _ := m[key].(string)
+	return s
+}
+
+// Parse decodes log entries from r. The input can be a JSON array, as
+// produced by `gcloud logging read --format=json`, or a stream of JSON
+// objects, like newline-delimited JSON.
+func Parse(r io.Reader) ([]Entry, error) {
+	dec := json.NewDecoder(r)
+	var entries []Entry
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("logging: invalid JSON: %w", err)
+		}
+		raw = json.RawMessage(strings.TrimSpace(string(raw)))
+		if len(raw) > 0 && raw[0] == '[' {
+			var list []Entry
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, fmt.Errorf("logging: invalid log entries: %w", err)
+			}
+			entries = append(entries, list...)
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return nil, fmt.Errorf("logging: invalid log entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+}
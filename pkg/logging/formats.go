@@ -0,0 +1,433 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LineParser parses log entries one line at a time.
+type LineParser interface {
+	// ParseLine parses the line into an entry. If ok is false, the line
+	// is a continuation of the previous entry, like a stack trace.
+	ParseLine(line string) (e Entry, ok bool)
+}
+
+// lineFunc adapts a stateless function into a LineParser.
+type lineFunc func(line string) (Entry, bool)
+
+func (f lineFunc) ParseLine(line string) (Entry, bool) { return f(line) }
+
+// Format is a log format that can be detected and normalized into Entries.
+type Format struct {
+	// Name identifies the format in command line flags.
+	Name string
+	// Guidance is added to the prompt to help the model interpret the
+	// entries of this format.
+	Guidance string
+
+	detect    func(sample string) bool
+	newParser func() LineParser
+}
+
+// Parse reads all entries from r in this format.
+func (f *Format) Parse(r io.Reader) ([]Entry, error) {
+	if f.newParser == nil {
+		return Parse(r)
+	}
+	p := f.newParser()
+	var entries []Entry
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), maxEntrySize)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		e, ok := p.ParseLine(line)
+		if !ok && len(entries) > 0 {
+			last := &entries[len(entries)-1]
+			last.TextPayload += "\n" + line
+			continue
+		}
+		if !ok {
+			e = Entry{TextPayload: line}
+		}
+		entries = append(entries, e)
+	}
+	return entries, s.Err()
+}
+
+// The supported formats.
+var (
+	CloudLogging = &Format{
+		Name:     "gcl",
+		Guidance: "As entradas são logs estruturados do Google Cloud Logging.",
+		// Any JSON not recognized by the previous formats is handled as
+		// Cloud Logging entries, keeping foreign objects as the payload.
+		detect: isJSON,
+	}
+	Journald = &Format{
+		Name: "journald",
+		Guidance: "As entradas vêm do journald do systemd (journalctl -o json). " +
+			"A severidade foi obtida de PRIORITY e o recurso indica a unidade do systemd e o host.",
+		detect: func(sample string) bool {
+			return isJSON(sample) && containsAny(sample, `"__REALTIME_TIMESTAMP"`, `"__CURSOR"`)
+		},
+		newParser: func() LineParser { return lineFunc(parseJournald) },
+	}
+	Syslog = &Format{
+		Name: "syslog",
+		Guidance: "As entradas são mensagens syslog no formato RFC 5424. " +
+			"A severidade foi obtida do campo PRI e os rótulos indicam o host e a aplicação.",
+		detect:    func(sample string) bool { return syslogLine.MatchString(firstLine(sample)) },
+		newParser: func() LineParser { return lineFunc(parseSyslog) },
+	}
+	Kubernetes = &Format{
+		Name: "k8s",
+		Guidance: "As entradas são logs de contêineres do Kubernetes. " +
+			"Mensagens em stderr e com palavras como error ou fatal tiveram a severidade estimada.",
+		detect: func(sample string) bool {
+			line := firstLine(sample)
+			return criLine.MatchString(line) || (isJSON(line) && containsAny(line, `"stream"`) && containsAny(line, `"log"`))
+		},
+		newParser: func() LineParser { return lineFunc(parseKubernetes) },
+	}
+	Nginx = &Format{
+		Name: "nginx",
+		Guidance: "As entradas são logs de acesso ou de erro do nginx ou Apache. " +
+			"Respostas 4xx foram marcadas como WARNING e 5xx como ERROR.",
+		detect: func(sample string) bool {
+			line := firstLine(sample)
+			return accessLine.MatchString(line) || nginxErrorLine.MatchString(line)
+		},
+		newParser: func() LineParser { return lineFunc(parseNginx) },
+	}
+	GoPanic = &Format{
+		Name: "gopanic",
+		Guidance: "A saída contém um panic de um programa em Go. " +
+			"Explique a causa provável com base na mensagem do panic e no stack trace da goroutine.",
+		detect: func(sample string) bool {
+			return containsAny(sample, "\npanic: ", "\nfatal error: ") || strings.HasPrefix(sample, "panic: ") ||
+				strings.HasPrefix(sample, "fatal error: ")
+		},
+		newParser: func() LineParser { return &panicParser{} },
+	}
+	PlainText = &Format{
+		Name:     "text",
+		Guidance: "As entradas são linhas de texto sem formato conhecido; a severidade foi estimada pelo conteúdo.",
+		detect:   func(sample string) bool { return true },
+		// Panics are also grouped in the output of programs that log plain
+		// text before crashing.
+		newParser: func() LineParser { return &panicParser{} },
+	}
+)
+
+// Formats lists the supported formats in detection order.
+var Formats = []*Format{Journald, Kubernetes, CloudLogging, Syslog, Nginx, GoPanic, PlainText}
+
+// LookupFormat returns the format with the given name.
+func LookupFormat(name string) (*Format, error) {
+	var names []string
+	for _, f := range Formats {
+		if f.Name == name {
+			return f, nil
+		}
+		names = append(names, f.Name)
+	}
+	return nil, fmt.Errorf("logging: unknown format %q (available: auto, %s)", name, strings.Join(names, ", "))
+}
+
+// DetectFormat returns the format of the sample, which should contain the
+// first lines of the input. Any JSON that is not recognized is treated as
+// Cloud Logging entries, and anything else as plain text.
+func DetectFormat(sample string) *Format {
+	sample = strings.TrimLeft(sample, " \t\r\n")
+	for _, f := range Formats {
+		if f.detect(sample) {
+			return f
+		}
+	}
+	return PlainText
+}
+
+func isJSON(s string) bool {
+	return strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// syslogSeverities maps the syslog severity values, also used by the
+// journald PRIORITY field, to the Cloud Logging severities.
+var syslogSeverities = []string{"EMERGENCY", "ALERT", "CRITICAL", "ERROR", "WARNING", "NOTICE", "INFO", "DEBUG"}
+
+func syslogSeverity(priority int) string {
+	if priority < 0 || priority >= len(syslogSeverities) {
+		return "DEFAULT"
+	}
+	return syslogSeverities[priority]
+}
+
+// guessSeverity estimates the severity of a text message from the level
+// names it contains, returning def if none is found.
+func guessSeverity(msg, def string) string {
+	upper := strings.ToUpper(msg)
+	for _, l := range []struct{ word, severity string }{
+		{"PANIC", "CRITICAL"}, {"FATAL", "CRITICAL"}, {"CRITICAL", "CRITICAL"},
+		{"ERROR", "ERROR"}, {"ERR ", "ERROR"}, {"EXCEPTION", "ERROR"},
+		{"WARN", "WARNING"}, {"DEBUG", "DEBUG"}, {"INFO", "INFO"},
+	} {
+		if strings.Contains(upper, l.word) {
+			return l.severity
+		}
+	}
+	return def
+}
+
+func parseJournald(line string) (Entry, bool) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return Entry{}, false
+	}
+	get := func(key string) string {
+		switch v := fields[key].(type) {
+		case string:
+			return v
+		case nil:
+			return ""
+		default:
+			// Binary messages are exported as arrays of bytes
+			b, _ := json.Marshal(v)
+			return string(b)
+		}
+	}
+	e := Entry{
+		TextPayload: get("MESSAGE"),
+		Severity:    "DEFAULT",
+		Resource: Resource{Type: "systemd_unit", Labels: labels(
+			"unit", get("_SYSTEMD_UNIT"),
+			"hostname", get("_HOSTNAME"),
+		)},
+		Labels: labels("identifier", get("SYSLOG_IDENTIFIER"), "pid", get("_PID")),
+	}
+	if p, err := strconv.Atoi(get("PRIORITY")); err == nil {
+		e.Severity = syslogSeverity(p)
+	}
+	if us, err := strconv.ParseInt(get("__REALTIME_TIMESTAMP"), 10, 64); err == nil {
+		e.Timestamp = time.UnixMicro(us).UTC()
+	}
+	return e, true
+}
+
+// syslogLine matches RFC 5424 messages:
+// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+var syslogLine = regexp.MustCompile(`^<(\d{1,3})>\d{1,2} (\S+) (\S+) (\S+) (\S+) (\S+) (-|(?:\[(?:[^\]"]|"(?:[^"\\]|\\.)*")*\])+)(?: (.*))?$`)
+
+func parseSyslog(line string) (Entry, bool) {
+	m := syslogLine.FindStringSubmatch(line)
+	if m == nil {
+		return Entry{}, false
+	}
+	pri, _ := strconv.Atoi(m[1])
+	e := Entry{
+		Severity: syslogSeverity(pri % 8),
+		Resource: Resource{Type: "syslog", Labels: labels("hostname", nilValue(m[3]))},
+		Labels: labels("app", nilValue(m[4]), "procid", nilValue(m[5]), "msgid", nilValue(m[6]),
+			"structuredData", nilValue(m[7])),
+		TextPayload: strings.TrimPrefix(m[8], "\ufeff"),
+	}
+	if t, err := time.Parse(time.RFC3339Nano, m[2]); err == nil {
+		e.Timestamp = t
+	}
+	return e, true
+}
+
+// labels returns a map with the non-empty key and value pairs, or nil if
+// all values are empty.
+func labels(kv ...string) map[string]string {
+	var m map[string]string
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] == "" {
+			continue
+		}
+		if m == nil {
+			m = make(map[string]string)
+		}
+		m[kv[i]] = kv[i+1]
+	}
+	return m
+}
+
+// nilValue converts the syslog NILVALUE "-" into an empty string.
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// criLine matches the container runtime (CRI) log format used by the
+// kubelet: TIMESTAMP STREAM TAG MESSAGE
+var criLine = regexp.MustCompile(`^(\d{4}-\d\d-\d\dT\S+) (stdout|stderr) ([FP]) ?(.*)$`)
+
+func parseKubernetes(line string) (Entry, bool) {
+	var stream, msg, ts string
+	if m := criLine.FindStringSubmatch(line); m != nil {
+		ts, stream, msg = m[1], m[2], m[4]
+	} else {
+		// Docker json-file format
+		var d struct {
+			Log    string `json:"log"`
+			Stream string `json:"stream"`
+			Time   string `json:"time"`
+		}
+		if err := json.Unmarshal([]byte(line), &d); err != nil || d.Stream == "" {
+			return Entry{}, false
+		}
+		ts, stream, msg = d.Time, d.Stream, strings.TrimRight(d.Log, "\n")
+	}
+	def := "INFO"
+	if stream == "stderr" {
+		def = "WARNING"
+	}
+	e := Entry{
+		Resource: Resource{Type: "k8s_container"},
+		Labels:   map[string]string{"stream": stream},
+	}
+	// Structured application logs are kept as the JSON payload
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(msg), &payload); err == nil {
+		e.JSONPayload = payload
+		level := ""
+		for _, key := range []string{"severity", "level", "lvl"} {
+			if s, ok := payload[key].(string); ok {
+				level = s
+				break
+			}
+		}
+		e.Severity = guessSeverity(level, def)
+	} else {
+		e.TextPayload = msg
+		e.Severity = guessSeverity(msg, def)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+		e.Timestamp = t
+	}
+	return e, true
+}
+
+// accessLine matches the common and combined access log formats used by
+// nginx and Apache.
+var accessLine = regexp.MustCompile(`^(\S+) \S+ (\S+) \[([^\]]+)\] "(\S+) (\S+)(?: [^"]*)?" (\d{3}) (\d+|-)(?: "([^"]*)" "([^"]*)")?`)
+
+// nginxErrorLine matches the nginx error log format.
+var nginxErrorLine = regexp.MustCompile(`^(\d{4}/\d\d/\d\d \d\d:\d\d:\d\d) \[(\w+)\] \d+#\d+: (.*)$`)
+
+func parseNginx(line string) (Entry, bool) {
+	if m := accessLine.FindStringSubmatch(line); m != nil {
+		status, _ := strconv.Atoi(m[6])
+		e := Entry{
+			Severity: "INFO",
+			Resource: Resource{Type: "http_server"},
+			HTTPRequest: &HTTPRequest{
+				RemoteIP:      m[1],
+				RequestMethod: m[4],
+				RequestURL:    m[5],
+				Status:        status,
+				ResponseSize:  m[7],
+				UserAgent:     m[9],
+			},
+		}
+		switch {
+		case status >= 500:
+			e.Severity = "ERROR"
+		case status >= 400:
+			e.Severity = "WARNING"
+		}
+		if t, err := time.Parse("02/Jan/2006:15:04:05 -0700", m[3]); err == nil {
+			e.Timestamp = t
+		}
+		return e, true
+	}
+	if m := nginxErrorLine.FindStringSubmatch(line); m != nil {
+		e := Entry{
+			Severity:    guessSeverity(m[2], "DEFAULT"),
+			Resource:    Resource{Type: "http_server"},
+			TextPayload: m[3],
+		}
+		if strings.EqualFold(m[2], "crit") || strings.EqualFold(m[2], "emerg") || strings.EqualFold(m[2], "alert") {
+			e.Severity = "CRITICAL"
+		} else if strings.EqualFold(m[2], "notice") {
+			e.Severity = "NOTICE"
+		}
+		if t, err := time.ParseInLocation("2006/01/02 15:04:05", m[1], time.Local); err == nil {
+			e.Timestamp = t
+		}
+		return e, true
+	}
+	return Entry{}, false
+}
+
+// panicParser groups the panic message and the goroutine stack traces
+// that follow it into a single CRITICAL entry. The first line that is not
+// part of a stack trace ends the panic.
+type panicParser struct {
+	inPanic bool
+}
+
+// stackFrame matches the function calls in a stack trace, like
+// main.(*Server).handle(0xc000010000, {0x0, 0x0}).
+var stackFrame = regexp.MustCompile(`^[^\s(]+\(.*\)$`)
+
+func (p *panicParser) ParseLine(line string) (Entry, bool) {
+	if strings.HasPrefix(line, "panic: ") || strings.HasPrefix(line, "fatal error: ") {
+		p.inPanic = true
+		return Entry{Severity: "CRITICAL", Resource: Resource{Type: "go_program"}, TextPayload: line}, true
+	}
+	if p.inPanic && isStackTrace(line) {
+		return Entry{}, false
+	}
+	p.inPanic = false
+	return parsePlain(line)
+}
+
+// isStackTrace reports if the line can be part of the stack traces
+// printed after a panic.
+func isStackTrace(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case trimmed == "", strings.HasPrefix(line, "\t"), stackFrame.MatchString(trimmed):
+		return true
+	case strings.HasPrefix(line, "goroutine ") && strings.Contains(line, " ["):
+		return true
+	}
+	for _, prefix := range []string{"created by ", "exit status ", "[signal ", "...additional frames elided..."} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePlain(line string) (Entry, bool) {
+	return Entry{TextPayload: line, Severity: guessSeverity(line, "DEFAULT")}, true
+}
@@ -0,0 +1,176 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	journaldLog = `{"__CURSOR":"s=1","__REALTIME_TIMESTAMP":"1696939200000000","PRIORITY":"3","_SYSTEMD_UNIT":"nginx.service","_HOSTNAME":"web-1","SYSLOG_IDENTIFIER":"nginx","_PID":"812","MESSAGE":"bind() to 0.0.0.0:80 failed"}
+{"__CURSOR":"s=2","__REALTIME_TIMESTAMP":"1696939201000000","PRIORITY":"6","_SYSTEMD_UNIT":"nginx.service","_HOSTNAME":"web-1","MESSAGE":"Stopped nginx"}
+`
+	syslogLog = `<34>1 2023-10-10T12:00:00.003Z web-1 su - ID47 [exampleSDID@32473 iut="3" eventSource="Application"] 'su root' failed for lonvick on /dev/pts/8
+<165>1 2023-10-10T12:00:01Z web-1 app 1234 - - started
+`
+	criLog = `2023-10-10T12:00:00.123456789Z stdout F {"level":"error","msg":"connection refused"}
+2023-10-10T12:00:01.000000000Z stderr F Traceback (most recent call last):
+2023-10-10T12:00:02.000000000Z stdout F listening on :8080
+`
+	dockerLog = `{"log":"WARN disk almost full\n","stream":"stdout","time":"2023-10-10T12:00:00.000000000Z"}
+`
+	nginxLog = `10.0.0.1 - - [10/Oct/2023:12:00:00 +0000] "GET /api/items?page=2 HTTP/1.1" 200 512 "-" "curl/8.0"
+10.0.0.2 - frank [10/Oct/2023:12:00:01 +0000] "POST /login HTTP/1.1" 401 0 "-" "Mozilla/5.0"
+10.0.0.3 - - [10/Oct/2023:12:00:02 +0000] "GET /api/items HTTP/1.1" 502 157
+2023/10/10 12:00:02 [error] 812#812: *7 connect() failed (111: Connection refused) while connecting to upstream
+`
+	panicLog = `starting server on :8080
+panic: runtime error: invalid memory address or nil pointer dereference
+[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4553b2]
+
+goroutine 1 [running]:
+main.handler(0x0)
+	/src/main.go:12 +0x12
+main.main()
+	/src/main.go:20 +0x25
+exit status 2
+`
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		input string
+		want  *Format
+	}{
+		{journaldLog, Journald},
+		{syslogLog, Syslog},
+		{criLog, Kubernetes},
+		{dockerLog, Kubernetes},
+		{nginxLog, Nginx},
+		{"\n" + nginxLog[strings.Index(nginxLog, "2023/"):], Nginx},
+		{panicLog, GoPanic},
+		{`[{"severity":"ERROR","textPayload":"boom"}]`, CloudLogging},
+		{`{"level":"error","msg":"boom"}`, CloudLogging},
+		{"just some text\n", PlainText},
+	}
+	for _, tc := range tests {
+		if got := DetectFormat(tc.input); got != tc.want {
+			t.Errorf("DetectFormat(%.30q) = %s, want %s", tc.input, got.Name, tc.want.Name)
+		}
+	}
+}
+
+func TestFormatParse(t *testing.T) {
+	tests := []struct {
+		format        *Format
+		input         string
+		wantSeverity  []string
+		wantMessage   string // message of the first entry
+		wantMethod    string // method of the first entry
+		wantTimestamp string // UTC timestamp of the first entry
+	}{
+		{Journald, journaldLog, []string{"ERROR", "INFO"},
+			"bind() to 0.0.0.0:80 failed", "", "12:00:00"},
+		{Syslog, syslogLog, []string{"CRITICAL", "NOTICE"},
+			"'su root' failed for lonvick on /dev/pts/8", "", "12:00:00"},
+		{Kubernetes, criLog, []string{"ERROR", "WARNING", "INFO"},
+			"connection refused", "", "12:00:00"},
+		{Kubernetes, dockerLog, []string{"WARNING"},
+			"WARN disk almost full", "", "12:00:00"},
+		{Nginx, nginxLog, []string{"INFO", "WARNING", "ERROR", "ERROR"},
+			"HTTP 200", "GET /api/items", "12:00:00"},
+		{GoPanic, panicLog, []string{"DEFAULT", "CRITICAL"},
+			"starting server on :8080", "", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.format.Name, func(t *testing.T) {
+			entries, err := tc.format.Parse(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Severity)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.wantSeverity, ",") {
+				t.Fatalf("got severities %v, want %v", got, tc.wantSeverity)
+			}
+			e := entries[0]
+			if got := e.Message(); got != tc.wantMessage {
+				t.Errorf("got message %q, want %q", got, tc.wantMessage)
+			}
+			if got := e.Method(); got != tc.wantMethod {
+				t.Errorf("got method %q, want %q", got, tc.wantMethod)
+			}
+			if tc.wantTimestamp != "" {
+				if got := e.Timestamp.UTC().Format("15:04:05"); got != tc.wantTimestamp {
+					t.Errorf("got timestamp %v, want %v", got, tc.wantTimestamp)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatDetails(t *testing.T) {
+	entries, err := GoPanic.Parse(strings.NewReader(panicLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entries[1].TextPayload; !strings.Contains(got, "goroutine 1 [running]") || !strings.HasSuffix(got, "exit status 2") {
+		t.Errorf("stack trace not grouped with the panic: %q", got)
+	}
+
+	entries, _ = Journald.Parse(strings.NewReader(journaldLog))
+	if r := entries[0].Resource; r.Type != "systemd_unit" || r.Labels["unit"] != "nginx.service" {
+		t.Errorf("got journald resource %+v", r)
+	}
+
+	entries, _ = Syslog.Parse(strings.NewReader(syslogLog))
+	if l := entries[0].Labels; l["app"] != "su" || l["procid"] != "" || !strings.Contains(l["structuredData"], "iut=\"3\"") {
+		t.Errorf("got syslog labels %+v", l)
+	}
+}
+
+func TestPanicFollowedByLogs(t *testing.T) {
+	input := "INFO start\npanic: boom\n\ngoroutine 1 [running]:\nmain.main()\n\t/src/main.go:20 +0x25\n" +
+		"exit status 2\nINFO restarted\nERROR db down\nINFO ok\n"
+	entries, err := PlainText.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Severity)
+	}
+	if want := "INFO,CRITICAL,INFO,ERROR,INFO"; strings.Join(got, ",") != want {
+		t.Fatalf("got severities %v, want %v", got, want)
+	}
+	if !strings.HasSuffix(entries[1].TextPayload, "exit status 2") || entries[2].TextPayload != "INFO restarted" {
+		t.Errorf("panic entry = %q, next entry = %q", entries[1].TextPayload, entries[2].TextPayload)
+	}
+}
+
+func TestLookupFormat(t *testing.T) {
+	if f, err := LookupFormat("k8s"); err != nil || f != Kubernetes {
+		t.Errorf("LookupFormat(k8s) = %v, %v", f, err)
+	}
+	if _, err := LookupFormat("xml"); err == nil {
+		t.Errorf("LookupFormat(xml) returned no error")
+	}
+}
+
+func TestScannerFormat(t *testing.T) {
+	s := NewScanner(strings.NewReader(panicLog))
+	s.now = func() time.Time { return time.Date(2023, 10, 10, 12, 0, 0, 0, time.UTC) }
+	s.SetFormat(GoPanic)
+	var got []string
+	for s.Scan() {
+		got = append(got, s.Entry().Severity)
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "DEFAULT,CRITICAL" {
+		t.Errorf("got severities %v, want [DEFAULT CRITICAL]", got)
+	}
+}
@@ -0,0 +1,177 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxEntrySize is the maximum size of a single entry read by the Scanner.
+const maxEntrySize = 1 << 20
+
+// Scanner reads log entries incrementally from a continuous stream, like
+// the output of `gcloud logging tail --format=json` or `kubectl logs -f`.
+//
+// Entries can be newline-delimited JSON or pretty-printed JSON objects,
+// optionally wrapped in an array. Lines that are not JSON are returned as
+// entries with the line as the text payload and the time it was read as
+// the timestamp. Other formats are read line by line after SetFormat.
+type Scanner struct {
+	r     *bufio.Reader
+	entry Entry
+	err   error
+
+	parser  LineParser
+	pending *Entry // entry waiting for continuation lines
+	eof     bool
+
+	// now returns the current time, used for entries without a timestamp.
+	now func() time.Time
+}
+
+// NewScanner returns a Scanner that reads from r.
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{r: bufio.NewReaderSize(r, 64*1024), now: time.Now}
+}
+
+// SetFormat sets the format of the entries. Formats parsed line by line
+// group continuation lines, like stack traces, with the previous entry
+// while they arrive together.
+func (s *Scanner) SetFormat(f *Format) {
+	s.parser = nil
+	if f != nil && f.newParser != nil {
+		s.parser = f.newParser()
+	}
+}
+
+// Scan advances to the next entry, which will then be available through
+// the Entry method. It returns false at the end of the input or on error.
+func (s *Scanner) Scan() bool {
+	if s.parser != nil {
+		return s.scanLines()
+	}
+	var buf strings.Builder
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			s.err = err
+			return false
+		}
+		if buf.Len() > 0 {
+			// Accumulating a multi-line JSON object, that can only be
+			// complete on lines with a closing brace.
+			buf.WriteString(line)
+			if strings.HasPrefix(strings.TrimSpace(line), "}") {
+				if raw := strings.TrimSuffix(strings.TrimSpace(buf.String()), ","); json.Valid([]byte(raw)) {
+					return s.decode(raw)
+				}
+			}
+			if buf.Len() > maxEntrySize {
+				s.err = errors.New("logging: entry too large")
+				return false
+			}
+		} else {
+			trimmed := strings.TrimSpace(line)
+			switch {
+			case trimmed == "" || trimmed == "[" || trimmed == "]" || trimmed == ",":
+				// Skip empty lines and the array delimiters
+			case strings.HasPrefix(trimmed, "{"):
+				trimmed = strings.TrimSuffix(trimmed, ",")
+				if json.Valid([]byte(trimmed)) {
+					return s.decode(trimmed)
+				}
+				buf.WriteString(line)
+			default:
+				s.entry = Entry{TextPayload: trimmed, Timestamp: s.now()}
+				return true
+			}
+		}
+		if err == io.EOF {
+			if buf.Len() > 0 {
+				s.err = errors.New("logging: incomplete JSON entry at end of input")
+			}
+			return false
+		}
+	}
+}
+
+// scanLines reads the next entry using the line parser. The pending entry
+// is returned as soon as no more input is buffered, so a slow stream is not
+// delayed waiting for continuation lines that may never come.
+func (s *Scanner) scanLines() bool {
+	for {
+		if s.pending != nil && (s.eof || s.r.Buffered() == 0) {
+			return s.emit()
+		}
+		if s.eof {
+			return false
+		}
+		line, err := s.r.ReadString('\n')
+		if err == io.EOF {
+			s.eof = true
+		} else if err != nil {
+			s.err = err
+			return false
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		e, ok := s.parser.ParseLine(line)
+		if !ok && s.pending != nil {
+			s.pending.TextPayload += "\n" + line
+			continue
+		}
+		if !ok {
+			e = Entry{TextPayload: line}
+		}
+		if s.pending == nil {
+			s.pending = &e
+			continue
+		}
+		s.emit()
+		s.pending = &e
+		return true
+	}
+}
+
+// emit makes the pending entry the current one.
+func (s *Scanner) emit() bool {
+	s.entry, s.pending = *s.pending, nil
+	if s.entry.Timestamp.IsZero() {
+		s.entry.Timestamp = s.now()
+	}
+	return true
+}
+
+// decode parses a JSON object as an Entry. Objects that are not Cloud
+// Logging entries are kept as the JSON payload.
+func (s *Scanner) decode(raw string) bool {
+	s.entry = Entry{}
+	if err := json.Unmarshal([]byte(raw), &s.entry); err != nil {
+		s.entry = Entry{}
+	}
+	e := &s.entry
+	if e.TextPayload == "" && e.JSONPayload == nil && e.ProtoPayload == nil && e.HTTPRequest == nil {
+		if err := json.Unmarshal([]byte(raw), &e.JSONPayload); err != nil {
+			e.TextPayload = raw
+		}
+	}
+	if s.entry.Timestamp.IsZero() {
+		s.entry.Timestamp = s.now()
+	}
+	return true
+}
+
+// Entry returns the last entry read by Scan.
+func (s *Scanner) Entry() Entry {
+	return s.entry
+}
+
+// Err returns the first error found by Scan, if any.
+func (s *Scanner) Err() error {
+	return s.err
+}
@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanner(t *testing.T) {
+	now := time.Date(2023, 10, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		input        string
+		wantMessages []string
+		wantErr      bool
+	}{
+		{
+			"ndjson",
+			"{\"severity\":\"INFO\",\"textPayload\":\"one\"}\n{\"severity\":\"ERROR\",\"textPayload\":\"two\"}\n",
+			[]string{"one", "two"},
+			false,
+		},
+		{
+			"pretty printed array",
+			"[\n  {\n    \"textPayload\": \"one\"\n  },\n  {\n    \"jsonPayload\": {\"message\": \"two\"}\n  }\n]\n",
+			[]string{"one", "two"},
+			false,
+		},
+		{
+			"plain text and foreign json",
+			"starting server\n{\"level\":\"error\",\"msg\":\"boom\"}\n",
+			[]string{"starting server", "boom"},
+			false,
+		},
+		{
+			"incomplete",
+			"{\"textPayload\": \"one\"}\n{\n  \"textPayload\":",
+			[]string{"one"},
+			true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewScanner(strings.NewReader(tc.input))
+			s.now = func() time.Time { return now }
+			var got []string
+			for s.Scan() {
+				e := s.Entry()
+				got = append(got, e.Message())
+				if e.Timestamp.IsZero() {
+					t.Errorf("entry %q has no timestamp", e.Message())
+				}
+			}
+			if (s.Err() != nil) != tc.wantErr {
+				t.Errorf("Err() = %v, wantErr %v", s.Err(), tc.wantErr)
+			}
+			if strings.Join(got, "|") != strings.Join(tc.wantMessages, "|") {
+				t.Errorf("got messages %q, want %q", got, tc.wantMessages)
+			}
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	start := time.Date(2023, 10, 10, 12, 0, 0, 0, time.UTC)
+	w := NewWindow(time.Minute, 3)
+	w.Threshold = SeverityLevel("ERROR")
+	w.Cooldown = 30 * time.Second
+
+	for i, msg := range []string{"a", "b", "c", "d"} {
+		if w.Add(Entry{TextPayload: msg}, start.Add(time.Duration(i)*time.Second)) {
+			t.Errorf("INFO entry %q closed the window", msg)
+		}
+	}
+	if w.Expired(start.Add(59 * time.Second)) {
+		t.Errorf("window expired before its duration")
+	}
+	if !w.Expired(start.Add(time.Minute)) {
+		t.Errorf("window did not expire after its duration")
+	}
+	entries, dropped := w.Flush()
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.TextPayload)
+	}
+	if strings.Join(got, "") != "bcd" || dropped != 1 {
+		t.Errorf("got %v entries and %d dropped, want [b c d] and 1", got, dropped)
+	}
+	if w.Len() != 0 || w.Expired(start.Add(time.Hour)) {
+		t.Errorf("window not reset after Flush")
+	}
+
+	alert := start.Add(2 * time.Minute)
+	if !w.Add(Entry{Severity: "ERROR"}, alert) {
+		t.Errorf("ERROR entry did not close the window")
+	}
+	if w.Add(Entry{Severity: "CRITICAL"}, alert.Add(10*time.Second)) {
+		t.Errorf("threshold closed the window during the cooldown")
+	}
+	if !w.Add(Entry{Severity: "ERROR"}, alert.Add(30*time.Second)) {
+		t.Errorf("threshold did not close the window after the cooldown")
+	}
+}
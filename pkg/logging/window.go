@@ -0,0 +1,81 @@
+package logging
+
+import "time"
+
+// Window accumulates entries from a continuous stream, deciding when a
+// new explanation should be produced. Memory is bounded by MaxEntries:
+// when the window is full, the oldest entries are discarded and only
+// counted.
+type Window struct {
+	// Duration is how long the window stays open after its first entry.
+	Duration time.Duration
+	// MaxEntries is the maximum number of entries kept in memory.
+	MaxEntries int
+	// Threshold is the severity level that closes the window immediately,
+	// like SeverityLevel("ERROR"). Zero disables it.
+	Threshold int
+	// Cooldown is the minimum time between two windows closed by the
+	// severity threshold, avoiding one explanation for each entry of a
+	// burst of errors.
+	Cooldown time.Duration
+
+	entries   []Entry // ring buffer with up to MaxEntries
+	next      int     // position of the next entry in the ring buffer
+	dropped   int
+	start     time.Time
+	lastAlert time.Time
+}
+
+// NewWindow returns a Window with the given duration and size.
+func NewWindow(duration time.Duration, maxEntries int) *Window {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &Window{Duration: duration, MaxEntries: maxEntries}
+}
+
+// Add includes the entry in the window, received at the given time. It
+// returns true if the entry crossed the severity threshold and the window
+// should be closed now.
+func (w *Window) Add(e Entry, now time.Time) bool {
+	if w.Len() == 0 && w.dropped == 0 {
+		w.start = now
+	}
+	if len(w.entries) < w.MaxEntries {
+		w.entries = append(w.entries, e)
+	} else {
+		w.entries[w.next] = e
+		w.dropped++
+	}
+	w.next = (w.next + 1) % w.MaxEntries
+	if w.Threshold > 0 && e.Level() >= w.Threshold && now.Sub(w.lastAlert) >= w.Cooldown {
+		w.lastAlert = now
+		return true
+	}
+	return false
+}
+
+// Expired returns true if the window has entries and has been open for
+// longer than its Duration.
+func (w *Window) Expired(now time.Time) bool {
+	return w.Len() > 0 && w.Duration > 0 && now.Sub(w.start) >= w.Duration
+}
+
+// Len returns the number of entries in the window.
+func (w *Window) Len() int {
+	return len(w.entries)
+}
+
+// Flush returns the entries in the window, in the order they were added,
+// and the number of older entries that were discarded. The window is
+// reset to receive new entries.
+func (w *Window) Flush() (entries []Entry, dropped int) {
+	if len(w.entries) < w.MaxEntries {
+		entries = w.entries
+	} else {
+		entries = append(append([]Entry{}, w.entries[w.next:]...), w.entries[:w.next]...)
+	}
+	dropped = w.dropped
+	w.entries, w.next, w.dropped = nil, 0, 0
+	return entries, dropped
+}
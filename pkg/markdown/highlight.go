@@ -0,0 +1,163 @@
+package markdown
+
+import (
+	"strings"
+	"unicode"
+)
+
+// syntax describes the tokens highlighted in the code of a language.
+type syntax struct {
+	comments []string
+	keywords map[string]bool
+	// shell enables highlighting of commands, flags and variables.
+	shell bool
+}
+
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var shellSyntax = &syntax{
+	comments: []string{"#"},
+	keywords: words("if then else elif fi for while until do done case esac in function return export local sudo"),
+	shell:    true,
+}
+
+// syntaxes maps the language of the code blocks to their syntax.
+var syntaxes = map[string]*syntax{
+	"sh":         shellSyntax,
+	"bash":       shellSyntax,
+	"shell":      shellSyntax,
+	"zsh":        shellSyntax,
+	"console":    shellSyntax,
+	"go":         {comments: []string{"//"}, keywords: words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false")},
+	"python":     {comments: []string{"#"}, keywords: words("and as assert break class continue def del elif else except finally for from global if import in is lambda None nonlocal not or pass raise return True False try while with yield")},
+	"javascript": {comments: []string{"//"}, keywords: words("async await break case catch class const continue default delete do else export extends false finally for function if import in instanceof let new null return switch this throw true try typeof var void while yield")},
+	"json":       {keywords: words("true false null")},
+	"yaml":       {comments: []string{"#"}, keywords: words("true false null yes no")},
+	"ini":        {comments: []string{"#", ";"}},
+}
+
+func init() {
+	syntaxes["py"] = syntaxes["python"]
+	syntaxes["js"] = syntaxes["javascript"]
+	syntaxes["yml"] = syntaxes["yaml"]
+	syntaxes["toml"] = syntaxes["ini"]
+	syntaxes[""] = shellSyntax
+}
+
+// highlight returns the code with ANSI colors. Unknown languages are
+// only dimmed; code blocks without a language are highlighted as shell,
+// the most common in the answers.
+func highlight(code, lang string) string {
+	s, ok := syntaxes[strings.ToLower(lang)]
+	if !ok {
+		return style(code, dim)
+	}
+	lines := strings.Split(code, "\n")
+	for i, l := range lines {
+		if lang == "console" {
+			if rest, ok := strings.CutPrefix(l, "$ "); ok {
+				lines[i] = gray + "$ " + reset + s.line(rest)
+			}
+			continue
+		}
+		lines[i] = s.line(l)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// line highlights a line of code.
+func (s *syntax) line(l string) string {
+	var b strings.Builder
+	command := s.shell
+	for i := 0; i < len(l); {
+		rest := l[i:]
+		c := rune(l[i])
+		switch {
+		case s.comment(l, i):
+			b.WriteString(gray + rest + reset)
+			return b.String()
+		case c == '"' || c == '\'' || c == '`':
+			end := stringEnd(l, i)
+			b.WriteString(green + l[i:end] + reset)
+			i, command = end, false
+		case s.shell && c == '$' && i+1 < len(l):
+			end := i + 1
+			if l[end] == '{' {
+				if j := strings.IndexByte(l[end:], '}'); j >= 0 {
+					end += j + 1
+				}
+			} else {
+				for end < len(l) && (isWord(rune(l[end])) || (end == i+1 && strings.ContainsRune("?#@*!$", rune(l[end])))) {
+					end++
+				}
+			}
+			b.WriteString(blue + l[i:end] + reset)
+			i = end
+		case s.shell && (c == '|' || c == ';' || c == '&'):
+			b.WriteByte(l[i])
+			i++
+			command = true
+		case isWord(c) || (s.shell && c == '-') || c == '.' || c == '/':
+			end := i
+			for end < len(l) && (isWord(rune(l[end])) || strings.ContainsRune("-./=:~+", rune(l[end]))) {
+				end++
+			}
+			word := l[i:end]
+			switch {
+			case s.keywords[word]:
+				b.WriteString(magenta + word + reset)
+			case s.shell && command && !strings.Contains(word, "="):
+				b.WriteString(bold + cyan + word + reset)
+				command = false
+			case s.shell && strings.HasPrefix(word, "-"):
+				b.WriteString(yellow + word + reset)
+			case unicode.IsDigit(c):
+				b.WriteString(magenta + word + reset)
+			default:
+				b.WriteString(word)
+			}
+			i = end
+		default:
+			b.WriteByte(l[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// comment returns true if a comment starts at the position. In shell
+// code, # only starts a comment after a space.
+func (s *syntax) comment(l string, i int) bool {
+	for _, c := range s.comments {
+		if strings.HasPrefix(l[i:], c) && (!s.shell || i == 0 || l[i-1] == ' ' || l[i-1] == '\t') {
+			return true
+		}
+	}
+	return false
+}
+
+// stringEnd returns the position after the closing quote of the string
+// starting at i, or the end of the line.
+func stringEnd(l string, i int) int {
+	quote := l[i]
+	for j := i + 1; j < len(l); j++ {
+		if l[j] == '\\' && quote != '\'' {
+			j++
+			continue
+		}
+		if l[j] == quote {
+			return j + 1
+		}
+	}
+	return len(l)
+}
+
+func isWord(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
@@ -0,0 +1,349 @@
+// Package markdown renders the Markdown returned by the models for the
+// terminal, with ANSI styles, syntax highlighted code blocks and lines
+// wrapped to the terminal width.
+//
+// When the output is not a terminal, or the NO_COLOR environment variable
+// is set, the text is rendered without styles, keeping the Markdown
+// markers so that it is still readable as plain text.
+package markdown
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ronoaldo/genai-demos/pkg/readline"
+)
+
+// ANSI escape sequences used by the renderer.
+const (
+	reset     = "\x1b[0m"
+	bold      = "\x1b[1m"
+	dim       = "\x1b[2m"
+	italic    = "\x1b[3m"
+	underline = "\x1b[4m"
+	green     = "\x1b[32m"
+	yellow    = "\x1b[33m"
+	blue      = "\x1b[34m"
+	magenta   = "\x1b[35m"
+	cyan      = "\x1b[36m"
+	gray      = "\x1b[90m"
+)
+
+// Renderer renders Markdown for the terminal.
+type Renderer struct {
+	// Width is the maximum width of the lines. Lines are not wrapped when
+	// it is zero.
+	Width int
+	// Color enables the ANSI styles and the syntax highlighting.
+	Color bool
+}
+
+// NewRenderer returns a Renderer for the file. Styles are enabled only if
+// it is a terminal and NO_COLOR is not set, and lines are wrapped only if
+// it is a terminal.
+func NewRenderer(f *os.File) *Renderer {
+	r := &Renderer{}
+	fd := int(f.Fd())
+	if !readline.IsTerminal(fd) {
+		return r
+	}
+	if r.Width = readline.Width(fd); r.Width == 0 {
+		r.Width = 80
+		if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+			r.Width = cols
+		}
+	}
+	r.Color = os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	return r
+}
+
+var (
+	fencePattern   = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([\\w+-]*)")
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	rulePattern    = regexp.MustCompile(`^\s*((\*\s*){3,}|(-\s*){3,}|(_\s*){3,})$`)
+	listPattern    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	quotePattern   = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	tablePattern   = regexp.MustCompile(`^\s*\|`)
+)
+
+// block is a paragraph, list item, heading or quote waiting to be wrapped.
+type block struct {
+	first, rest string
+	text        []string
+	style       string
+	quote       bool
+}
+
+// Render returns the rendered Markdown, without a trailing newline. When
+// there are no styles nor width, the Markdown is returned as is.
+func (r *Renderer) Render(md string) string {
+	if !r.Color && r.Width <= 0 {
+		return strings.TrimRight(md, "\n")
+	}
+	var out []string
+	var pending *block
+	flush := func() {
+		if pending == nil {
+			return
+		}
+		text := strings.Join(pending.text, " ")
+		if r.Color {
+			text = r.inline(text)
+			if pending.style != "" {
+				text = style(text, pending.style)
+			}
+		}
+		out = append(out, r.wrap(text, pending.first, pending.rest))
+		pending = nil
+	}
+	blank := func() {
+		if len(out) > 0 && out[len(out)-1] != "" {
+			out = append(out, "")
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		if m := fencePattern.FindStringSubmatch(line); m != nil {
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]); i++ {
+				code = append(code, lines[i])
+			}
+			out = append(out, r.code(code, m[1], m[2])...)
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			blank()
+			continue
+		}
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			pending = &block{text: []string{m[2]}}
+			if r.Color {
+				pending.style = bold + cyan
+				if len(m[1]) == 1 {
+					pending.style += underline
+				}
+			} else {
+				pending.first = m[1] + " "
+			}
+			flush()
+			continue
+		}
+		if rulePattern.MatchString(line) {
+			flush()
+			out = append(out, r.rule())
+			continue
+		}
+		if tablePattern.MatchString(line) {
+			flush()
+			out = append(out, line)
+			continue
+		}
+		if m := listPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			indent, marker := strings.Repeat(" ", len(m[1])), m[2]
+			rest := indent + strings.Repeat(" ", utf8.RuneCountInString(marker)+1)
+			if r.Color {
+				if !strings.ContainsAny(marker[len(marker)-1:], ".)") {
+					marker = "•"
+				}
+				marker = yellow + marker + reset
+			}
+			pending = &block{first: indent + marker + " ", rest: rest, text: []string{m[3]}}
+			continue
+		}
+		if m := quotePattern.FindStringSubmatch(line); m != nil {
+			if pending == nil || !pending.quote {
+				flush()
+				prefix := "> "
+				if r.Color {
+					prefix = gray + "│ " + reset
+				}
+				pending = &block{first: prefix, rest: prefix, quote: true}
+				if r.Color {
+					pending.style = italic
+				}
+			}
+			pending.text = append(pending.text, m[1])
+			continue
+		}
+		if pending == nil {
+			pending = &block{}
+		}
+		pending.text = append(pending.text, strings.TrimSpace(line))
+	}
+	flush()
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return strings.Join(out, "\n")
+}
+
+// code renders a code block. Without colors the fences are kept.
+func (r *Renderer) code(lines []string, fence, lang string) []string {
+	if !r.Color {
+		return append(append([]string{fence + lang}, lines...), fence)
+	}
+	var out []string
+	if lang != "" {
+		out = append(out, gray+"  "+lang+reset)
+	}
+	for _, l := range strings.Split(highlight(strings.Join(lines, "\n"), lang), "\n") {
+		out = append(out, "  "+l)
+	}
+	return out
+}
+
+// rule renders a horizontal rule.
+func (r *Renderer) rule() string {
+	if !r.Color {
+		return "---"
+	}
+	width := r.Width
+	if width <= 0 {
+		width = 40
+	}
+	return gray + strings.Repeat("─", width) + reset
+}
+
+var inlinePattern = regexp.MustCompile("`([^`]+)`" +
+	`|\[([^\]]+)\]\(([^)\s]+)\)` +
+	`|\*\*([^*]+)\*\*|__([^_]+)__` +
+	`|\*([^*\s][^*]*)\*|\b_([^_]+)_\b`)
+
+// inline applies the styles of code, links, bold and italics.
+func (r *Renderer) inline(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range inlinePattern.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(s[last:m[0]])
+		group := func(n int) string { return s[m[2*n]:m[2*n+1]] }
+		switch {
+		case m[2] >= 0:
+			b.WriteString(style(group(1), cyan))
+		case m[4] >= 0:
+			text, url := group(2), group(3)
+			if text == url {
+				b.WriteString(style(url, underline+blue))
+			} else {
+				b.WriteString(style(r.inline(text), underline) + " " + style("("+url+")", gray))
+			}
+		case m[8] >= 0:
+			b.WriteString(style(r.inline(group(4)), bold))
+		case m[10] >= 0:
+			b.WriteString(style(r.inline(group(5)), bold))
+		case m[12] >= 0:
+			b.WriteString(style(r.inline(group(6)), italic))
+		case m[14] >= 0:
+			b.WriteString(style(r.inline(group(7)), italic))
+		}
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// style wraps the text with the escape sequence, restoring it after any
+// reset inside the text, so that styles can be nested.
+func style(s, code string) string {
+	return code + strings.ReplaceAll(s, reset, reset+code) + reset
+}
+
+var escapePattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// visibleWidth returns the number of columns used by the text, ignoring
+// the escape sequences.
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(escapePattern.ReplaceAllString(s, ""))
+}
+
+// wrap breaks the text in lines of at most Width columns, starting with
+// the first prefix and indenting the next lines with rest. The styles
+// active at the end of a line are restored after the indentation.
+func (r *Renderer) wrap(text, first, rest string) string {
+	if r.Width <= 0 {
+		return first + text
+	}
+	var b strings.Builder
+	b.WriteString(first)
+	col, lineStart := visibleWidth(first), true
+	active := ""
+	for _, word := range strings.Fields(text) {
+		w := visibleWidth(word)
+		if !lineStart && col+1+w > r.Width {
+			if active != "" {
+				b.WriteString(reset)
+			}
+			b.WriteString("\n" + rest + active)
+			col, lineStart = visibleWidth(rest), true
+		}
+		if !lineStart {
+			b.WriteString(" ")
+			col++
+		}
+		b.WriteString(word)
+		col += w
+		lineStart = false
+		for _, seq := range escapePattern.FindAllString(word, -1) {
+			if seq == reset {
+				active = ""
+			} else {
+				active += seq
+			}
+		}
+	}
+	return b.String()
+}
+
+// Box draws the text inside a box with the title, like the warning shown
+// before the answers. Lines are wrapped when the box does not fit the
+// width, but the box grows to fit words wider than it.
+func (r *Renderer) Box(title, text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	inner := visibleWidth(title) + 4
+	for _, l := range lines {
+		inner = max(inner, visibleWidth(l))
+	}
+	if r.Width > 0 && inner+4 > r.Width {
+		inner = max(r.Width-4, 10)
+		wrapped := &Renderer{Width: inner}
+		var split []string
+		for _, l := range lines {
+			split = append(split, strings.Split(wrapped.wrap(l, "", ""), "\n")...)
+		}
+		lines = split
+		for _, l := range lines {
+			inner = max(inner, visibleWidth(l))
+		}
+	}
+
+	h, v, corners := "-", "|", [4]string{"+", "+", "+", "+"}
+	border, titleStyle := "", ""
+	if r.Color {
+		h, v, corners = "─", "│", [4]string{"┌", "┐", "└", "┘"}
+		border, titleStyle = yellow, bold+yellow
+	}
+	paint := func(s, code string) string {
+		if code == "" {
+			return s
+		}
+		return code + s + reset
+	}
+	var b strings.Builder
+	fill := inner + 2 - visibleWidth(title) - 4
+	b.WriteString(paint(corners[0]+h+h+"[", border) + paint(title, titleStyle) +
+		paint("]"+strings.Repeat(h, max(fill, 0))+corners[1], border) + "\n")
+	for _, l := range lines {
+		pad := strings.Repeat(" ", max(inner-visibleWidth(l), 0))
+		b.WriteString(paint(v, border) + " " + l + pad + " " + paint(v, border) + "\n")
+	}
+	b.WriteString(paint(corners[2]+strings.Repeat(h, inner+2)+corners[3], border) + "\n")
+	return b.String()
+}
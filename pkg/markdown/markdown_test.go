@@ -0,0 +1,131 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+const answer = "# Disco\n\nUse **du** e `df -h`, veja [o manual](https://example.com/df).\n\n" +
+	"* primeiro item\n* segundo item com texto longo\n\n```bash\ndu -sh ~ # total\n```\n"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		r        *Renderer
+		md       string
+		want     string
+		contains []string
+	}{
+		{
+			"plain without width",
+			&Renderer{},
+			answer,
+			strings.TrimRight(answer, "\n"),
+			nil,
+		},
+		{
+			"plain wrapped",
+			&Renderer{Width: 20},
+			answer,
+			"# Disco\n\nUse **du** e `df\n-h`, veja [o\nmanual](https://example.com/df).\n\n" +
+				"* primeiro item\n* segundo item com\n  texto longo\n\n```bash\ndu -sh ~ # total\n```",
+			nil,
+		},
+		{
+			"color",
+			&Renderer{Width: 80, Color: true},
+			answer,
+			"",
+			[]string{
+				bold + cyan + underline + "Disco" + reset,
+				"Use " + bold + "du" + reset + " e " + cyan + "df -h" + reset,
+				underline + "o manual" + reset + " " + gray + "(https://example.com/df)" + reset,
+				yellow + "•" + reset + " primeiro item",
+				gray + "  bash" + reset,
+				"  " + bold + cyan + "du" + reset + " " + yellow + "-sh" + reset + " ~ " + gray + "# total" + reset,
+			},
+		},
+		{
+			"nested styles",
+			&Renderer{Color: true},
+			"**negrito com `código`**",
+			bold + "negrito com " + cyan + "código" + reset + bold + reset,
+			nil,
+		},
+		{
+			"quote and rule",
+			&Renderer{Width: 10},
+			"> linha um\n> linha dois\n\n***",
+			"> linha um\n> linha\n> dois\n\n---",
+			nil,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.r.Render(tc.md)
+			if tc.want != "" && got != tc.want {
+				t.Errorf("Render() =\n%q\nwant\n%q", got, tc.want)
+			}
+			for _, want := range tc.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("Render() does not contain %q:\n%q", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWrapKeepsStyles(t *testing.T) {
+	r := &Renderer{Width: 12, Color: true}
+	got := r.wrap(style("um texto em negrito", bold), "", "  ")
+	want := bold + "um texto em" + reset + "\n  " + bold + "negrito" + reset
+	if got != want {
+		t.Errorf("wrap() = %q, want %q", got, want)
+	}
+	for _, line := range strings.Split(got, "\n") {
+		if w := visibleWidth(line); w > r.Width {
+			t.Errorf("line %q has width %d", line, w)
+		}
+	}
+}
+
+func TestBox(t *testing.T) {
+	text := "Este é um conteúdo gerado por IA.\nRevise quaisquer comandos antes de executá-los."
+	tests := []struct {
+		name string
+		r    *Renderer
+		want string
+	}{
+		{"fits", &Renderer{}, `+--[Aviso]----------------------------------------+
+| Este é um conteúdo gerado por IA.               |
+| Revise quaisquer comandos antes de executá-los. |
++-------------------------------------------------+
+`},
+		{"narrow", &Renderer{Width: 30}, `+--[Aviso]-------------------+
+| Este é um conteúdo gerado  |
+| por IA.                    |
+| Revise quaisquer comandos  |
+| antes de executá-los.      |
++----------------------------+
+`},
+		{"word wider than the box", &Renderer{Width: 12}, `+--[Aviso]-----+
+| Este é um    |
+| conteúdo     |
+| gerado por   |
+| IA.          |
+| Revise       |
+| quaisquer    |
+| comandos     |
+| antes de     |
+| executá-los. |
++--------------+
+`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.r.Box("Aviso", text); got != tc.want {
+				t.Errorf("Box() =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Package rank selects the best among multiple candidates returned by the
+// text generation models when CandidateCount is greater than one.
+//
+// Candidates are scored by one or more Scorers, such as the safety
+// attributes, the answer length, majority voting over normalized answers,
+// the distance to the embedding centroid or an LLM acting as a judge. The
+// weighted scores are combined into a ranking.
+package rank
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// Scorer assigns a score between 0 and 1 to each candidate, where
+// higher is better. The returned slice must have one score per candidate.
+type Scorer interface {
+	Name() string
+	Score(ctx context.Context, prompt string, candidates []text.Prediction) ([]float64, error)
+}
+
+// Weighted is a Scorer with a weight applied to its scores when combined
+// by the Ranker.
+type Weighted struct {
+	Scorer
+	Weight float64
+}
+
+// Candidate is a ranked prediction.
+type Candidate struct {
+	// Index is the position of the prediction in the model response.
+	Index      int                `json:"index"`
+	Prediction text.Prediction    `json:"prediction"`
+	Score      float64            `json:"score"`
+	Scores     map[string]float64 `json:"scores"`
+}
+
+// Ranker combines the scores of multiple Scorers into a ranking.
+type Ranker struct {
+	Scorers []Weighted
+}
+
+// NewRanker returns a Ranker using all scorers with weight 1.
+func NewRanker(scorers ...Scorer) *Ranker {
+	r := &Ranker{}
+	for _, s := range scorers {
+		r.Scorers = append(r.Scorers, Weighted{Scorer: s, Weight: 1})
+	}
+	return r
+}
+
+// Rank scores the candidates and returns them sorted from best to worst.
+// The combined score is the weighted average of the individual scores.
+// Blocked candidates are always ranked last.
+func (r *Ranker) Rank(ctx context.Context, prompt string, predictions []text.Prediction) ([]Candidate, error) {
+	candidates := make([]Candidate, len(predictions))
+	for i, p := range predictions {
+		candidates[i] = Candidate{Index: i, Prediction: p, Scores: make(map[string]float64)}
+	}
+	var total float64
+	for _, s := range r.Scorers {
+		scores, err := s.Score(ctx, prompt, predictions)
+		if err != nil {
+			return nil, fmt.Errorf("rank: %s: %w", s.Name(), err)
+		}
+		if len(scores) != len(predictions) {
+			return nil, fmt.Errorf("rank: %s: got %d scores for %d candidates", s.Name(), len(scores), len(predictions))
+		}
+		for i, score := range scores {
+			candidates[i].Scores[s.Name()] = score
+			candidates[i].Score += s.Weight * score
+		}
+		total += s.Weight
+	}
+	for i := range candidates {
+		if total > 0 {
+			candidates[i].Score /= total
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		bi, bj := candidates[i].Prediction.SafetyAttributes.Blocked, candidates[j].Prediction.SafetyAttributes.Blocked
+		if bi != bj {
+			return !bi
+		}
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates, nil
+}
+
+// Best is a helper that ranks the predictions and returns the best one.
+func (r *Ranker) Best(ctx context.Context, prompt string, predictions []text.Prediction) (*Candidate, error) {
+	if len(predictions) == 0 {
+		return nil, fmt.Errorf("rank: no candidates to rank")
+	}
+	ranking, err := r.Rank(ctx, prompt, predictions)
+	if err != nil {
+		return nil, err
+	}
+	return &ranking[0], nil
+}
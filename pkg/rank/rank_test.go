@@ -0,0 +1,118 @@
+package rank
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+	"github.com/ronoaldo/genai-demos/pkg/text/texttest"
+)
+
+func predictions(contents ...string) []text.Prediction {
+	var preds []text.Prediction
+	for _, c := range contents {
+		preds = append(preds, text.Prediction{Content: c})
+	}
+	return preds
+}
+
+type fakeEmbedder map[string][]float64
+
+func (f fakeEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]float64, error) {
+	var embeddings [][]float64
+	for _, t := range texts {
+		embeddings = append(embeddings, f[t])
+	}
+	return embeddings, nil
+}
+
+func TestRank(t *testing.T) {
+	blocked := text.Prediction{Content: "ls", SafetyAttributes: text.SafetyAttributes{Blocked: true}}
+	tests := []struct {
+		name      string
+		scorers   []Scorer
+		preds     []text.Prediction
+		wantIndex int
+	}{
+		{
+			"majority",
+			[]Scorer{Majority{}},
+			predictions("Use rm.", "use ls", "Use LS!"),
+			1,
+		},
+		{
+			"majority ignores blocked",
+			[]Scorer{Majority{}},
+			append(predictions("ls", "ls"), blocked, blocked, blocked),
+			0,
+		},
+		{
+			"safety",
+			[]Scorer{Safety{}},
+			[]text.Prediction{
+				{Content: "a", SafetyAttributes: text.SafetyAttributes{Scores: []float64{0.9}}},
+				{Content: "b", SafetyAttributes: text.SafetyAttributes{Scores: []float64{0.1, 0.2}}},
+			},
+			1,
+		},
+		{
+			"length",
+			[]Scorer{Length{Target: 5}},
+			predictions("a", "abcdef", "abcdefghijklmnop"),
+			1,
+		},
+		{
+			"centroid",
+			[]Scorer{Centroid{Embedder: fakeEmbedder{
+				"a": {1, 0}, "b": {0.9, 0.1}, "c": {0, 1},
+			}}},
+			predictions("a", "b", "c"),
+			1,
+		},
+		{
+			"judge",
+			[]Scorer{Judge{Generator: &texttest.Generator{Replies: []string{"Answer 1: 3\nAnswer 2: 9\n3: 5"}}}},
+			predictions("a", "b", "c"),
+			1,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRanker(tc.scorers...)
+			best, err := r.Best(context.Background(), "question", tc.preds)
+			if err != nil {
+				t.Fatalf("Best() error = %v", err)
+			}
+			if best.Index != tc.wantIndex {
+				t.Errorf("got best candidate %d (%v), want %d", best.Index, best.Scores, tc.wantIndex)
+			}
+		})
+	}
+}
+
+func TestWeights(t *testing.T) {
+	preds := []text.Prediction{
+		{Content: "x", SafetyAttributes: text.SafetyAttributes{Scores: []float64{0.5}}},
+		{Content: "y"},
+		{Content: "x", SafetyAttributes: text.SafetyAttributes{Scores: []float64{0.5}}},
+	}
+	r := &Ranker{Scorers: []Weighted{{Majority{}, 3}, {Safety{}, 1}}}
+	ranking, err := r.Rank(context.Background(), "q", preds)
+	if err != nil {
+		t.Fatalf("Rank() error = %v", err)
+	}
+	// x: (3*2/3 + 1*0.5)/4 = 0.625; y: (3*1/3 + 1*1)/4 = 0.5
+	if ranking[0].Prediction.Content != "x" || ranking[2].Prediction.Content != "y" {
+		t.Errorf("unexpected ranking: %+v", ranking)
+	}
+	if got, want := ranking[0].Score, 0.625; got != want {
+		t.Errorf("got score %v, want %v", got, want)
+	}
+}
+
+func TestConsensus(t *testing.T) {
+	answer, votes := Majority{}.Consensus(predictions("Linus Torvalds.", "linus torvalds", "Richard Stallman"))
+	if answer != "linus torvalds" || votes != 2 {
+		t.Errorf("got consensus %q with %d votes, want %q with 2", answer, votes, "linus torvalds")
+	}
+}
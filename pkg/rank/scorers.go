@@ -0,0 +1,230 @@
+package rank
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// Safety scores candidates by their safety attributes: blocked candidates
+// get zero, and the others get one minus their highest category score.
+type Safety struct{}
+
+// Name implements Scorer.
+func (Safety) Name() string { return "safety" }
+
+// Score implements Scorer.
+func (Safety) Score(ctx context.Context, prompt string, candidates []text.Prediction) ([]float64, error) {
+	scores := make([]float64, len(candidates))
+	for i, c := range candidates {
+		if c.SafetyAttributes.Blocked {
+			continue
+		}
+		worst := 0.0
+		for _, s := range c.SafetyAttributes.Scores {
+			worst = math.Max(worst, s)
+		}
+		scores[i] = 1 - worst
+	}
+	return scores, nil
+}
+
+// Length scores candidates by how close their length is to Target
+// characters. If Target is zero, the median length of the candidates is
+// used, favoring answers that are neither too short nor too long.
+type Length struct {
+	Target int
+}
+
+// Name implements Scorer.
+func (Length) Name() string { return "length" }
+
+// Score implements Scorer.
+func (l Length) Score(ctx context.Context, prompt string, candidates []text.Prediction) ([]float64, error) {
+	target := float64(l.Target)
+	if target <= 0 {
+		lengths := make([]float64, len(candidates))
+		for i, c := range candidates {
+			lengths[i] = float64(len(c.Content))
+		}
+		sort.Float64s(lengths)
+		target = lengths[len(lengths)/2]
+	}
+	scores := make([]float64, len(candidates))
+	for i, c := range candidates {
+		if target == 0 {
+			scores[i] = 1
+			continue
+		}
+		scores[i] = 1 / (1 + math.Abs(float64(len(c.Content))-target)/target)
+	}
+	return scores, nil
+}
+
+// Majority implements self-consistency voting: each candidate is scored
+// by the fraction of candidates with the same normalized answer.
+type Majority struct {
+	// Normalize is used to compare answers. If nil, Normalize is used.
+	Normalize func(string) string
+}
+
+// Name implements Scorer.
+func (Majority) Name() string { return "majority" }
+
+// Score implements Scorer.
+func (m Majority) Score(ctx context.Context, prompt string, candidates []text.Prediction) ([]float64, error) {
+	votes := m.votes(candidates)
+	scores := make([]float64, len(candidates))
+	for i, c := range candidates {
+		scores[i] = float64(votes[m.normalize(c.Content)]) / float64(len(candidates))
+	}
+	return scores, nil
+}
+
+// Consensus returns the most voted normalized answer among the candidates
+// that are not blocked, and the number of votes it received.
+func (m Majority) Consensus(candidates []text.Prediction) (answer string, votes int) {
+	counts := m.votes(candidates)
+	for _, c := range candidates {
+		n := m.normalize(c.Content)
+		if counts[n] > votes {
+			answer, votes = n, counts[n]
+		}
+	}
+	return answer, votes
+}
+
+func (m Majority) votes(candidates []text.Prediction) map[string]int {
+	votes := make(map[string]int)
+	for _, c := range candidates {
+		if c.SafetyAttributes.Blocked {
+			continue
+		}
+		votes[m.normalize(c.Content)]++
+	}
+	return votes
+}
+
+func (m Majority) normalize(s string) string {
+	if m.Normalize != nil {
+		return m.Normalize(s)
+	}
+	return Normalize(s)
+}
+
+// Normalize returns the answer in lower case, without punctuation and
+// with consecutive spaces collapsed, so that trivially different answers
+// are counted as the same vote.
+func Normalize(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) {
+			return ' '
+		}
+		return unicode.ToLower(r)
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Centroid scores candidates by the cosine similarity of their embeddings
+// to the centroid of all candidate embeddings. It is a softer form of
+// majority voting that works for long, free-form answers.
+type Centroid struct {
+	Embedder text.Embedder
+}
+
+// Name implements Scorer.
+func (Centroid) Name() string { return "centroid" }
+
+// Score implements Scorer.
+func (c Centroid) Score(ctx context.Context, prompt string, candidates []text.Prediction) ([]float64, error) {
+	texts := make([]string, len(candidates))
+	for i, p := range candidates {
+		texts[i] = p.Content
+	}
+	embeddings, err := c.Embedder.EmbedTexts(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(candidates) {
+		return nil, fmt.Errorf("got %d embeddings for %d candidates", len(embeddings), len(candidates))
+	}
+	centroid := make([]float64, len(embeddings[0]))
+	for _, e := range embeddings {
+		for i := range centroid {
+			if i < len(e) {
+				centroid[i] += e[i] / float64(len(embeddings))
+			}
+		}
+	}
+	scores := make([]float64, len(candidates))
+	for i, e := range embeddings {
+		// Map the cosine similarity from [-1, 1] into [0, 1]
+		scores[i] = (text.CosineSimilarity(e, centroid) + 1) / 2
+	}
+	return scores, nil
+}
+
+// judgeContext is the prompt used by the Judge scorer. It is formatted
+// with the original prompt and the numbered list of candidates.
+var judgeContext = `You are a strict judge evaluating answers to the same question.
+Rate each answer from 0 to 10 for correctness, relevance and clarity.
+Reply only with one line per answer in the format "N: SCORE".
+
+Question: %s
+
+%s
+Ratings:
+`
+
+var judgeLine = regexp.MustCompile(`(?im)^\s*(?:answer\s*)?\[?(\d+)\]?\s*[:=-]\s*(\d+(?:\.\d+)?)`)
+
+// Judge uses a text generation model to rate the candidates.
+type Judge struct {
+	Generator text.Generator
+	Params    text.Parameters
+}
+
+// Name implements Scorer.
+func (Judge) Name() string { return "judge" }
+
+// Score implements Scorer.
+func (j Judge) Score(ctx context.Context, prompt string, candidates []text.Prediction) ([]float64, error) {
+	var sb strings.Builder
+	for i, c := range candidates {
+		fmt.Fprintf(&sb, "Answer %d:\n%s\n\n", i+1, strings.TrimSpace(c.Content))
+	}
+	judgePrompt := fmt.Sprintf(judgeContext, prompt, sb.String())
+	resp, err := j.Generator.GenerateText(ctx, "%s", judgePrompt, j.Params)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Predictions) == 0 {
+		return nil, fmt.Errorf("judge returned no predictions")
+	}
+	return ParseRatings(resp.Predictions[0].Content, len(candidates)), nil
+}
+
+// ParseRatings parses the "N: SCORE" lines returned by the judge into
+// scores between 0 and 1. Candidates without a rating get zero.
+func ParseRatings(s string, n int) []float64 {
+	scores := make([]float64, n)
+	for _, m := range judgeLine.FindAllStringSubmatch(s, -1) {
+		i, err := strconv.Atoi(m[1])
+		if err != nil || i < 1 || i > n {
+			continue
+		}
+		v, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		scores[i-1] = math.Min(v, 10) / 10
+	}
+	return scores
+}
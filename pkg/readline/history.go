@@ -0,0 +1,97 @@
+package readline
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// History keeps the lines entered by the user, optionally persisted to a
+// file with one line per entry.
+type History struct {
+	// Max is the maximum number of entries kept. Older entries are
+	// discarded, also from the file.
+	Max int
+
+	path    string
+	entries []string
+}
+
+// LoadHistory reads the history from the file. A missing file is not an
+// error, and it is created when the first line is added. An empty path
+// keeps the history only in memory.
+func LoadHistory(path string, max int) (*History, error) {
+	h := &History{Max: max, path: path}
+	if path == "" {
+		return h, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if line := s.Text(); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	h.trim()
+	return h, s.Err()
+}
+
+// Entries returns a copy of the entries, from oldest to newest.
+func (h *History) Entries() []string {
+	return append([]string{}, h.entries...)
+}
+
+// Add appends the line to the history and to the file. Empty lines and
+// repetitions of the last line are ignored.
+func (h *History) Add(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.Contains(line, "\n") ||
+		(len(h.entries) > 0 && h.entries[len(h.entries)-1] == line) {
+		return nil
+	}
+	h.entries = append(h.entries, line)
+	if h.path == "" {
+		h.trim()
+		return nil
+	}
+	if h.trim() {
+		return h.save()
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// trim discards the oldest entries above Max, returning true if any entry
+// was discarded.
+func (h *History) trim() bool {
+	if h.Max <= 0 || len(h.entries) <= h.Max {
+		return false
+	}
+	h.entries = h.entries[len(h.entries)-h.Max:]
+	return true
+}
+
+// save rewrites the history file with the current entries.
+func (h *History) save() error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(h.path, []byte(strings.Join(h.entries, "\n")+"\n"), 0600)
+}
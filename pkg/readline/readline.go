@@ -0,0 +1,265 @@
+// Package readline implements a minimal line editor for interactive
+// command line programs, with cursor movement, word and line deletion and
+// history navigation using the usual Emacs-like keys.
+//
+// When the input is not a terminal, lines are read without editing, so the
+// same program works with piped input.
+package readline
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ErrInterrupt is returned by ReadLine when Ctrl-C is pressed.
+var ErrInterrupt = errors.New("readline: interrupted")
+
+// Editor reads lines from the input, echoing the edited line to the output.
+type Editor struct {
+	// Prompt is printed before each line.
+	Prompt string
+	// History, if not nil, is navigated with the up and down arrows. Lines
+	// are not added automatically.
+	History *History
+
+	in       *bufio.Reader
+	out      io.Writer
+	fd       int
+	terminal bool
+}
+
+// New returns an Editor reading from the file, usually os.Stdin. Line
+// editing is enabled only if the file is a terminal.
+func New(in *os.File, out io.Writer) *Editor {
+	e := NewEditor(in, out)
+	e.fd = int(in.Fd())
+	e.terminal = IsTerminal(e.fd)
+	return e
+}
+
+// NewEditor returns an Editor that processes the editing keys from r
+// without changing the terminal mode.
+func NewEditor(r io.Reader, w io.Writer) *Editor {
+	return &Editor{in: bufio.NewReader(r), out: w, fd: -1}
+}
+
+// Terminal returns true if the input is an interactive terminal.
+func (e *Editor) Terminal() bool {
+	return e.terminal
+}
+
+// ReadLine reads the next line, without the line terminator. It returns
+// io.EOF at the end of the input or when Ctrl-D is pressed on an empty
+// line, and ErrInterrupt when Ctrl-C is pressed.
+func (e *Editor) ReadLine() (string, error) {
+	if e.fd >= 0 && !e.terminal {
+		// Piped input is read without the prompt or editing
+		line, err := e.in.ReadString('\n')
+		if err == io.EOF && line != "" {
+			err = nil
+		}
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	if e.terminal {
+		restore, err := makeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+		defer restore()
+	}
+	return e.edit()
+}
+
+// Keys recognized by the editor, including the escape sequences of the
+// arrows and other special keys.
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlH     = 8
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyBackspace = 127
+
+	keyUp rune = unicode.MaxRune + iota
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// edit runs the editing loop until a line is entered.
+func (e *Editor) edit() (string, error) {
+	var line []rune
+	pos := 0
+	// The history is browsed from a copy, so that edits to recalled lines
+	// don't change it.
+	var history []string
+	if e.History != nil {
+		history = append(e.History.Entries(), "")
+	}
+	current := len(history) - 1
+
+	e.refresh(line, pos)
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				break
+			}
+			return "", err
+		}
+		switch key {
+		case keyEnter, '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(line), nil
+		case keyCtrlC:
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", ErrInterrupt
+		case keyCtrlD:
+			if len(line) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			fallthrough
+		case keyDelete:
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+		case keyBackspace, keyCtrlH:
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+		case keyLeft, keyCtrlB:
+			if pos > 0 {
+				pos--
+			}
+		case keyRight, keyCtrlF:
+			if pos < len(line) {
+				pos++
+			}
+		case keyHome, keyCtrlA:
+			pos = 0
+		case keyEnd, keyCtrlE:
+			pos = len(line)
+		case keyCtrlK:
+			line = line[:pos]
+		case keyCtrlU:
+			line, pos = line[pos:], 0
+		case keyCtrlW:
+			start := pos
+			for start > 0 && line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && line[start-1] != ' ' {
+				start--
+			}
+			line, pos = append(line[:start], line[pos:]...), start
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyUp, keyCtrlP, keyDown, keyCtrlN:
+			next := current - 1
+			if key == keyDown || key == keyCtrlN {
+				next = current + 1
+			}
+			if next < 0 || next >= len(history) {
+				break
+			}
+			history[current] = string(line)
+			current = next
+			line = []rune(history[current])
+			pos = len(line)
+		case keyUnknown, keyEscape:
+		default:
+			if unicode.IsPrint(key) {
+				line = append(line[:pos], append([]rune{key}, line[pos:]...)...)
+				pos++
+			}
+		}
+		e.refresh(line, pos)
+	}
+	return string(line), nil
+}
+
+// readKey reads a key, decoding the escape sequences of special keys.
+func (e *Editor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+	// Escape sequences are ESC [ or ESC O, followed by optional numeric
+	// parameters and a final character.
+	next, _, err := e.in.ReadRune()
+	if err != nil {
+		return keyEscape, nil
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+	var params strings.Builder
+	for {
+		c, _, err := e.in.ReadRune()
+		if err != nil {
+			return keyUnknown, nil
+		}
+		if (c >= '0' && c <= '9') || c == ';' {
+			params.WriteRune(c)
+			continue
+		}
+		switch c {
+		case 'A':
+			return keyUp, nil
+		case 'B':
+			return keyDown, nil
+		case 'C':
+			return keyRight, nil
+		case 'D':
+			return keyLeft, nil
+		case 'H':
+			return keyHome, nil
+		case 'F':
+			return keyEnd, nil
+		case '~':
+			switch params.String() {
+			case "1", "7":
+				return keyHome, nil
+			case "4", "8":
+				return keyEnd, nil
+			case "3":
+				return keyDelete, nil
+			}
+		}
+		return keyUnknown, nil
+	}
+}
+
+// refresh redraws the prompt and the line, placing the cursor at pos.
+func (e *Editor) refresh(line []rune, pos int) {
+	var b strings.Builder
+	b.WriteString("\r")
+	b.WriteString(e.Prompt)
+	b.WriteString(string(line))
+	b.WriteString("\x1b[K")
+	if back := len(line) - pos; back > 0 {
+		fmt.Fprintf(&b, "\x1b[%dD", back)
+	}
+	io.WriteString(e.out, b.String())
+}
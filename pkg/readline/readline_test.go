@@ -0,0 +1,94 @@
+package readline
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditor(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"plain", "ls -la\r", "ls -la", nil},
+		{"backspace", "lss\x7f -l\r", "ls -l", nil},
+		{"insert after left arrows", "l -l\x1b[D\x1b[D\x1b[Ds\r", "ls -l", nil},
+		{"home and end", "s -l\x1b[Hl\x1b[F\r", "ls -l", nil},
+		{"ctrl-a and ctrl-k", "echo ok\x01\x06\x06\x06\x06\x0b\r", "echo", nil},
+		{"ctrl-u", "rm -rf /\x15ls\r", "ls", nil},
+		{"ctrl-w", "git commit --amend\x17\x17status\r", "git status", nil},
+		{"delete key", "lxs\x1b[D\x1b[D\x1b[3~\r", "ls", nil},
+		{"accented characters", "configuração\x7fo\r", "configuração", nil},
+		{"ctrl-c", "ls\x03", "", ErrInterrupt},
+		{"ctrl-d on empty line", "\x04", "", io.EOF},
+		{"ctrl-d deletes", "ab\x1b[D\x04\r", "a", nil},
+		{"end of input", "ls", "ls", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e := NewEditor(strings.NewReader(tc.input), io.Discard)
+			got, err := e.ReadLine()
+			if err != tc.wantErr {
+				t.Fatalf("ReadLine() error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("ReadLine() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	h, _ := LoadHistory("", 10)
+	h.Add("first")
+	h.Add("second")
+	tests := []struct {
+		input, want string
+	}{
+		{"\x1b[A\r", "second"},
+		{"\x1b[A\x1b[A\r", "first"},
+		{"\x1b[A\x1b[A\x1b[A\x1b[A\r", "first"},
+		{"\x1b[A\x1b[A\x1b[B\r", "second"},
+		{"new\x1b[A\x1b[B\r", "new"},
+		{"\x10!\r", "second!"},
+	}
+	for _, tc := range tests {
+		e := NewEditor(strings.NewReader(tc.input), io.Discard)
+		e.History = h
+		if got, _ := e.ReadLine(); got != tc.want {
+			t.Errorf("ReadLine(%q) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+	if got := h.Entries(); len(got) != 2 || got[1] != "second" {
+		t.Errorf("editing changed the history: %q", got)
+	}
+}
+
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "history")
+	h, err := LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"a", "b", "b", "", "c"} {
+		if err := h.Add(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b, _ := os.ReadFile(path); string(b) != "a\nb\nc\n" {
+		t.Errorf("got history file %q", b)
+	}
+	h.Add("d")
+	h, err = LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(h.Entries(), ","); got != "b,c,d" {
+		t.Errorf("got entries %q, want b,c,d", got)
+	}
+}
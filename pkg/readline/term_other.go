@@ -0,0 +1,21 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package readline
+
+import "errors"
+
+// IsTerminal returns true if the file descriptor is a terminal. Line
+// editing is not supported on this platform, so it always returns false.
+func IsTerminal(fd int) bool {
+	return false
+}
+
+// Width returns the number of columns of the terminal. It is not
+// supported on this platform, so it always returns zero.
+func Width(fd int) int {
+	return 0
+}
+
+func makeRaw(fd int) (restore func() error, err error) {
+	return nil, errors.New("readline: raw mode not supported")
+}
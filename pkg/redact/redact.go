@@ -0,0 +1,176 @@
+// Package redact removes secrets and personal information from prompts
+// before they are sent to the models, replacing each value by a stable
+// placeholder, like <EMAIL_1>. The original values never leave the machine
+// and are restored locally in the generated text.
+package redact
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// Finding reports one redacted value.
+type Finding struct {
+	Rule        string `json:"rule"`
+	Placeholder string `json:"placeholder"`
+	// Count is how many times the value was redacted.
+	Count int `json:"count"`
+}
+
+// Redactor replaces sensitive values with placeholders. The same value is
+// always replaced by the same placeholder, so the model can still relate
+// entries that mention it. It is safe for concurrent use.
+type Redactor struct {
+	rules []Rule
+
+	mu       sync.Mutex
+	values   map[string]string // placeholder to original value
+	findings map[string]*Finding
+	byValue  map[string]string // original value to placeholder
+	next     map[string]int    // last placeholder index by rule name
+}
+
+// New returns a Redactor using the given rules, in order.
+func New(rules []Rule) (*Redactor, error) {
+	r := &Redactor{
+		values:   make(map[string]string),
+		findings: make(map[string]*Finding),
+		byValue:  make(map[string]string),
+		next:     make(map[string]int),
+	}
+	for _, rule := range rules {
+		if err := rule.compile(); err != nil {
+			return nil, err
+		}
+		r.rules = append(r.rules, rule)
+	}
+	return r, nil
+}
+
+// Redact returns s with all values matched by the rules replaced by their
+// placeholders. Placeholders created by earlier rules are not redacted
+// again by later ones, so that Restore gets back the original values.
+func (r *Redactor) Redact(s string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rule := range r.rules {
+		matches := rule.re.FindAllStringSubmatchIndex(s, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		redacted := r.placeholderSpans(s)
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[2*rule.Group], m[2*rule.Group+1]
+			if start < 0 || start == end || overlaps(redacted, start, end) {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(r.placeholder(rule.Name, s[start:end]))
+			last = end
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s
+}
+
+// placeholder returns the placeholder of the value, creating a new one if
+// needed, and counts the redaction.
+func (r *Redactor) placeholder(name, value string) string {
+	key := name + "\x00" + value
+	p, ok := r.byValue[key]
+	if !ok {
+		r.next[name]++
+		p = fmt.Sprintf("<%s_%d>", name, r.next[name])
+		r.byValue[key] = p
+		r.values[p] = value
+		r.findings[p] = &Finding{Rule: name, Placeholder: p}
+	}
+	r.findings[p].Count++
+	return p
+}
+
+var placeholders = regexp.MustCompile(`<[A-Z][A-Z0-9_]*_\d+>`)
+
+// placeholderSpans returns the positions of the placeholders created by
+// this Redactor in s.
+func (r *Redactor) placeholderSpans(s string) [][]int {
+	var spans [][]int
+	for _, span := range placeholders.FindAllStringIndex(s, -1) {
+		if _, ok := r.values[s[span[0]:span[1]]]; ok {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// overlaps reports if the text between start and end overlaps any of the
+// spans.
+func overlaps(spans [][]int, start, end int) bool {
+	for _, span := range spans {
+		if start < span[1] && span[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// Restore returns s with the placeholders created by this Redactor
+// replaced by the original values. Unknown placeholders are kept.
+func (r *Redactor) Restore(s string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return placeholders.ReplaceAllStringFunc(s, func(p string) string {
+		if v, ok := r.values[p]; ok {
+			return v
+		}
+		return p
+	})
+}
+
+// Report returns the redacted values found so far, sorted by rule name and
+// placeholder index. The original values are not included.
+func (r *Redactor) Report() []Finding {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var report []Finding
+	for _, f := range r.findings {
+		report = append(report, *f)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		a, b := report[i], report[j]
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return len(a.Placeholder) < len(b.Placeholder) ||
+			len(a.Placeholder) == len(b.Placeholder) && a.Placeholder < b.Placeholder
+	})
+	return report
+}
+
+// Generator redacts the prompts sent to another Generator and restores
+// the original values in the generated predictions.
+type Generator struct {
+	text.Generator
+	Redactor *Redactor
+}
+
+// GenerateText implements text.Generator.
+func (g Generator) GenerateText(ctx context.Context, promptContext, prompt string, params text.Parameters) (*text.Response, error) {
+	resp, err := g.Generator.GenerateText(ctx, g.Redactor.Redact(promptContext), g.Redactor.Redact(prompt), params)
+	if err != nil {
+		return nil, err
+	}
+	for i := range resp.Predictions {
+		resp.Predictions[i].Content = g.Redactor.Restore(resp.Predictions[i].Content)
+	}
+	return resp, nil
+}
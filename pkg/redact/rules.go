@@ -0,0 +1,104 @@
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Rule detects one kind of sensitive value with a regular expression.
+type Rule struct {
+	// Name is used in the placeholders, like EMAIL in <EMAIL_1>.
+	Name string `json:"name"`
+	// Pattern is the regular expression that matches the values.
+	Pattern string `json:"pattern"`
+	// Group is the submatch with the value to be redacted, allowing the
+	// pattern to include some context around it. Zero redacts the whole
+	// match.
+	Group int `json:"group,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// compile parses the rule pattern.
+func (r *Rule) compile() error {
+	if !validName.MatchString(r.Name) {
+		return fmt.Errorf("redact: invalid rule name %q: use only uppercase letters, digits and _", r.Name)
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return fmt.Errorf("redact: rule %s: %v", r.Name, err)
+	}
+	if r.Group < 0 || r.Group > re.NumSubexp() {
+		return fmt.Errorf("redact: rule %s: invalid group %d", r.Name, r.Group)
+	}
+	r.re = re
+	return nil
+}
+
+var validName = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// DefaultRules detect common secrets and personal information found in
+// logs. Secrets come first, so that a token is not partially redacted by
+// a more generic rule.
+var DefaultRules = []Rule{
+	{Name: "PRIVATE_KEY", Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+	{Name: "JWT", Pattern: `\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`},
+	{Name: "TOKEN", Pattern: `(?i)\bbearer\s+([A-Za-z0-9._~+/-]{16,}=*)`, Group: 1},
+	{Name: "TOKEN", Pattern: `\bya29\.[A-Za-z0-9_-]{20,}`},
+	{Name: "API_KEY", Pattern: `\bAIza[0-9A-Za-z_-]{35}\b`},
+	{Name: "API_KEY", Pattern: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: "SECRET", Pattern: `(?i)\b(?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token|client[_-]?secret)\b["']?\s*[:=]\s*["']?([^\s"',;&]{4,})`, Group: 1},
+	{Name: "EMAIL", Pattern: `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`},
+	{Name: "IP", Pattern: `\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`},
+	{Name: "IP", Pattern: `\b(?:[0-9a-fA-F]{1,4}:){7}[0-9a-fA-F]{1,4}\b`},
+	{Name: "PROJECT_NUMBER", Pattern: `\bprojects/(\d{6,})\b`, Group: 1},
+	{Name: "PROJECT_NUMBER", Pattern: `"projectNumber"\s*:\s*"?(\d{6,})`, Group: 1},
+}
+
+// Config is the redaction ruleset loaded from a JSON file, like:
+//
+//	{
+//	  "rules": [{"name": "CUSTOMER", "pattern": "\\bcust-[0-9]+\\b"}],
+//	  "disable": ["IP"]
+//	}
+type Config struct {
+	// Rules are added after the default rules.
+	Rules []Rule `json:"rules"`
+	// Disable lists the names of the default rules that are not used.
+	Disable []string `json:"disable,omitempty"`
+	// NoDefaults ignores all default rules.
+	NoDefaults bool `json:"noDefaults,omitempty"`
+}
+
+// LoadConfig reads the configuration from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("redact: invalid config %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// RuleSet returns the rules enabled by the configuration.
+func (c *Config) RuleSet() []Rule {
+	var rules []Rule
+	if !c.NoDefaults {
+	next:
+		for _, r := range DefaultRules {
+			for _, name := range c.Disable {
+				if strings.EqualFold(name, r.Name) {
+					continue next
+				}
+			}
+			rules = append(rules, r)
+		}
+	}
+	return append(rules, c.Rules...)
+}
@@ -0,0 +1,181 @@
+package releasenotes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ronoaldo/genai-demos/pkg/summarize"
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// DefaultContext is the prompt context used to summarize the notes of a
+// product in a launch stage. The first %s is the product name and the
+// stage, and the second one the notes.
+var DefaultContext = `Summarize the following Google Cloud release notes about %s
+in a few short topics, one per line starting with "* ".
+Keep the Markdown links to the documentation exactly as they are written.
+
+%%s
+`
+
+// DefaultMergeContext is the prompt context used to merge the partial
+// summaries of products with many notes.
+var DefaultMergeContext = `The following are summaries of Google Cloud release notes about %s.
+Merge them into a few short topics, one per line starting with "* ", without repeating
+information and keeping the Markdown links exactly as they are written.
+
+%%s
+`
+
+// DefaultTitle is the title of digests without one.
+const DefaultTitle = "Google Cloud release notes"
+
+// Digest is the summary of the release notes in a period.
+type Digest struct {
+	Title        string
+	Since, Until time.Time
+	Products     []Product
+}
+
+// Digester summarizes the notes of each product with a text model.
+type Digester struct {
+	Summarizer *summarize.Summarizer
+	// Context and MergeContext are the prompt contexts, in the format of
+	// DefaultContext and DefaultMergeContext, that are used if empty.
+	Context      string
+	MergeContext string
+}
+
+// NewDigester returns a Digester that uses the generator to summarize the
+// notes.
+func NewDigester(gen text.Generator) *Digester {
+	return &Digester{Summarizer: summarize.New(gen)}
+}
+
+// Summarize fills the Summary of each group of notes. Groups with many
+// notes are summarized in parts and merged.
+func (d *Digester) Summarize(ctx context.Context, products []Product) error {
+	chunkContext, mergeContext := d.Context, d.MergeContext
+	if chunkContext == "" {
+		chunkContext = DefaultContext
+	}
+	if mergeContext == "" {
+		mergeContext = DefaultMergeContext
+	}
+	for i := range products {
+		p := &products[i]
+		for j := range p.Groups {
+			g := &p.Groups[j]
+			about := strings.ReplaceAll(p.Name+" ("+g.Stage.Title()+")", "%", "%%")
+			s := *d.Summarizer
+			s.ChunkContext = fmt.Sprintf(chunkContext, about)
+			s.MergeContext = fmt.Sprintf(mergeContext, about)
+			res, err := s.Summarize(ctx, notesText(g.Notes))
+			if err != nil {
+				return fmt.Errorf("releasenotes: summarizing %s: %w", p.Name, err)
+			}
+			g.Summary = strings.TrimSpace(res.Summary.Text)
+		}
+	}
+	return nil
+}
+
+// notesText formats the notes as paragraphs, so that they are not split
+// when summarized in parts.
+func notesText(notes []Note) string {
+	var b strings.Builder
+	for _, n := range notes {
+		fmt.Fprintf(&b, "%s (%s): %s\n\n", n.Date.Format("2006-01-02"), typeTitle(n.Type),
+			strings.Join(strings.Fields(n.Description), " "))
+	}
+	return b.String()
+}
+
+// typeTitle formats a release note type, like BREAKING_CHANGE, as
+// "Breaking change".
+func typeTitle(t string) string {
+	t = strings.ToLower(strings.ReplaceAll(t, "_", " "))
+	if t == "" {
+		return "Update"
+	}
+	return strings.ToUpper(t[:1]) + t[1:]
+}
+
+// maxNoteLinks limits the links listed for each note in the sources,
+// since some notes, like the client library digests, have dozens of them.
+const maxNoteLinks = 3
+
+// WriteMarkdown writes the digest as Markdown, with the summary of each
+// product and stage followed by the links of the notes summarized.
+func WriteMarkdown(w io.Writer, d *Digest) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n_%s_\n", d.title(), d.Description())
+	for _, p := range d.Products {
+		fmt.Fprintf(&b, "\n## %s\n", p.Name)
+		for _, g := range p.Groups {
+			fmt.Fprintf(&b, "\n### %s\n\n%s\n", g.Stage.Title(), g.Summary)
+		}
+		b.WriteString("\nSources:\n\n")
+		for _, g := range p.Groups {
+			for _, n := range g.Notes {
+				fmt.Fprintf(&b, "* %s, %s%s\n", n.Date.Format("2006-01-02"), typeTitle(n.Type), formatLinks(n.Links()))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (d *Digest) title() string {
+	if d.Title == "" {
+		return DefaultTitle
+	}
+	return d.Title
+}
+
+// Description returns the period and the number of notes in the digest.
+func (d *Digest) Description() string {
+	return fmt.Sprintf("%s, %d notes of %d products.", period(d.Since, d.Until), countNotes(d.Products), len(d.Products))
+}
+
+func formatLinks(links []Link) string {
+	if len(links) == 0 {
+		return ""
+	}
+	var parts []string
+	for i, l := range links {
+		if i == maxNoteLinks {
+			parts = append(parts, fmt.Sprintf("and %d more", len(links)-maxNoteLinks))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("[%s](%s)", l.Text, l.URL))
+	}
+	return ": " + strings.Join(parts, ", ")
+}
+
+// period describes the date range of the digest.
+func period(since, until time.Time) string {
+	const day = "2006-01-02"
+	switch {
+	case since.IsZero() && until.IsZero():
+		return "All dates"
+	case since.IsZero():
+		return "Until " + until.Format(day)
+	case until.IsZero():
+		return "Since " + since.Format(day)
+	}
+	return "From " + since.Format(day) + " to " + until.Format(day)
+}
+
+func countNotes(products []Product) int {
+	count := 0
+	for _, p := range products {
+		for _, g := range p.Groups {
+			count += len(g.Notes)
+		}
+	}
+	return count
+}
@@ -0,0 +1,51 @@
+package releasenotes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+	"github.com/ronoaldo/genai-demos/pkg/text/texttest"
+)
+
+// firstLine returns the first line of the prompt as a topic.
+func firstLine(c texttest.Call) (*text.Response, error) {
+	first, _, _ := strings.Cut(c.Compiled(), "\n")
+	return texttest.Reply("* " + first), nil
+}
+
+func TestDigest(t *testing.T) {
+	notes, err := Read(strings.NewReader(exportJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &texttest.Generator{Respond: firstLine}
+	d := &Digest{Since: date("2023-09-27"), Until: date("2023-09-30"), Products: GroupNotes(notes)}
+	if err := NewDigester(g).Summarize(context.Background(), d.Products); err != nil {
+		t.Fatal(err)
+	}
+	calls := g.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("got %d prompts, want one per group", len(calls))
+	}
+	if !strings.Contains(calls[1].Compiled(), "2023-09-28 (Feature): You can now use [IAM conditions](https://cloud.google.com/bigquery/docs/conditions).") {
+		t.Errorf("unexpected prompt:\n%s", calls[1].Compiled())
+	}
+
+	var b strings.Builder
+	if err := WriteMarkdown(&b, d); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Google Cloud release notes\n\n_From 2023-09-27 to 2023-09-30, 3 notes of 2 products._",
+		"## BigQuery\n\n### Generally available\n\n* Summarize the following Google Cloud release notes about BigQuery (Generally available)\n",
+		"* 2023-09-28, Feature: [IAM conditions](https://cloud.google.com/bigquery/docs/conditions)\n",
+		"## Vertex AI\n\n### Other updates\n",
+		"* 2023-09-27, Fix: [leakage](https://en.wikipedia.org/wiki/Leakage_(machine_learning))\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("markdown does not contain %q:\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package releasenotes
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Feed describes the feed of the digest entries.
+type Feed struct {
+	Title string
+	// BaseURL is the address where the site is published, used to build
+	// absolute links to the pages and the entry IDs.
+	BaseURL string
+	Updated time.Time
+	Entries []Entry
+}
+
+// link returns the absolute link to a page of the site.
+func (f Feed) link(page string) string {
+	base := strings.TrimSuffix(f.BaseURL, "/")
+	if page == "" {
+		return base + "/"
+	}
+	return base + "/" + page
+}
+
+// entryID returns the entry ID, as a tag URI when there is no base URL.
+func (f Feed) entryID(e Entry) string {
+	if f.BaseURL == "" {
+		return "urn:release-notes:" + e.ID
+	}
+	return f.link(e.Page) + "#" + e.ID
+}
+
+// entryHTML returns the summary and sources of the entry as HTML.
+func entryHTML(e Entry) string {
+	var b strings.Builder
+	b.WriteString(string(markdownHTML(e.Summary)))
+	b.WriteString("<p>Sources:</p>\n<ul>\n")
+	for _, s := range e.Sources {
+		fmt.Fprintf(&b, "<li>%s</li>\n", sourceHTML(s))
+	}
+	b.WriteString("</ul>\n")
+	return b.String()
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  string      `xml:"author>name"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// WriteAtom writes the feed in the Atom format.
+func WriteAtom(w io.Writer, f Feed) error {
+	feed := atomFeed{
+		Title:   f.Title,
+		ID:      f.link(""),
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Links:   []atomLink{{Href: f.link("")}, {Href: f.link("atom.xml"), Rel: "self"}},
+		Author:  f.Title,
+	}
+	if f.BaseURL == "" {
+		feed.ID = "urn:release-notes:feed"
+	}
+	for _, e := range f.Entries {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   e.Title(),
+			ID:      f.entryID(e),
+			Updated: e.Updated.UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: f.link(e.Page) + "#" + e.ID},
+			Content: atomContent{Type: "html", Body: entryHTML(e)},
+		})
+	}
+	return writeXML(w, feed)
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssFeed struct {
+	XMLName     xml.Name  `xml:"rss"`
+	Version     string    `xml:"version,attr"`
+	Title       string    `xml:"channel>title"`
+	Link        string    `xml:"channel>link"`
+	Description string    `xml:"channel>description"`
+	PubDate     string    `xml:"channel>lastBuildDate"`
+	Items       []rssItem `xml:"channel>item"`
+}
+
+// WriteRSS writes the feed in the RSS 2.0 format.
+func WriteRSS(w io.Writer, f Feed) error {
+	feed := rssFeed{
+		Version:     "2.0",
+		Title:       f.Title,
+		Link:        f.link(""),
+		Description: f.Title,
+		PubDate:     f.Updated.UTC().Format(time.RFC1123Z),
+	}
+	for _, e := range f.Entries {
+		feed.Items = append(feed.Items, rssItem{
+			Title:       e.Title(),
+			Link:        f.link(e.Page) + "#" + e.ID,
+			GUID:        rssGUID{Value: f.entryID(e)},
+			PubDate:     e.Updated.UTC().Format(time.RFC1123Z),
+			Description: entryHTML(e),
+		})
+	}
+	return writeXML(w, feed)
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,97 @@
+package releasenotes
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() Feed {
+	updated := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	return Feed{
+		Title:   "Release notes",
+		BaseURL: "https://example.com/notes/",
+		Updated: updated,
+		Entries: []Entry{{
+			ID:      "0123456789abcdef",
+			Product: "BigQuery",
+			Stage:   Preview,
+			Updated: updated,
+			Summary: "* **IAM conditions** for [datasets](https://cloud.google.com/bigquery/docs/conditions) & <tables>",
+			Sources: []Source{{Date: "2023-09-28", Type: "Feature", Links: []Link{{"IAM conditions", "https://cloud.google.com/bigquery/docs/conditions"}}}},
+			Page:    "digest-1.html",
+		}},
+	}
+}
+
+func TestWriteAtom(t *testing.T) {
+	var b strings.Builder
+	if err := WriteAtom(&b, testFeed()); err != nil {
+		t.Fatal(err)
+	}
+	var feed struct {
+		Entries []struct {
+			Title   string `xml:"title"`
+			ID      string `xml:"id"`
+			Updated string `xml:"updated"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal([]byte(b.String()), &feed); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, b.String())
+	}
+	if len(feed.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(feed.Entries))
+	}
+	e := feed.Entries[0]
+	if e.Title != "BigQuery: Preview" || e.ID != "https://example.com/notes/digest-1.html#0123456789abcdef" || e.Updated != "2023-10-01T12:00:00Z" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if !strings.Contains(e.Content, `<strong>IAM conditions</strong> for <a href="https://cloud.google.com/bigquery/docs/conditions">datasets</a> &amp; &lt;tables&gt;`) {
+		t.Errorf("unexpected content %s", e.Content)
+	}
+}
+
+func TestWriteRSS(t *testing.T) {
+	f := testFeed()
+	f.BaseURL = ""
+	var b strings.Builder
+	if err := WriteRSS(&b, f); err != nil {
+		t.Fatal(err)
+	}
+	var feed struct {
+		Items []struct {
+			GUID    string `xml:"guid"`
+			PubDate string `xml:"pubDate"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal([]byte(b.String()), &feed); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, b.String())
+	}
+	if len(feed.Items) != 1 || feed.Items[0].GUID != "urn:release-notes:0123456789abcdef" || feed.Items[0].PubDate != "Sun, 01 Oct 2023 12:00:00 +0000" {
+		t.Errorf("unexpected items %+v", feed.Items)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	f := testFeed()
+	var b strings.Builder
+	err := WriteHTML(&b, Page{Title: "Notes <1>", Feed: "atom.xml", Entries: f.Entries,
+		Runs: []Run{{Time: f.Updated, Page: "digest-1.html", Entries: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<title>Notes &lt;1&gt;</title>",
+		`<h2>BigQuery</h2>`,
+		`<h3 id="0123456789abcdef">Preview</h3>`,
+		`<li><strong>IAM conditions</strong> for <a href="https://cloud.google.com/bigquery/docs/conditions">datasets</a> &amp; &lt;tables&gt;</li>`,
+		`<li>2023-09-28, Feature: <a href="https://cloud.google.com/bigquery/docs/conditions">IAM conditions</a></li>`,
+		`<a href="digest-1.html">2023-10-01 12:00</a> (1 updates)`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("page does not contain %q:\n%s", want, b.String())
+		}
+	}
+}
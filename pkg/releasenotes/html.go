@@ -0,0 +1,144 @@
+package releasenotes
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// markdownHTML converts the subset of Markdown used in the summaries to
+// HTML: paragraphs, headings, lists, links, bold, italics and code. All
+// other text is escaped.
+func markdownHTML(md string) template.HTML {
+	var b strings.Builder
+	inList := false
+	closeList := func() {
+		if inList {
+			b.WriteString("</ul>\n")
+			inList = false
+		}
+	}
+	for _, line := range strings.Split(md, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			closeList()
+		case strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "- "):
+			if !inList {
+				b.WriteString("<ul>\n")
+				inList = true
+			}
+			fmt.Fprintf(&b, "<li>%s</li>\n", inlineHTML(line[2:]))
+		case strings.HasPrefix(line, "#"):
+			closeList()
+			level := len(line) - len(strings.TrimLeft(line, "#"))
+			level = min(level+2, 6) // page headings use h1 to h3
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", level, inlineHTML(strings.TrimLeft(line, "# ")), level)
+		default:
+			closeList()
+			fmt.Fprintf(&b, "<p>%s</p>\n", inlineHTML(line))
+		}
+	}
+	closeList()
+	return template.HTML(b.String())
+}
+
+var (
+	codePattern   = regexp.MustCompile("`([^`]+)`")
+	boldPattern   = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	italicPattern = regexp.MustCompile(`(^|[^*])\*([^*\s][^*]*)\*`)
+)
+
+// inlineHTML converts the inline Markdown elements of the escaped text.
+func inlineHTML(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range linkPattern.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(inlineText(s[last:m[0]]))
+		fmt.Fprintf(&b, `<a href="%s">%s</a>`, html.EscapeString(s[m[4]:m[5]]), inlineText(s[m[2]:m[3]]))
+		last = m[1]
+	}
+	b.WriteString(inlineText(s[last:]))
+	return b.String()
+}
+
+func inlineText(s string) string {
+	s = html.EscapeString(s)
+	s = codePattern.ReplaceAllString(s, "<code>$1</code>")
+	s = boldPattern.ReplaceAllString(s, "<strong>$1</strong>")
+	return italicPattern.ReplaceAllString(s, "$1<em>$2</em>")
+}
+
+// sourceHTML formats the source like in the Markdown digest.
+func sourceHTML(s Source) template.HTML {
+	return template.HTML(inlineHTML(s.Date + ", " + s.Type + formatLinks(s.Links)))
+}
+
+var pageTemplate = template.Must(template.New("page").Funcs(template.FuncMap{
+	"markdown": markdownHTML,
+	"source":   sourceHTML,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{if .Feed}}<link rel="alternate" type="application/atom+xml" title="{{.Title}}" href="{{.Feed}}">
+{{end}}<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+.meta, .sources { color: #555; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with .Subtitle}}<p class="meta">{{.}}</p>
+{{end}}{{range .Products}}<h2>{{.Name}}</h2>
+{{range .Entries}}<h3 id="{{.ID}}">{{.Stage.Title}}</h3>
+{{markdown .Summary}}<ul class="sources">
+{{range .Sources}}<li>{{source .}}</li>
+{{end}}</ul>
+{{end}}{{end}}{{if .Runs}}<h2>Previous digests</h2>
+<ul>
+{{range .Runs}}<li><a href="{{.Page}}">{{.Time.Format "2006-01-02 15:04"}}</a> ({{.Entries}} updates)</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+`))
+
+// pageProduct are the entries of a product in a page.
+type pageProduct struct {
+	Name    string
+	Entries []Entry
+}
+
+// Page is the content of an HTML page.
+type Page struct {
+	Title, Subtitle string
+	// Feed is the link to the Atom feed, if any.
+	Feed    string
+	Entries []Entry
+	// Runs are links to the pages of previous runs.
+	Runs []Run
+}
+
+// WriteHTML writes the page, with the entries grouped by product.
+func WriteHTML(w io.Writer, p Page) error {
+	var products []pageProduct
+	index := make(map[string]int)
+	for _, e := range p.Entries {
+		i, ok := index[e.Product]
+		if !ok {
+			i = len(products)
+			index[e.Product] = i
+			products = append(products, pageProduct{Name: e.Product})
+		}
+		products[i].Entries = append(products[i].Entries, e)
+	}
+	return pageTemplate.Execute(w, struct {
+		Page
+		Products []pageProduct
+	}{p, products})
+}
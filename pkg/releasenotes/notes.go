@@ -0,0 +1,233 @@
+// Package releasenotes reads Google Cloud release notes, like the ones in
+// the bigquery-public-data.google_cloud_release_notes dataset, and builds
+// a digest summarized by a text model, grouped by product and launch
+// stage.
+//
+// Digests can be rendered as Markdown, HTML pages and Atom or RSS feeds.
+// A State keeps the notes already summarized between runs, so that each
+// run only summarizes new notes and feed entries are never repeated.
+package releasenotes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stage is the launch stage of the feature described by a note.
+type Stage string
+
+const (
+	GA      Stage = "GA"
+	Preview Stage = "Preview"
+	Other   Stage = ""
+)
+
+// Title returns the heading used for the stage in the digest.
+func (s Stage) Title() string {
+	switch s {
+	case GA:
+		return "Generally available"
+	case Preview:
+		return "Preview"
+	}
+	return "Other updates"
+}
+
+// stageOrder is the order of the stages in the digest.
+var stageOrder = []Stage{GA, Preview, Other}
+
+// Note is a release note, with the fields of the BigQuery dataset.
+type Note struct {
+	Product     string    `json:"product_name"`
+	Type        string    `json:"release_note_type"`
+	Description string    `json:"description"`
+	Date        time.Time `json:"-"`
+	// PublishedAt is the date as read from the input.
+	PublishedAt string `json:"published_at"`
+}
+
+// Stage returns the launch stage mentioned in the description.
+func (n Note) Stage() Stage {
+	d := strings.ToLower(n.Description)
+	switch {
+	case gaPattern.MatchString(d):
+		return GA
+	case previewPattern.MatchString(d):
+		return Preview
+	}
+	return Other
+}
+
+var (
+	gaPattern      = regexp.MustCompile(`generally available|\[ga\]|general availability|\bis now ga\b`)
+	previewPattern = regexp.MustCompile(`\bpreview\b`)
+)
+
+// Link is a link found in a note description.
+type Link struct {
+	Text, URL string
+}
+
+// stagesURL is the link to the launch stages page, present in most notes.
+const stagesURL = "https://cloud.google.com/products/#product-launch-stages"
+
+var linkPattern = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^\s()]+(?:\([^\s()]*\)[^\s()]*)*)\)`)
+
+// Links returns the Markdown links in the description, except the one to
+// the launch stages page.
+func (n Note) Links() []Link {
+	var links []Link
+	seen := make(map[string]bool)
+	for _, m := range linkPattern.FindAllStringSubmatch(n.Description, -1) {
+		text, url := strings.Trim(m[1], "` "), m[2]
+		if url == stagesURL || seen[url] {
+			continue
+		}
+		seen[url] = true
+		links = append(links, Link{Text: text, URL: url})
+	}
+	return links
+}
+
+// dateLayouts are the accepted formats of published_at.
+var dateLayouts = []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04:05 MST", "2006-01-02 15:04:05"}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("releasenotes: invalid date %q", value)
+}
+
+// Read reads the notes from a BigQuery JSON export, that is a JSON array
+// like the output of "bq query --format=json", or from newline-delimited
+// JSON, like the output of "bq extract". Notes are sorted by date.
+func Read(r io.Reader) ([]Note, error) {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var notes []Note
+	if first == '[' {
+		if err := json.NewDecoder(br).Decode(&notes); err != nil {
+			return nil, fmt.Errorf("releasenotes: invalid JSON: %v", err)
+		}
+	} else {
+		dec := json.NewDecoder(br)
+		for line := 1; ; line++ {
+			var n Note
+			if err := dec.Decode(&n); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("releasenotes: invalid JSON in record %d: %v", line, err)
+			}
+			notes = append(notes, n)
+		}
+	}
+	for i := range notes {
+		if notes[i].Date, err = parseDate(notes[i].PublishedAt); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(notes, func(i, j int) bool { return notes[i].Date.Before(notes[j].Date) })
+	return notes, nil
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.ContainsAny(b, " \t\r\n") {
+			return b[0], nil
+		}
+		r.ReadByte()
+	}
+}
+
+// Filter selects the notes of some products in a date range.
+type Filter struct {
+	// Products are matched, ignoring case, as part of the product name.
+	// All products are selected when it is empty.
+	Products []string
+	// Since and Until are the first and last days included. Zero values
+	// are not checked.
+	Since, Until time.Time
+}
+
+// Apply returns the notes selected by the filter.
+func (f Filter) Apply(notes []Note) []Note {
+	var selected []Note
+	for _, n := range notes {
+		if !f.Since.IsZero() && n.Date.Before(f.Since) {
+			continue
+		}
+		if !f.Until.IsZero() && n.Date.After(f.Until) {
+			continue
+		}
+		if len(f.Products) > 0 && !f.matches(n.Product) {
+			continue
+		}
+		selected = append(selected, n)
+	}
+	return selected
+}
+
+func (f Filter) matches(product string) bool {
+	product = strings.ToLower(product)
+	for _, p := range f.Products {
+		if p = strings.TrimSpace(p); p != "" && strings.Contains(product, strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Product are the notes of a product, grouped by stage.
+type Product struct {
+	Name   string
+	Groups []Group
+}
+
+// Group are the notes of a product in the same launch stage.
+type Group struct {
+	Stage   Stage
+	Notes   []Note
+	Summary string
+}
+
+// GroupNotes groups the notes by product, sorted by name, and by stage.
+func GroupNotes(notes []Note) []Product {
+	byProduct := make(map[string]map[Stage][]Note)
+	for _, n := range notes {
+		if byProduct[n.Product] == nil {
+			byProduct[n.Product] = make(map[Stage][]Note)
+		}
+		byProduct[n.Product][n.Stage()] = append(byProduct[n.Product][n.Stage()], n)
+	}
+	var products []Product
+	for name, stages := range byProduct {
+		p := Product{Name: name}
+		for _, s := range stageOrder {
+			if len(stages[s]) > 0 {
+				p.Groups = append(p.Groups, Group{Stage: s, Notes: stages[s]})
+			}
+		}
+		products = append(products, p)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].Name < products[j].Name })
+	return products
+}
@@ -0,0 +1,114 @@
+package releasenotes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const exportJSON = `[
+  {"description": "You can now use [IAM conditions](https://cloud.google.com/bigquery/docs/conditions). This feature is in [preview](https://cloud.google.com/products/#product-launch-stages).", "release_note_type": "FEATURE", "published_at": "2023-09-28", "product_name": "BigQuery"},
+  {"description": "The [query inspector](https://cloud.google.com/bigquery/docs/admin) is now [generally available](https://cloud.google.com/products/#product-launch-stages).", "release_note_type": "FEATURE", "published_at": "2023-09-29", "product_name": "BigQuery"},
+  {"description": "Fixed an issue with [leakage](https://en.wikipedia.org/wiki/Leakage_(machine_learning)).", "release_note_type": "FIX", "published_at": "2023-09-27", "product_name": "Vertex AI"}
+]`
+
+const exportNDJSON = `{"description": "Cloud Run jobs are now GA. [generally available](https://cloud.google.com/run)", "release_note_type": "FEATURE", "published_at": "2023-10-02 00:00:00 UTC", "product_name": "Cloud Run"}
+{"description": "New regions.", "release_note_type": "ANNOUNCEMENT", "published_at": "2023-10-01T00:00:00Z", "product_name": "Cloud Run"}
+`
+
+func date(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantProducts []string
+		wantDates    []string
+		wantErr      bool
+	}{
+		{"json", exportJSON, []string{"Vertex AI", "BigQuery", "BigQuery"}, []string{"2023-09-27", "2023-09-28", "2023-09-29"}, false},
+		{"ndjson", exportNDJSON, []string{"Cloud Run", "Cloud Run"}, []string{"2023-10-01", "2023-10-02"}, false},
+		{"empty", "  \n", nil, nil, false},
+		{"invalid", "{\"published_at\": 1}", nil, nil, true},
+		{"invalid date", `[{"published_at": "yesterday"}]`, nil, nil, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			notes, err := Read(strings.NewReader(tc.input))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			var products, dates []string
+			for _, n := range notes {
+				products = append(products, n.Product)
+				dates = append(dates, n.Date.Format("2006-01-02"))
+			}
+			if !reflect.DeepEqual(products, tc.wantProducts) || !reflect.DeepEqual(dates, tc.wantDates) {
+				t.Errorf("got products %q at %q, want %q at %q", products, dates, tc.wantProducts, tc.wantDates)
+			}
+		})
+	}
+}
+
+func TestNoteStageAndLinks(t *testing.T) {
+	notes, err := Read(strings.NewReader(exportJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStages := []Stage{Other, Preview, GA}
+	wantLinks := [][]Link{
+		{{"leakage", "https://en.wikipedia.org/wiki/Leakage_(machine_learning)"}},
+		{{"IAM conditions", "https://cloud.google.com/bigquery/docs/conditions"}},
+		{{"query inspector", "https://cloud.google.com/bigquery/docs/admin"}},
+	}
+	for i, n := range notes {
+		if got := n.Stage(); got != wantStages[i] {
+			t.Errorf("note %d: got stage %q, want %q", i, got, wantStages[i])
+		}
+		if got := n.Links(); !reflect.DeepEqual(got, wantLinks[i]) {
+			t.Errorf("note %d: got links %q, want %q", i, got, wantLinks[i])
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	notes, _ := Read(strings.NewReader(exportJSON))
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"all", Filter{}, 3},
+		{"product", Filter{Products: []string{"bigquery"}}, 2},
+		{"products", Filter{Products: []string{"vertex", " BigQuery "}}, 3},
+		{"since", Filter{Since: date("2023-09-28")}, 2},
+		{"range", Filter{Since: date("2023-09-28"), Until: date("2023-09-28")}, 1},
+		{"none", Filter{Products: []string{"Cloud Run"}}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := len(tc.filter.Apply(notes)); got != tc.want {
+				t.Errorf("got %d notes, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGroupNotes(t *testing.T) {
+	notes, _ := Read(strings.NewReader(exportJSON))
+	products := GroupNotes(notes)
+	var got []string
+	for _, p := range products {
+		for _, g := range p.Groups {
+			got = append(got, p.Name+"/"+g.Stage.Title())
+		}
+	}
+	want := []string{"BigQuery/Generally available", "BigQuery/Preview", "Vertex AI/Other updates"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got groups %q, want %q", got, want)
+	}
+}
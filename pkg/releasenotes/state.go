@@ -0,0 +1,175 @@
+package releasenotes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ID returns a stable identifier of the note, derived from its content, so
+// that the same note has the same ID in every run.
+func (n Note) ID() string {
+	return hash(n.Product, n.Date.Format("2006-01-02"), n.Type, n.Description)
+}
+
+func hash(values ...string) string {
+	h := sha256.New()
+	for _, v := range values {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Entry is a feed entry with the summary of a group of notes. Its ID is
+// derived from the IDs of the notes, so feed readers don't show the same
+// notes twice.
+type Entry struct {
+	ID      string    `json:"id"`
+	Product string    `json:"product"`
+	Stage   Stage     `json:"stage"`
+	Updated time.Time `json:"updated"`
+	// Summary is the Markdown summary of the notes.
+	Summary string `json:"summary"`
+	// Sources are the dates, types and links of the notes.
+	Sources []Source `json:"sources"`
+	// Page is the name of the HTML page of the run that created the entry.
+	Page string `json:"page,omitempty"`
+}
+
+// Title returns the title of the entry, like "BigQuery: Preview".
+func (e Entry) Title() string {
+	return e.Product + ": " + e.Stage.Title()
+}
+
+// Source is the reference to a note summarized in an entry.
+type Source struct {
+	Date  string `json:"date"`
+	Type  string `json:"type"`
+	Links []Link `json:"links,omitempty"`
+}
+
+// Entries returns the feed entries of the digest, one for each product
+// and stage, updated at the given time.
+func Entries(d *Digest, updated time.Time, page string) []Entry {
+	var entries []Entry
+	for _, p := range d.Products {
+		for _, g := range p.Groups {
+			ids := make([]string, len(g.Notes))
+			e := Entry{Product: p.Name, Stage: g.Stage, Updated: updated, Summary: g.Summary, Page: page}
+			for i, n := range g.Notes {
+				ids[i] = n.ID()
+				e.Sources = append(e.Sources, Source{
+					Date:  n.Date.Format("2006-01-02"),
+					Type:  typeTitle(n.Type),
+					Links: n.Links(),
+				})
+			}
+			sort.Strings(ids)
+			e.ID = hash(ids...)
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Run is an execution that summarized new notes.
+type Run struct {
+	Time    time.Time `json:"time"`
+	Page    string    `json:"page,omitempty"`
+	Entries int       `json:"entries"`
+}
+
+// State is kept between runs, so that only new notes are summarized and
+// the feeds keep the entries of previous runs.
+type State struct {
+	// Seen maps the IDs of the notes already summarized to their date.
+	Seen    map[string]string `json:"seen"`
+	Entries []Entry           `json:"entries"`
+	Runs    []Run             `json:"runs"`
+}
+
+// LoadState reads the state from the file. A missing file returns an
+// empty state.
+func LoadState(path string) (*State, error) {
+	s := &State{Seen: make(map[string]string)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	if s.Seen == nil {
+		s.Seen = make(map[string]string)
+	}
+	return s, nil
+}
+
+// Save writes the state to the file, replacing it only after the new
+// content is completely written.
+func (s *State) Save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Unseen returns the notes that were not summarized in previous runs.
+func (s *State) Unseen(notes []Note) []Note {
+	var unseen []Note
+	for _, n := range notes {
+		if _, ok := s.Seen[n.ID()]; !ok {
+			unseen = append(unseen, n)
+		}
+	}
+	return unseen
+}
+
+// Add records a run, marking the notes of the entries as seen and keeping
+// the newest maxEntries entries, newest first.
+func (s *State) Add(run Run, notes []Note, entries []Entry, maxEntries int) {
+	for _, n := range notes {
+		s.Seen[n.ID()] = n.Date.Format("2006-01-02")
+	}
+	run.Entries = len(entries)
+	s.Runs = append([]Run{run}, s.Runs...)
+	s.Entries = append(append([]Entry{}, entries...), s.Entries...)
+	if maxEntries > 0 && len(s.Entries) > maxEntries {
+		s.Entries = s.Entries[:maxEntries]
+	}
+}
+
+// Forget removes the seen notes published before the date. These notes
+// are no longer selected by the date filter, so there is no need to keep
+// them.
+func (s *State) Forget(before time.Time) {
+	day := before.Format("2006-01-02")
+	for id, date := range s.Seen {
+		if strings.Compare(date, day) < 0 {
+			delete(s.Seen, id)
+		}
+	}
+}
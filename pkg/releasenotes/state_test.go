@@ -0,0 +1,64 @@
+package releasenotes
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestState(t *testing.T) {
+	notes, err := Read(strings.NewReader(exportJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Unseen(notes); len(got) != 3 {
+		t.Fatalf("got %d unseen notes in a new state, want 3", len(got))
+	}
+
+	first := notes[:2]
+	d := &Digest{Products: GroupNotes(first)}
+	run := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	entries := Entries(d, run, "digest-1.html")
+	s.Add(Run{Time: run, Page: "digest-1.html"}, first, entries, 10)
+	if err := s.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unseen := s.Unseen(notes)
+	if len(unseen) != 1 || unseen[0].ID() != notes[2].ID() {
+		t.Errorf("got unseen notes %v, want only the third note", unseen)
+	}
+	if !reflect.DeepEqual(s.Entries, entries) || len(s.Runs) != 1 || s.Runs[0].Entries != 2 {
+		t.Errorf("state not saved: entries %v, runs %v", s.Entries, s.Runs)
+	}
+
+	// Summarizing the same notes again produces the same entry IDs
+	again := Entries(&Digest{Products: GroupNotes(first)}, run.Add(time.Hour), "")
+	for i := range again {
+		if again[i].ID != entries[i].ID {
+			t.Errorf("entry %d: got ID %s, want %s", i, again[i].ID, entries[i].ID)
+		}
+	}
+
+	later := Entries(&Digest{Products: GroupNotes(notes[2:])}, run.Add(24*time.Hour), "digest-2.html")
+	s.Add(Run{Time: run.Add(24 * time.Hour)}, notes[2:], later, 2)
+	if len(s.Entries) != 2 || s.Entries[0].Product != "BigQuery" || s.Entries[0].ID != later[0].ID {
+		t.Errorf("got entries %v, want the newest 2", s.Entries)
+	}
+
+	s.Forget(date("2023-09-28"))
+	if len(s.Seen) != 2 {
+		t.Errorf("got %d seen notes after Forget, want 2", len(s.Seen))
+	}
+}
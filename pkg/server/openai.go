@@ -0,0 +1,420 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// HandleOpenAI registers the endpoints compatible with the OpenAI
+// completions and chat completions API, so that existing clients can be
+// pointed to the server unchanged:
+//
+//	POST /v1/completions
+//	POST /v1/chat/completions
+//	GET  /v1/models
+//
+// The model requested by the client is ignored: the text.ModelVersion is
+// always used, and returned in the responses.
+func (s *Server) HandleOpenAI() {
+	s.mux.HandleFunc("/v1/completions", s.openAI(s.completions))
+	s.mux.HandleFunc("/v1/chat/completions", s.openAI(s.chatCompletions))
+	s.mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"object": "list",
+			"data": []map[string]any{{
+				"id":       text.ModelVersion,
+				"object":   "model",
+				"owned_by": "google",
+			}},
+		})
+	})
+}
+
+// CompletionRequest is the body of /v1/completions. Only the fields with
+// an equivalent in the model are used.
+type CompletionRequest struct {
+	Model string `json:"model"`
+	// Prompt is either a string or a list with a single string.
+	Prompt json.RawMessage `json:"prompt"`
+	OpenAIParameters
+}
+
+// ChatCompletionRequest is the body of /v1/chat/completions.
+type ChatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	OpenAIParameters
+}
+
+// ChatMessage is a message in a chat completion.
+type ChatMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content"`
+}
+
+// OpenAIParameters are the sampling options shared by both APIs.
+//
+// The temperature range of OpenAI is 0 to 2, and values above 1 are
+// limited to 1, the maximum of the Vertex AI text models. Stop sequences
+// are applied to the generated text, except when streaming.
+type OpenAIParameters struct {
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	N           *int     `json:"n,omitempty"`
+	Stream      bool     `json:"stream,omitempty"`
+	Stop        any      `json:"stop,omitempty"`
+}
+
+// parameters maps the OpenAI options to the model parameters, starting
+// from the default preset.
+func (o OpenAIParameters) parameters() (text.Parameters, error) {
+	p := Parameters{TopP: o.TopP, MaxOutputTokens: o.MaxTokens, CandidateCount: o.N}
+	if o.Temperature != nil {
+		if *o.Temperature < 0 || *o.Temperature > 2 {
+			return text.Parameters{}, errors.New("temperature must be between 0 and 2")
+		}
+		t := min(*o.Temperature, 1)
+		p.Temperature = &t
+	}
+	params, err := p.apply("default")
+	if err != nil {
+		return params, err
+	}
+	if o.Stream && params.CandidateCount > 1 {
+		return params, errors.New("n must be 1 when stream is set")
+	}
+	return params, nil
+}
+
+// stops returns the stop sequences, that can be a string or a list.
+func (o OpenAIParameters) stops() ([]string, error) {
+	switch stop := o.Stop.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{stop}, nil
+	case []any:
+		var stops []string
+		for _, s := range stop {
+			str, ok := s.(string)
+			if !ok {
+				return nil, errors.New("stop must be a string or a list of strings")
+			}
+			stops = append(stops, str)
+		}
+		return stops, nil
+	}
+	return nil, errors.New("stop must be a string or a list of strings")
+}
+
+// Choice is a generated completion in the OpenAI responses. Text is used
+// by completions, Message by chat completions and Delta by streamed chat
+// completions.
+type Choice struct {
+	Index        int          `json:"index"`
+	Text         *string      `json:"text,omitempty"`
+	Message      *ChatMessage `json:"message,omitempty"`
+	Delta        *ChatMessage `json:"delta,omitempty"`
+	FinishReason *string      `json:"finish_reason"`
+}
+
+// Usage is the number of tokens used by the request.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Completion is the response of both OpenAI APIs.
+type Completion struct {
+	ID      string   `json:"id"`
+	Object  string   `json:"object"`
+	Created int64    `json:"created"`
+	Model   string   `json:"model"`
+	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
+}
+
+// openAIRequest is the translated request, ready to call the model.
+type openAIRequest struct {
+	promptContext, prompt string
+	params                text.Parameters
+	stream                bool
+	stops                 []string
+	chat                  bool
+}
+
+// openAI decodes the request with the translate function and generates the
+// completion, returning errors in the OpenAI format.
+func (s *Server) openAI(translate func(r *http.Request) (*openAIRequest, int, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeOpenAIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.MaxBodyBytes)
+		req, status, err := translate(r)
+		if err != nil {
+			writeOpenAIError(w, status, err)
+			return
+		}
+		if req.stream {
+			s.streamOpenAI(w, r, req)
+			return
+		}
+		resp, err := s.Generator.GenerateText(r.Context(), req.promptContext, req.prompt, req.params)
+		if err != nil {
+			writeOpenAIError(w, generateStatus(err), err)
+			return
+		}
+		c := newCompletion(req.chat, false)
+		for i, p := range resp.Predictions {
+			content, reason := p.Content, "stop"
+			if p.SafetyAttributes.Blocked {
+				reason = "content_filter"
+			}
+			if cut, ok := cutStop(content, req.stops); ok {
+				content = cut
+			}
+			choice := Choice{Index: i, FinishReason: &reason}
+			if req.chat {
+				choice.Message = &ChatMessage{Role: "assistant", Content: content}
+			} else {
+				choice.Text = &content
+			}
+			c.Choices = append(c.Choices, choice)
+		}
+		c.Usage = usage(resp, text.CompilePrompt(req.promptContext, req.prompt))
+		writeJSON(w, http.StatusOK, c)
+	}
+}
+
+// completions translates a /v1/completions request.
+func (s *Server) completions(r *http.Request) (*openAIRequest, int, error) {
+	in := &CompletionRequest{}
+	if status, err := decodeOpenAI(r, in); err != nil {
+		return nil, status, err
+	}
+	var prompt string
+	if err := json.Unmarshal(in.Prompt, &prompt); err != nil {
+		var prompts []string
+		if err = json.Unmarshal(in.Prompt, &prompts); err != nil || len(prompts) > 1 {
+			return nil, http.StatusBadRequest, errors.New("prompt must be a string or a list with a single string")
+		}
+		if len(prompts) == 1 {
+			prompt = prompts[0]
+		}
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return nil, http.StatusBadRequest, errors.New("prompt is required")
+	}
+	return newOpenAIRequest(in.OpenAIParameters, "%s", prompt, false)
+}
+
+// chatCompletions translates a /v1/chat/completions request. The messages
+// are converted to a transcript, with the system messages first, and the
+// last user message is used as the prompt, so that it is the only part
+// truncated when the prompt is too long.
+func (s *Server) chatCompletions(r *http.Request) (*openAIRequest, int, error) {
+	in := &ChatCompletionRequest{}
+	if status, err := decodeOpenAI(r, in); err != nil {
+		return nil, status, err
+	}
+	last := len(in.Messages) - 1
+	if last < 0 || in.Messages[last].Role != "user" {
+		return nil, http.StatusBadRequest, errors.New("messages must end with a user message")
+	}
+	var system, transcript strings.Builder
+	for _, m := range in.Messages[:last] {
+		switch m.Role {
+		case "system":
+			system.WriteString(m.Content + "\n\n")
+		case "user":
+			transcript.WriteString("User: " + m.Content + "\n")
+		case "assistant":
+			transcript.WriteString("Assistant: " + m.Content + "\n")
+		default:
+			return nil, http.StatusBadRequest, fmt.Errorf("unsupported message role %q", m.Role)
+		}
+	}
+	escape := func(s string) string { return strings.ReplaceAll(s, "%", "%%") }
+	promptContext := escape(system.String()+transcript.String()) + "User: %s\nAssistant:"
+	return newOpenAIRequest(in.OpenAIParameters, promptContext, in.Messages[last].Content, true)
+}
+
+func newOpenAIRequest(o OpenAIParameters, promptContext, prompt string, chat bool) (*openAIRequest, int, error) {
+	params, err := o.parameters()
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	stops, err := o.stops()
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return &openAIRequest{
+		promptContext: promptContext,
+		prompt:        prompt,
+		params:        params,
+		stream:        o.Stream,
+		stops:         stops,
+		chat:          chat,
+	}, http.StatusOK, nil
+}
+
+// streamOpenAI sends the completion chunks as server-sent events, ending
+// with the [DONE] message.
+func (s *Server) streamOpenAI(w http.ResponseWriter, r *http.Request, req *openAIRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeOpenAIError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	c := newCompletion(req.chat, true)
+	send := func(role, content string, reason *string) error {
+		choice := Choice{FinishReason: reason}
+		if req.chat {
+			choice.Delta = &ChatMessage{Role: role, Content: content}
+		} else {
+			choice.Text = &content
+		}
+		c.Choices = []Choice{choice}
+		b, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	chunk := func(p text.Prediction) error { return send("", p.Content, nil) }
+
+	var err error
+	if req.chat {
+		err = send("assistant", "", nil)
+	}
+	if streamer, ok := s.Generator.(text.Streamer); ok && err == nil {
+		err = streamer.GenerateTextStream(r.Context(), req.promptContext, req.prompt, req.params, chunk)
+	} else if err == nil {
+		var resp *text.Response
+		if resp, err = s.Generator.GenerateText(r.Context(), req.promptContext, req.prompt, req.params); err == nil {
+			for _, p := range resp.Predictions {
+				if err = chunk(p); err != nil {
+					break
+				}
+			}
+		}
+	}
+	if err == nil {
+		reason := "stop"
+		err = send("", "", &reason)
+	}
+	if err != nil {
+		if s.Logger != nil {
+			s.Logger.LogAttrs(r.Context(), slog.LevelWarn, "stream failed", slog.String("path", r.URL.Path), slog.Any("error", err))
+		}
+		b, _ := json.Marshal(openAIError(generateStatus(err), err))
+		fmt.Fprintf(w, "data: %s\n\n", b)
+	}
+	// OpenAI clients wait for [DONE], even after an error.
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	flusher.Flush()
+}
+
+// decodeOpenAI decodes the request body. Unknown fields are accepted,
+// since clients send options that have no equivalent in the model.
+func decodeOpenAI(r *http.Request, v any) (int, error) {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return http.StatusRequestEntityTooLarge, fmt.Errorf("request body larger than %d bytes", maxErr.Limit)
+		}
+		return http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err)
+	}
+	return http.StatusOK, nil
+}
+
+func newCompletion(chat, stream bool) *Completion {
+	b := make([]byte, 12)
+	rand.Read(b)
+	c := &Completion{
+		ID:      "cmpl-" + hex.EncodeToString(b),
+		Object:  "text_completion",
+		Created: time.Now().Unix(),
+		Model:   text.ModelVersion,
+	}
+	if chat {
+		c.ID = "chatcmpl-" + hex.EncodeToString(b)
+		c.Object = "chat.completion"
+		if stream {
+			c.Object = "chat.completion.chunk"
+		}
+	}
+	return c
+}
+
+// usage returns the token usage from the response metadata, or an estimate
+// when the model returns no metadata.
+func usage(resp *text.Response, prompt string) *Usage {
+	u := &Usage{
+		PromptTokens:     resp.Metadata.InputTokenCount.TotalTokens,
+		CompletionTokens: resp.Metadata.OutputTokenCount.TotalTokens,
+	}
+	if u.PromptTokens == 0 {
+		u.PromptTokens = text.EstimateTokens(prompt)
+	}
+	if u.CompletionTokens == 0 {
+		for _, p := range resp.Predictions {
+			u.CompletionTokens += text.EstimateTokens(p.Content)
+		}
+	}
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	return u
+}
+
+// cutStop returns the content before the first stop sequence.
+func cutStop(content string, stops []string) (string, bool) {
+	end := -1
+	for _, stop := range stops {
+		if i := strings.Index(content, stop); stop != "" && i >= 0 && (end < 0 || i < end) {
+			end = i
+		}
+	}
+	if end < 0 {
+		return content, false
+	}
+	return content[:end], true
+}
+
+func openAIError(status int, err error) map[string]any {
+	kind := "invalid_request_error"
+	if status >= 500 {
+		kind = "api_error"
+	}
+	return map[string]any{"error": map[string]any{
+		"message": err.Error(),
+		"type":    kind,
+		"param":   nil,
+		"code":    nil,
+	}}
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, openAIError(status, err))
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+func newOpenAIServer(g text.Generator) *Server {
+	s := newTestServer(g)
+	s.HandleOpenAI()
+	return s
+}
+
+func TestCompletions(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		err        error
+		wantStatus int
+		wantText   string
+		wantParams text.Parameters
+	}{
+		{
+			"completion",
+			"/v1/completions",
+			`{"model": "gpt-3.5-turbo-instruct", "prompt": "Say hello", "max_tokens": 64, "temperature": 1.5, "top_p": 0.5, "n": 2, "user": "x"}`,
+			nil, 200, "Say hello",
+			text.Parameters{Temperature: 1, TopP: 0.5, TopK: 40, MaxTokens: 64, CandidateCount: 2},
+		},
+		{
+			"prompt list",
+			"/v1/completions",
+			`{"prompt": ["Say hello. Bye"], "stop": ". "}`,
+			nil, 200, "Say hello",
+			text.DefaultParameters,
+		},
+		{
+			"chat",
+			"/v1/chat/completions",
+			`{"model": "gpt-4", "messages": [
+				{"role": "system", "content": "Be brief, 100%."},
+				{"role": "user", "content": "Hi"},
+				{"role": "assistant", "content": "Hello!"},
+				{"role": "user", "content": "How are you?"}
+			]}`,
+			nil, 200, "Be brief, 100%.\n\nUser: Hi\nAssistant: Hello!\nUser: How are you?\nAssistant:",
+			text.DefaultParameters,
+		},
+		{"missing prompt", "/v1/completions", `{"model": "x"}`, nil, 400, "", text.Parameters{}},
+		{"many prompts", "/v1/completions", `{"prompt": ["a", "b"]}`, nil, 400, "", text.Parameters{}},
+		{"temperature", "/v1/completions", `{"prompt": "a", "temperature": 3}`, nil, 400, "", text.Parameters{}},
+		{"chat without user", "/v1/chat/completions", `{"messages": [{"role": "system", "content": "x"}]}`, nil, 400, "", text.Parameters{}},
+		{"stream with n", "/v1/completions", `{"prompt": "a", "stream": true, "n": 2}`, nil, 400, "", text.Parameters{}},
+		{"model error", "/v1/completions", `{"prompt": "a"}`, errors.New("boom"), 502, "", text.Parameters{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := echoGenerator(tc.err)
+			w := httptest.NewRecorder()
+			newOpenAIServer(g).ServeHTTP(w, httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body)))
+			if w.Code != tc.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tc.wantStatus, w.Body)
+			}
+			if w.Code != 200 {
+				var e struct {
+					Error struct{ Message, Type string }
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil || e.Error.Message == "" {
+					t.Errorf("invalid error body %s", w.Body)
+				}
+				return
+			}
+			var c Completion
+			if err := json.Unmarshal(w.Body.Bytes(), &c); err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if strings.Contains(tc.path, "chat") {
+				if c.Object != "chat.completion" || c.Choices[0].Message.Role != "assistant" {
+					t.Errorf("got object %q and message %#v", c.Object, c.Choices[0].Message)
+				}
+				got = c.Choices[0].Message.Content
+			} else {
+				got = *c.Choices[0].Text
+			}
+			if got != tc.wantText {
+				t.Errorf("got text %q, want %q", got, tc.wantText)
+			}
+			if g.Last().Params != tc.wantParams {
+				t.Errorf("got params %#v, want %#v", g.Last().Params, tc.wantParams)
+			}
+			if c.Model != text.ModelVersion || *c.Choices[0].FinishReason != "stop" {
+				t.Errorf("got model %q and finish reason %q", c.Model, *c.Choices[0].FinishReason)
+			}
+			if c.Usage == nil || c.Usage.TotalTokens != c.Usage.PromptTokens+c.Usage.CompletionTokens || c.Usage.PromptTokens == 0 {
+				t.Errorf("invalid usage %#v", c.Usage)
+			}
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	resp := &text.Response{}
+	resp.Metadata.InputTokenCount.TotalTokens = 5
+	resp.Metadata.OutputTokenCount.TotalTokens = 52
+	want := &Usage{PromptTokens: 5, CompletionTokens: 52, TotalTokens: 57}
+	if got := usage(resp, "ignored"); !reflect.DeepEqual(got, want) {
+		t.Errorf("usage() = %#v, want %#v", got, want)
+	}
+}
+
+func TestCompletionsStreamError(t *testing.T) {
+	srv := httptest.NewServer(newOpenAIServer(echoGenerator(errors.New("model unavailable"))))
+	defer srv.Close()
+	resp, err := http.Post(srv.URL+"/v1/completions", "application/json", strings.NewReader(`{"prompt": "a", "stream": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); !strings.Contains(got, "model unavailable") || !strings.HasSuffix(got, "data: [DONE]\n\n") {
+		t.Errorf("got stream %q, want the error followed by [DONE]", got)
+	}
+}
+
+func TestChatCompletionsStream(t *testing.T) {
+	srv := httptest.NewServer(newOpenAIServer(&fakeStreamer{}))
+	defer srv.Close()
+	body := `{"stream": true, "messages": [{"role": "user", "content": "hello world"}]}`
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var content []string
+	var done bool
+	s := bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		data, ok := strings.CutPrefix(s.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var c Completion
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			t.Fatal(err)
+		}
+		if c.Object != "chat.completion.chunk" {
+			t.Errorf("got object %q", c.Object)
+		}
+		d := c.Choices[0].Delta
+		content = append(content, d.Role+d.Content)
+	}
+	want := []string{"assistant", "hello", "world", ""}
+	if !done || !reflect.DeepEqual(content, want) {
+		t.Errorf("got chunks %q (done %v), want %q", content, done, want)
+	}
+}
@@ -0,0 +1,348 @@
+// Package server exposes a text.Generator as an HTTP/JSON API, so that
+// other tools can generate text with shared credentials without embedding
+// the text package.
+//
+// The API has the following endpoints:
+//
+//	POST /v1/generate         generates text and returns a text.Response
+//	POST /v1/generate/stream  generates text as server-sent events
+//	GET  /healthz             reports that the server is running
+//	GET  /readyz              reports if the server accepts requests
+//
+// Both generation endpoints accept a Request as JSON. Endpoints compatible
+// with the OpenAI API can be added with HandleOpenAI. When the Server has
+// a Token, all endpoints but the health checks require it as a bearer
+// token in the Authorization header.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// DefaultMaxBodyBytes is the default limit of the request body size.
+const DefaultMaxBodyBytes = 1 << 20
+
+// Request is the body of the generation endpoints.
+type Request struct {
+	// Prompt is the text sent to the model. It is required.
+	Prompt string `json:"prompt"`
+	// Context is the prompt context template, as in text.GenerateText.
+	// When empty, the prompt is sent as is.
+	Context string `json:"context,omitempty"`
+	// Preset is the name of the parameters in text.Presets used as the
+	// base for Parameters. The default preset is used when it is empty.
+	Preset string `json:"preset,omitempty"`
+	// Parameters override the values of the preset.
+	Parameters Parameters `json:"parameters,omitempty"`
+}
+
+// Parameters are the model parameters of a Request. Only the fields that
+// are set change the preset values.
+type Parameters struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	TopK            *int     `json:"topK,omitempty"`
+	MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
+	CandidateCount  *int     `json:"candidateCount,omitempty"`
+}
+
+// Error is the body of the responses with an error.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Server is an http.Handler that serves the API with a single Generator,
+// shared by all requests.
+type Server struct {
+	// Generator generates the text. If it is also a text.Streamer, it is
+	// used by the streaming endpoint; otherwise the whole response is sent
+	// as a single event.
+	Generator text.Generator
+	// Logger receives the access logs.
+	Logger *slog.Logger
+	// MaxBodyBytes limits the size of the request body.
+	MaxBodyBytes int64
+	// Token, if set, must be sent as "Authorization: Bearer TOKEN" in
+	// the requests, except to /healthz and /readyz. The API uses the
+	// credentials and quota of the server, so a Token should be set
+	// whenever it is reachable by other hosts.
+	Token string
+
+	mux   *http.ServeMux
+	ready atomic.Bool
+}
+
+// New returns a ready Server that uses the generator, logging to the
+// default slog.Logger.
+func New(g text.Generator) *Server {
+	s := &Server{
+		Generator:    g,
+		Logger:       slog.Default(),
+		MaxBodyBytes: DefaultMaxBodyBytes,
+		mux:          http.NewServeMux(),
+	}
+	s.ready.Store(true)
+	s.mux.HandleFunc("/v1/generate", s.post(s.generate))
+	s.mux.HandleFunc("/v1/generate/stream", s.post(s.stream))
+	s.mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	s.mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !s.ready.Load() {
+			writeError(w, http.StatusServiceUnavailable, errors.New("server is shutting down"))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+	})
+	return s
+}
+
+// Handle registers an additional handler for the pattern, like the ones
+// of other API flavors.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// SetReady changes the readiness reported by /readyz. It should be set to
+// false before shutting down, so that load balancers stop sending new
+// requests.
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// ServeHTTP serves the request and writes its access log.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+	if s.authorized(r) {
+		s.mux.ServeHTTP(rec, r)
+	} else {
+		rec.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(rec, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+	}
+	if s.Logger != nil {
+		s.Logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote", r.RemoteAddr),
+			slog.String("userAgent", r.UserAgent()),
+		)
+	}
+}
+
+// authorized reports if the request has the Token, when it is required.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.Token == "" || r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+		return true
+	}
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	return strings.EqualFold(scheme, "Bearer") &&
+		subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(s.Token)) == 1
+}
+
+// post accepts only POST requests with a valid Request in the body.
+func (s *Server) post(handler func(http.ResponseWriter, *http.Request, *Request, text.Parameters)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		req := &Request{}
+		if status, err := s.decode(w, r, req); err != nil {
+			writeError(w, status, err)
+			return
+		}
+		params, err := req.Validate()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Context == "" {
+			req.Context = "%s"
+		}
+		handler(w, r, req, params)
+	}
+}
+
+// decode reads the JSON body into v, returning the status code to use if
+// it fails.
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v any) (int, error) {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return http.StatusRequestEntityTooLarge, fmt.Errorf("request body larger than %d bytes", maxErr.Limit)
+		}
+		return http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err)
+	}
+	return http.StatusOK, nil
+}
+
+// Validate checks the request and returns the model parameters to use.
+func (req *Request) Validate() (text.Parameters, error) {
+	if strings.TrimSpace(req.Prompt) == "" {
+		return text.Parameters{}, errors.New("prompt is required")
+	}
+	return req.Parameters.apply(req.Preset)
+}
+
+// apply returns the preset parameters with the values that are set,
+// checking that they are in the ranges accepted by the model.
+func (p Parameters) apply(preset string) (text.Parameters, error) {
+	if preset == "" {
+		preset = "default"
+	}
+	params, ok := text.Presets[preset]
+	if !ok {
+		return params, fmt.Errorf("unknown preset %q, use one of %s",
+			preset, strings.Join(text.PresetNames(), ", "))
+	}
+	checkFloat := func(name string, v *float64, dst *float64) error {
+		if v == nil {
+			return nil
+		}
+		if *v < 0 || *v > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+		*dst = *v
+		return nil
+	}
+	checkInt := func(name string, v *int, min, max int, dst *int) error {
+		if v == nil {
+			return nil
+		}
+		if *v < min || *v > max {
+			return fmt.Errorf("%s must be between %d and %d", name, min, max)
+		}
+		*dst = *v
+		return nil
+	}
+	maxOutput := text.ModelLimits[text.ModelVersion].OutputTokens
+	for _, err := range []error{
+		checkFloat("temperature", p.Temperature, &params.Temperature),
+		checkFloat("topP", p.TopP, &params.TopP),
+		checkInt("topK", p.TopK, 1, 40, &params.TopK),
+		checkInt("maxOutputTokens", p.MaxOutputTokens, 1, maxOutput, &params.MaxTokens),
+		checkInt("candidateCount", p.CandidateCount, 1, 4, &params.CandidateCount),
+	} {
+		if err != nil {
+			return params, err
+		}
+	}
+	return params, nil
+}
+
+// generate returns the full response as JSON.
+func (s *Server) generate(w http.ResponseWriter, r *http.Request, req *Request, params text.Parameters) {
+	resp, err := s.Generator.GenerateText(r.Context(), req.Context, req.Prompt, params)
+	if err != nil {
+		writeError(w, generateStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// stream sends each chunk of the generated text as a "prediction" event,
+// followed by a "done" event. Errors are sent as an "error" event, since
+// the status code was already sent.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request, req *Request, params text.Parameters) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	send := func(event string, v any) error {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	var err error
+	if streamer, ok := s.Generator.(text.Streamer); ok {
+		err = streamer.GenerateTextStream(r.Context(), req.Context, req.Prompt, params, func(p text.Prediction) error {
+			return send("prediction", p)
+		})
+	} else {
+		var resp *text.Response
+		if resp, err = s.Generator.GenerateText(r.Context(), req.Context, req.Prompt, params); err == nil {
+			for _, p := range resp.Predictions {
+				if err = send("prediction", p); err != nil {
+					break
+				}
+			}
+		}
+	}
+	if err != nil {
+		send("error", Error{Code: generateStatus(err), Message: err.Error()})
+		return
+	}
+	send("done", struct{}{})
+}
+
+// generateStatus returns the status code for an error of the generator.
+func generateStatus(err error) int {
+	if errors.Is(err, text.ErrPromptTooLong) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadGateway
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]Error{"error": {Code: status, Message: err.Error()}})
+}
+
+// recorder keeps the status and size of the response for the access log.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+	"github.com/ronoaldo/genai-demos/pkg/text/texttest"
+)
+
+// echoGenerator returns a Generator that echoes the prompt, or fails
+// with err when it is not nil.
+func echoGenerator(err error) *texttest.Generator {
+	return &texttest.Generator{Err: err, Respond: func(c texttest.Call) (*text.Response, error) {
+		return texttest.Reply(c.Compiled()), nil
+	}}
+}
+
+// fakeStreamer sends each word of the prompt as a chunk.
+type fakeStreamer struct {
+	texttest.Generator
+}
+
+func (f *fakeStreamer) GenerateTextStream(ctx context.Context, promptContext, prompt string, params text.Parameters, fn func(text.Prediction) error) error {
+	for _, w := range strings.Fields(prompt) {
+		if err := fn(text.Prediction{Content: w}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestServer(g text.Generator) *Server {
+	s := New(g)
+	s.Logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	return s
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		err        error
+		wantStatus int
+		wantBody   string
+	}{
+		{"prompt", "POST", `{"prompt": "hello"}`, nil, 200, `"content":"hello"`},
+		{"context", "POST", `{"prompt": "hello", "context": "Say: %s"}`, nil, 200, `"content":"Say: hello"`},
+		{"missing prompt", "POST", `{"context": "x"}`, nil, 400, "prompt is required"},
+		{"unknown field", "POST", `{"prompt": "x", "temp": 1}`, nil, 400, "unknown field"},
+		{"invalid json", "POST", `{`, nil, 400, "invalid request body"},
+		{"unknown preset", "POST", `{"prompt": "x", "preset": "wild"}`, nil, 400, "unknown preset"},
+		{"temperature", "POST", `{"prompt": "x", "parameters": {"temperature": 1.5}}`, nil, 400, "temperature must be"},
+		{"top-k", "POST", `{"prompt": "x", "parameters": {"topK": 0}}`, nil, 400, "topK must be"},
+		{"method", "GET", ``, nil, 405, "not allowed"},
+		{"too long", "POST", `{"prompt": "x"}`, text.ErrPromptTooLong, 413, "too long"},
+		{"model error", "POST", `{"prompt": "x"}`, errors.New("quota exceeded"), 502, "quota exceeded"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(echoGenerator(tc.err))
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, httptest.NewRequest(tc.method, "/v1/generate", strings.NewReader(tc.body)))
+			if w.Code != tc.wantStatus {
+				t.Errorf("got status %d, want %d", w.Code, tc.wantStatus)
+			}
+			if !strings.Contains(w.Body.String(), tc.wantBody) {
+				t.Errorf("body %q does not contain %q", w.Body.String(), tc.wantBody)
+			}
+		})
+	}
+}
+
+func TestGenerateParameters(t *testing.T) {
+	g := echoGenerator(nil)
+	s := newTestServer(g)
+	body := `{"prompt": "x", "preset": "deterministic", "parameters": {"maxOutputTokens": 256, "candidateCount": 2}}`
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/v1/generate", strings.NewReader(body)))
+	want := text.MoreDeterministic
+	want.MaxTokens, want.CandidateCount = 256, 2
+	if w.Code != 200 || g.Last().Params != want {
+		t.Errorf("got status %d and params %#v, want %#v", w.Code, g.Last().Params, want)
+	}
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name string
+		g    text.Generator
+		want []string
+	}{
+		{"streamer", &fakeStreamer{}, []string{
+			"event: prediction", `data: {"content":"hello"`,
+			"event: prediction", `data: {"content":"world"`,
+			"event: done",
+		}},
+		{"generator", echoGenerator(nil), []string{
+			"event: prediction", `data: {"content":"hello world"`,
+			"event: done",
+		}},
+		{"error", echoGenerator(errors.New("boom")), []string{
+			"event: error", `data: {"code":502,"message":"boom"}`,
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(newTestServer(tc.g))
+			defer srv.Close()
+			resp, err := http.Post(srv.URL+"/v1/generate/stream", "application/json", strings.NewReader(`{"prompt": "hello world"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+				t.Errorf("got content type %q", ct)
+			}
+			var got []string
+			s := bufio.NewScanner(resp.Body)
+			for s.Scan() {
+				if line := s.Text(); line != "" && line != "data: {}" {
+					got = append(got, line)
+				}
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got events %q, want %q", got, tc.want)
+			}
+			for i := range got {
+				if !strings.HasPrefix(got[i], tc.want[i]) {
+					t.Errorf("got event line %q, want %q", got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s := newTestServer(echoGenerator(nil))
+	check := func(path string, want int) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s: got status %d, want %d", path, w.Code, want)
+		}
+	}
+	check("/healthz", 200)
+	check("/readyz", 200)
+	s.SetReady(false)
+	check("/healthz", 200)
+	check("/readyz", 503)
+}
+
+func TestToken(t *testing.T) {
+	s := newTestServer(echoGenerator(nil))
+	s.Token = "s3cr3t"
+	tests := []struct {
+		path          string
+		authorization string
+		want          int
+	}{
+		{"/v1/generate", "", 401},
+		{"/v1/generate", "Bearer wrong", 401},
+		{"/v1/generate", "Basic s3cr3t", 401},
+		{"/v1/generate", "Bearer s3cr3t", 200},
+		{"/healthz", "", 200},
+		{"/readyz", "", 200},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest("POST", tc.path, strings.NewReader(`{"prompt": "hello"}`))
+		if tc.path != "/v1/generate" {
+			req = httptest.NewRequest("GET", tc.path, nil)
+		}
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s with %q: got status %d, want %d", tc.path, tc.authorization, w.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formats are the formats accepted by Export.
+var Formats = []string{"markdown", "json"}
+
+// Export writes the session in the format, markdown or json.
+func Export(w io.Writer, sess *Session, format string) error {
+	switch format {
+	case "markdown", "md":
+		return WriteMarkdown(w, sess)
+	case "json":
+		return WriteJSON(w, sess)
+	}
+	return fmt.Errorf("session: unknown format %q, use one of %s", format, strings.Join(Formats, ", "))
+}
+
+// WriteJSON writes the session as indented JSON, like it is stored.
+func WriteJSON(w io.Writer, sess *Session) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sess)
+}
+
+// maxHeadingChars limits the size of the prompt used as the heading of
+// each turn. Longer prompts are also included in a code block.
+const maxHeadingChars = 80
+
+// WriteMarkdown writes the session as Markdown, with a section for each
+// turn and the parameters, model and token usage of its response.
+func WriteMarkdown(w io.Writer, sess *Session) error {
+	var b strings.Builder
+	u := sess.Usage()
+	fmt.Fprintf(&b, "# %s\n\n_%s, %d turns, from %s to %s, %d input and %d output tokens._\n",
+		sess.Name, sess.Tool, len(sess.Turns),
+		sess.Created.Format("2006-01-02 15:04"), sess.Updated.Format("2006-01-02 15:04"),
+		u.InputTokenCount.TotalTokens, u.OutputTokenCount.TotalTokens)
+	for i, t := range sess.Turns {
+		prompt := strings.TrimSpace(t.Prompt)
+		heading, _, multiline := strings.Cut(prompt, "\n")
+		if r := []rune(heading); len(r) > maxHeadingChars {
+			heading = string(r[:maxHeadingChars-1]) + "…"
+			multiline = true
+		}
+		fmt.Fprintf(&b, "\n## %d. %s\n\n", i+1, heading)
+		if multiline {
+			fmt.Fprintf(&b, "```text\n%s\n```\n\n", prompt)
+		}
+		p := t.Parameters
+		fmt.Fprintf(&b, "%s\n\n_%s, %s, temperature=%g topk=%d topp=%g max=%d, %d input and %d output tokens._\n",
+			strings.TrimSpace(t.Response), t.Time.Format("2006-01-02 15:04:05"), t.Model,
+			p.Temperature, p.TopK, p.TopP, p.MaxTokens,
+			t.Usage.InputTokenCount.TotalTokens, t.Usage.OutputTokenCount.TotalTokens)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,57 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+func TestExport(t *testing.T) {
+	created := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	sess := &Session{Name: "logs", Tool: "log-guru", Created: created}
+	sess.Add(Turn{Time: created, Prompt: "Quais erros?", Response: "Nenhum erro.",
+		Parameters: text.DefaultParameters, Usage: usage(5, 10)})
+	sess.Add(Turn{Time: created.Add(time.Minute), Prompt: "Resumo:\n2 entradas ERROR", Response: "Falha no banco.",
+		Parameters: text.DefaultParameters, Usage: usage(30, 12)})
+
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"markdown", "# logs\n\n" +
+			"_log-guru, 2 turns, from 2023-10-01 12:00 to 2023-10-01 12:01, 35 input and 22 output tokens._\n\n" +
+			"## 1. Quais erros?\n\nNenhum erro.\n\n" +
+			"_2023-10-01 12:00:00, text-bison@001, temperature=0.2 topk=40 topp=0.8 max=1024, 5 input and 10 output tokens._\n\n" +
+			"## 2. Resumo:\n\n```text\nResumo:\n2 entradas ERROR\n```\n\nFalha no banco.\n\n" +
+			"_2023-10-01 12:01:00, text-bison@001, temperature=0.2 topk=40 topp=0.8 max=1024, 30 input and 12 output tokens._\n",
+			false},
+		{"json", "", false},
+		{"html", "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			var b strings.Builder
+			err := Export(&b, sess, tc.format)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Export() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.want != "" && b.String() != tc.want {
+				t.Errorf("Export() =\n%s\nwant\n%s", b.String(), tc.want)
+			}
+			if tc.format == "json" {
+				var got Session
+				if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got.Turns, sess.Turns) {
+					t.Errorf("Export() JSON turns = %+v, want %+v", got.Turns, sess.Turns)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package session keeps the conversations with the models between
+// invocations of the command line tools, so that they can be resumed,
+// listed, deleted and exported.
+//
+// Each session is a JSON file in the store directory, named after the
+// session.
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// ErrNotFound is returned when the session does not exist.
+var ErrNotFound = errors.New("session: not found")
+
+// Turn is a prompt sent to the model and its response.
+type Turn struct {
+	Time     time.Time `json:"time"`
+	Prompt   string    `json:"prompt"`
+	Response string    `json:"response"`
+	// Parameters and Model are the ones used to generate the response.
+	Parameters text.Parameters    `json:"parameters"`
+	Model      string             `json:"model"`
+	Usage      text.TokenMetadata `json:"usage"`
+}
+
+// Session is a named conversation.
+type Session struct {
+	Name string `json:"name"`
+	// Tool is the command that created the session.
+	Tool    string    `json:"tool"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	Turns   []Turn    `json:"turns"`
+}
+
+// Add appends the turn to the session, setting its time if empty.
+func (s *Session) Add(t Turn) {
+	if t.Time.IsZero() {
+		t.Time = time.Now()
+	}
+	if t.Model == "" {
+		t.Model = text.ModelVersion
+	}
+	s.Turns = append(s.Turns, t)
+	s.Updated = t.Time
+}
+
+// Usage returns the total of tokens and billable characters used by the
+// turns of the session.
+func (s *Session) Usage() text.TokenMetadata {
+	var u text.TokenMetadata
+	for _, t := range s.Turns {
+		u.InputTokenCount.TotalTokens += t.Usage.InputTokenCount.TotalTokens
+		u.InputTokenCount.TotalBillableCharacters += t.Usage.InputTokenCount.TotalBillableCharacters
+		u.OutputTokenCount.TotalTokens += t.Usage.OutputTokenCount.TotalTokens
+		u.OutputTokenCount.TotalBillableCharacters += t.Usage.OutputTokenCount.TotalBillableCharacters
+	}
+	return u
+}
+
+// Store keeps the sessions as files in a directory.
+type Store struct {
+	Dir string
+}
+
+// NewStore returns a store that keeps the sessions in dir.
+func NewStore(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// DefaultDir returns the directory where the sessions of the tool are
+// kept, in the user configuration directory.
+func DefaultDir(tool string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, tool, "sessions")
+}
+
+var namePattern = regexp.MustCompile(`^[\w.-]+$`)
+
+// ValidName reports an error if the name can not be used as a session
+// name. Names must be made of letters, digits, dots, dashes and
+// underscores.
+func ValidName(name string) error {
+	if !namePattern.MatchString(name) || strings.Trim(name, ".") == "" {
+		return fmt.Errorf("session: invalid name %q: use only letters, digits, '.', '-' and '_'", name)
+	}
+	return nil
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.Dir, name+".json")
+}
+
+// Load reads the session. It returns ErrNotFound if the session does not
+// exist.
+func (s *Store) Load(name string) (*Session, error) {
+	if err := ValidName(name); err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(s.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+	} else if err != nil {
+		return nil, err
+	}
+	sess := &Session{}
+	if err := json.Unmarshal(b, sess); err != nil {
+		return nil, fmt.Errorf("session: reading %q: %w", name, err)
+	}
+	sess.Name = name
+	return sess, nil
+}
+
+// Open reads the session, or returns a new one for the tool if it does
+// not exist yet. New sessions are only written by Save.
+func (s *Store) Open(name, tool string) (*Session, error) {
+	sess, err := s.Load(name)
+	if errors.Is(err, ErrNotFound) {
+		now := time.Now()
+		return &Session{Name: name, Tool: tool, Created: now, Updated: now}, nil
+	}
+	return sess, err
+}
+
+// Save writes the session, replacing the previous file only after the new
+// content is completely written.
+func (s *Store) Save(sess *Session) error {
+	if err := ValidName(sess.Name); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(sess, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	path := s.path(sess.Name)
+	tmp, err := os.CreateTemp(s.Dir, filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Delete removes the session. It returns ErrNotFound if the session does
+// not exist.
+func (s *Store) Delete(name string) error {
+	if err := ValidName(name); err != nil {
+		return err
+	}
+	err := os.Remove(s.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	return err
+}
+
+// List returns the sessions in the store, most recently updated first.
+// An empty or missing directory has no sessions.
+func (s *Store) List() ([]*Session, error) {
+	files, err := os.ReadDir(s.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var sessions []*Session
+	for _, f := range files {
+		name, ok := strings.CutSuffix(f.Name(), ".json")
+		if !ok || f.IsDir() || ValidName(name) != nil {
+			continue
+		}
+		sess, err := s.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+	return sessions, nil
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+func usage(in, out int) text.TokenMetadata {
+	var u text.TokenMetadata
+	u.InputTokenCount.TotalTokens = in
+	u.OutputTokenCount.TotalTokens = out
+	return u
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "sessions"))
+	if sessions, err := s.List(); err != nil || len(sessions) != 0 {
+		t.Fatalf("List() on a missing directory = %v, %v, want no sessions", sessions, err)
+	}
+
+	sess, err := s.Open("backup", "linux-guru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sess.Turns) != 0 || sess.Tool != "linux-guru" {
+		t.Fatalf("Open() of a new session = %+v", sess)
+	}
+	if _, err := s.Load("backup"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Load() before Save() error = %v, want ErrNotFound", err)
+	}
+	first := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	sess.Add(Turn{Time: first, Prompt: "como fazer backup?", Response: "Use tar.",
+		Parameters: text.DefaultParameters, Usage: usage(5, 10)})
+	sess.Add(Turn{Time: first.Add(time.Minute), Prompt: "e compactado?", Response: "Use tar -czf.",
+		Parameters: text.MoreDeterministic, Usage: usage(20, 15)})
+	if err := s.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := s.Open("disco", "linux-guru")
+	other.Add(Turn{Time: first.Add(-time.Hour), Prompt: "uso de disco?", Response: "Use df."})
+	if err := s.Save(other); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Load("backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Updated.Equal(first.Add(time.Minute)) || !got.Created.Equal(sess.Created) {
+		t.Errorf("Load() times = %v, %v", got.Created, got.Updated)
+	}
+	if !reflect.DeepEqual(got.Turns, sess.Turns) {
+		t.Errorf("Load() turns = %+v, want %+v", got.Turns, sess.Turns)
+	}
+	if got.Turns[0].Model != text.ModelVersion {
+		t.Errorf("turn model = %q, want %q", got.Turns[0].Model, text.ModelVersion)
+	}
+	if u := got.Usage(); u != usage(25, 25) {
+		t.Errorf("Usage() = %+v", u)
+	}
+
+	sessions, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, sess := range sessions {
+		names = append(names, sess.Name)
+	}
+	if want := []string{"backup", "disco"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("List() = %v, want %v", names, want)
+	}
+
+	if err := s.Delete("disco"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("disco"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() of a deleted session error = %v, want ErrNotFound", err)
+	}
+	if sessions, _ := s.List(); len(sessions) != 1 {
+		t.Errorf("List() after Delete() has %d sessions, want 1", len(sessions))
+	}
+}
+
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"backup", false},
+		{"projeto-2023_10.v2", false},
+		{"", true},
+		{"..", true},
+		{"../etc/passwd", true},
+		{"com espaço", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ValidName(tc.name); (err != nil) != tc.wantErr {
+				t.Errorf("ValidName(%q) error = %v, wantErr %v", tc.name, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package shell
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Part is a piece of a command line and what it does.
+type Part struct {
+	Text        string
+	Description string
+}
+
+// commands describes the most common commands suggested in the answers.
+var commands = map[string]string{
+	"apt":        "gerencia pacotes no Debian e Ubuntu",
+	"apt-get":    "gerencia pacotes no Debian e Ubuntu",
+	"awk":        "processa texto por colunas",
+	"cat":        "mostra o conteúdo de arquivos",
+	"cd":         "muda o diretório atual",
+	"chmod":      "altera as permissões de arquivos",
+	"chown":      "altera o dono de arquivos",
+	"cp":         "copia arquivos",
+	"curl":       "transfere dados de uma URL",
+	"cut":        "extrai partes de cada linha",
+	"dd":         "copia dados em blocos, inclusive de e para dispositivos",
+	"df":         "mostra o espaço livre nos sistemas de arquivos",
+	"dnf":        "gerencia pacotes no Fedora",
+	"du":         "mostra o espaço usado por arquivos e diretórios",
+	"echo":       "mostra um texto",
+	"find":       "procura arquivos",
+	"free":       "mostra o uso de memória",
+	"git":        "controla versões de código",
+	"grep":       "procura linhas que contêm um padrão",
+	"head":       "mostra o início de arquivos",
+	"journalctl": "mostra os logs do systemd",
+	"kill":       "envia um sinal para processos",
+	"less":       "mostra arquivos página por página",
+	"ln":         "cria links entre arquivos",
+	"ls":         "lista arquivos",
+	"mkdir":      "cria diretórios",
+	"mount":      "monta sistemas de arquivos",
+	"mv":         "move ou renomeia arquivos",
+	"ps":         "lista processos",
+	"rm":         "remove arquivos",
+	"rsync":      "sincroniza arquivos",
+	"sed":        "edita texto em fluxo",
+	"sh":         "executa comandos em um shell",
+	"bash":       "executa comandos em um shell",
+	"sort":       "ordena linhas",
+	"ssh":        "acessa outro computador remotamente",
+	"sudo":       "executa o comando seguinte como superusuário",
+	"systemctl":  "controla os serviços do systemd",
+	"tail":       "mostra o final de arquivos",
+	"tar":        "cria ou extrai arquivos compactados",
+	"tee":        "grava a entrada em arquivos e na saída",
+	"top":        "mostra os processos em execução",
+	"touch":      "cria arquivos ou atualiza a data de modificação",
+	"uname":      "mostra informações do sistema",
+	"uniq":       "remove linhas repetidas consecutivas",
+	"wc":         "conta linhas, palavras e caracteres",
+	"wget":       "baixa arquivos da internet",
+	"xargs":      "executa um comando com os argumentos recebidos pela entrada",
+	"zip":        "compacta arquivos no formato zip",
+}
+
+// options describes the options of some commands.
+var options = map[string]map[string]string{
+	"ls":    {"-l": "formato longo, com permissões, dono, tamanho e data", "-a": "inclui arquivos ocultos", "-h": "tamanhos legíveis", "-S": "ordena por tamanho", "-t": "ordena por data", "-r": "inverte a ordem", "-R": "lista os subdiretórios"},
+	"rm":    {"-r": "remove diretórios e seu conteúdo", "-R": "remove diretórios e seu conteúdo", "-f": "não pede confirmação e ignora arquivos inexistentes", "-i": "pede confirmação", "-v": "mostra o que é removido"},
+	"cp":    {"-r": "copia diretórios", "-R": "copia diretórios", "-a": "preserva atributos e copia diretórios", "-v": "mostra o que é copiado", "-i": "pede confirmação antes de sobrescrever"},
+	"mv":    {"-i": "pede confirmação antes de sobrescrever", "-v": "mostra o que é movido", "-f": "sobrescreve sem confirmação"},
+	"tar":   {"-c": "cria um arquivo", "-x": "extrai um arquivo", "-z": "usa compressão gzip", "-j": "usa compressão bzip2", "-J": "usa compressão xz", "-v": "mostra os arquivos processados", "-f": "nome do arquivo", "-t": "lista o conteúdo"},
+	"grep":  {"-r": "procura nos subdiretórios", "-i": "ignora maiúsculas e minúsculas", "-n": "mostra o número das linhas", "-v": "mostra as linhas que não contêm o padrão", "-l": "mostra apenas o nome dos arquivos", "-E": "usa expressões regulares estendidas"},
+	"du":    {"-h": "tamanhos legíveis", "-s": "mostra apenas o total", "-a": "inclui arquivos"},
+	"df":    {"-h": "tamanhos legíveis", "-T": "mostra o tipo do sistema de arquivos"},
+	"chmod": {"-R": "altera também os subdiretórios"},
+	"chown": {"-R": "altera também os subdiretórios"},
+	"mkdir": {"-p": "cria os diretórios intermediários"},
+	"ps":    {"-e": "todos os processos", "-f": "formato completo"},
+	"curl":  {"-f": "falha em erros HTTP", "-s": "não mostra o progresso", "-S": "mostra erros", "-L": "segue redirecionamentos", "-o": "grava em um arquivo", "-O": "grava com o nome remoto"},
+	"sudo":  {"-u": "executa como outro usuário", "-i": "inicia um shell de login"},
+	"kill":  {"-9": "força o encerramento imediato (SIGKILL)"},
+	"tail":  {"-f": "continua mostrando novas linhas", "-n": "número de linhas"},
+	"head":  {"-n": "número de linhas"},
+	"sort":  {"-n": "ordem numérica", "-r": "ordem inversa", "-h": "ordena tamanhos legíveis", "-u": "remove repetidos"},
+	"find":  {"-name": "filtra pelo nome", "-type": "filtra pelo tipo", "-size": "filtra pelo tamanho", "-mtime": "filtra pela data de modificação", "-delete": "remove os arquivos encontrados", "-exec": "executa um comando para cada arquivo"},
+}
+
+// operatorDescriptions describes the list and pipeline operators.
+var operatorDescriptions = map[string]string{
+	"|":  "envia a saída do comando anterior para a entrada do próximo",
+	"&&": "executa o próximo comando somente se o anterior tiver sucesso",
+	"||": "executa o próximo comando somente se o anterior falhar",
+	";":  "executa o próximo comando em seguida",
+	"&":  "executa o comando anterior em segundo plano",
+}
+
+// Explain describes each part of the script: the commands, their known
+// options, the redirections and the operators.
+func Explain(s *Script) []Part {
+	var parts []Part
+	for _, p := range s.Pipelines {
+		for i, c := range p.Commands {
+			if i > 0 {
+				parts = append(parts, Part{"|", operatorDescriptions["|"]})
+			}
+			parts = append(parts, explainCommand(c)...)
+		}
+		if p.Op != "" {
+			parts = append(parts, Part{p.Op, operatorDescriptions[p.Op]})
+		}
+	}
+	return parts
+}
+
+func explainCommand(c Command) []Part {
+	var parts []Part
+	for _, a := range c.Assignments {
+		name, _, _ := strings.Cut(a, "=")
+		parts = append(parts, Part{a, fmt.Sprintf("define a variável de ambiente %s para o comando", name)})
+	}
+	if len(c.Args) > 0 {
+		name := filepath.Base(c.Args[0])
+		desc, ok := commands[name]
+		if !ok {
+			desc = fmt.Sprintf("comando não reconhecido; consulte `man %s`", name)
+		}
+		parts = append(parts, Part{c.Args[0], desc})
+		for i := 1; i < len(c.Args); i++ {
+			arg := c.Args[i]
+			if !strings.HasPrefix(arg, "-") || len(arg) == 1 {
+				if name == "sudo" {
+					// Explain the command run by sudo
+					parts = append(parts, explainCommand(Command{Args: c.Args[i:]})...)
+					break
+				}
+				continue
+			}
+			parts = append(parts, explainOption(name, arg)...)
+			if name == "sudo" && (arg == "-u" || arg == "-g") {
+				i++ // skip the user or group
+			}
+		}
+	}
+	return append(parts, explainRedirects(c)...)
+}
+
+// explainOption describes an option, splitting combined short options.
+func explainOption(name, arg string) []Part {
+	known := options[name]
+	if desc, ok := known[arg]; ok {
+		return []Part{{arg, desc}}
+	}
+	// find uses long options with a single dash
+	if strings.HasPrefix(arg, "--") || len(arg) == 2 || name == "find" {
+		return []Part{{arg, fmt.Sprintf("opção de %s; consulte `man %s`", name, name)}}
+	}
+	var parts []Part
+	for _, c := range arg[1:] {
+		opt := "-" + string(c)
+		desc, ok := known[opt]
+		if !ok {
+			desc = fmt.Sprintf("opção de %s; consulte `man %s`", name, name)
+		}
+		parts = append(parts, Part{opt, desc})
+	}
+	return parts
+}
+
+func explainRedirects(c Command) []Part {
+	var parts []Part
+	for _, r := range c.Redirects {
+		text := r.Op + " " + r.Target
+		var desc string
+		switch op := strings.TrimLeft(r.Op, "0123456789"); {
+		case op == ">&" && isDigits(r.Target):
+			desc = "junta a saída de erros com a saída padrão"
+			text = r.Op + r.Target
+		case op == ">" || op == ">&" || op == "&>":
+			desc = fmt.Sprintf("grava a saída em %s, substituindo o conteúdo", r.Target)
+		case op == ">>":
+			desc = fmt.Sprintf("acrescenta a saída ao final de %s", r.Target)
+		case op == "<":
+			desc = fmt.Sprintf("lê a entrada de %s", r.Target)
+		case op == "<<":
+			desc = "lê a entrada das linhas seguintes (here-document)"
+		}
+		if strings.HasPrefix(r.Op, "2") && desc != "" && !strings.HasPrefix(desc, "junta") {
+			desc = strings.Replace(desc, "a saída", "a saída de erros", 1)
+		}
+		parts = append(parts, Part{text, desc})
+	}
+	return parts
+}
@@ -0,0 +1,177 @@
+package shell
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// shellLanguages are the code block languages that contain shell commands.
+var shellLanguages = map[string]bool{
+	"": true, "sh": true, "bash": true, "shell": true, "zsh": true, "console": true, "terminal": true,
+}
+
+var inlineCode = regexp.MustCompile("`([^`\n]+)`")
+
+// Extract returns the shell commands found in the code blocks of a
+// Markdown answer, in order and without repetitions. The "$ " prompts are
+// removed, and lines continued with a backslash are joined. In blocks with
+// prompts, the lines without them are considered output and ignored.
+//
+// Inline code is also considered when it starts with a known command and
+// has arguments, like `ls -la`.
+func Extract(answer string) []string {
+	var cmds []string
+	seen := make(map[string]bool)
+	add := func(cmd string) {
+		cmd = strings.TrimSpace(cmd)
+		if cmd != "" && !seen[cmd] {
+			seen[cmd] = true
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	lines := strings.Split(answer, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "```") {
+			for _, m := range inlineCode.FindAllStringSubmatch(line, -1) {
+				if looksLikeCommand(m[1]) {
+					add(m[1])
+				}
+			}
+			continue
+		}
+		lang := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "```")))
+		var block []string
+		for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+			block = append(block, lines[i])
+		}
+		if shellLanguages[lang] {
+			for _, cmd := range blockCommands(block) {
+				add(cmd)
+			}
+		}
+	}
+	return cmds
+}
+
+// blockCommands returns the commands in the lines of a code block.
+// Control structures, like for or if, and here-documents spanning several
+// lines are kept together as a single command, with the lines separated
+// by newlines, so that they are assessed and run as a whole.
+func blockCommands(block []string) []string {
+	prompts := false
+	for _, line := range block {
+		if _, ok := cutPrompt(line); ok {
+			prompts = true
+			break
+		}
+	}
+	var cmds []string
+	var current strings.Builder
+	var heredocs []string // delimiters of the open here-documents
+	continued, depth := false, 0
+	for _, raw := range block {
+		if len(heredocs) > 0 {
+			current.WriteString(raw + "\n")
+			if delim := strings.TrimLeft(raw, "\t"); delim == heredocs[0] {
+				heredocs = heredocs[1:]
+			}
+			if len(heredocs) == 0 && depth == 0 {
+				cmds = append(cmds, strings.TrimSuffix(current.String(), "\n"))
+				current.Reset()
+			}
+			continue
+		}
+		line := strings.TrimSpace(raw)
+		if !continued {
+			cmd, ok := cutPrompt(line)
+			if prompts && !ok {
+				if depth == 0 {
+					continue // command output
+				}
+				cmd = strings.TrimSpace(strings.TrimPrefix(line, ">"))
+			}
+			line = cmd
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+		}
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSpace(strings.TrimSuffix(line, "\\")) + " ")
+			continued = true
+			continue
+		}
+		continued = false
+		current.WriteString(line)
+		depth = max(depth+nesting(line), 0)
+		for _, m := range heredoc.FindAllStringSubmatch(line, -1) {
+			heredocs = append(heredocs, m[2])
+		}
+		if depth > 0 || len(heredocs) > 0 {
+			current.WriteString("\n")
+			continue
+		}
+		cmds = append(cmds, current.String())
+		current.Reset()
+	}
+	if current.Len() > 0 {
+		cmds = append(cmds, strings.TrimSpace(current.String()))
+	}
+	return cmds
+}
+
+// heredoc matches the start of a here-document, like <<EOF or <<-'EOF',
+// but not the here-strings started by <<<.
+var heredoc = regexp.MustCompile(`(?:^|[^<])<<(-?)\s*['"]?([A-Za-z_][A-Za-z0-9_]*)['"]?`)
+
+// nesting returns how many control structures the line opens, minus the
+// ones it closes. Only the words in the position of a command are
+// considered, so that echo done is not the end of a loop.
+func nesting(line string) int {
+	n, command, function := 0, true, false
+	for _, word := range strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' }) {
+		separator := strings.TrimRight(word, ";&|")
+		atCommand := command
+		if command {
+			switch strings.Trim(separator, "()") {
+			case "if", "for", "while", "until", "case", "select", "{":
+				n++
+			case "fi", "done", "esac", "}":
+				n--
+			}
+		}
+		switch strings.Trim(separator, "()") {
+		case "then", "do", "else", "elif", "{", "!", "if", "while", "until":
+			command = true
+		default:
+			// the body of a function definition, like f() { or
+			// function f {, starts in the position of a command
+			command = separator != word || word == "&&" || word == "||" || word == "|" ||
+				function || strings.HasSuffix(word, "()")
+		}
+		function = atCommand && word == "function"
+	}
+	return n
+}
+
+// cutPrompt removes the "$ " shell prompt from the line. The root prompt
+// "# " is not removed, since it can't be told apart from comments.
+func cutPrompt(line string) (string, bool) {
+	if cmd, ok := strings.CutPrefix(line, "$ "); ok {
+		return strings.TrimSpace(cmd), true
+	}
+	return line, false
+}
+
+// looksLikeCommand returns true if the inline code starts with a known
+// command and has arguments.
+func looksLikeCommand(code string) bool {
+	fields := strings.Fields(code)
+	if len(fields) < 2 {
+		return false
+	}
+	_, known := commands[filepath.Base(fields[0])]
+	return known
+}
@@ -0,0 +1,337 @@
+// Package shell extracts shell commands from generated answers and helps
+// reviewing them before execution: commands are parsed with a small POSIX
+// shell tokenizer, classified by risk and explained part by part.
+//
+// The parser understands quoting, pipelines, lists and redirections, which
+// is enough to review the commands suggested by the models. It does not
+// support control structures, functions or here-documents.
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Redirect is an input or output redirection, like "> file" or "2>&1".
+type Redirect struct {
+	Op     string // one of <, >, >>, >&, &>, <<, optionally prefixed by a file descriptor
+	Target string
+}
+
+// Command is a simple command with its arguments and redirections.
+type Command struct {
+	// Assignments are the variable assignments before the command name.
+	Assignments []string
+	// Args are the command name and its arguments, with quotes removed.
+	Args      []string
+	Redirects []Redirect
+	// Substitutions are the contents of the $(...) and `...` command
+	// substitutions found in the arguments.
+	Substitutions []string
+}
+
+// Name returns the command name, or an empty string.
+func (c Command) Name() string {
+	if len(c.Args) == 0 {
+		return ""
+	}
+	return c.Args[0]
+}
+
+// Pipeline is a sequence of commands connected by pipes.
+type Pipeline struct {
+	Commands []Command
+	// Op is the operator that ends the pipeline: ";", "&&", "||", "&" or
+	// empty for the last one.
+	Op string
+}
+
+// Script is a parsed command line.
+type Script struct {
+	Source    string
+	Pipelines []Pipeline
+}
+
+// Commands returns all commands in the script.
+func (s *Script) Commands() []Command {
+	var cmds []Command
+	for _, p := range s.Pipelines {
+		cmds = append(cmds, p.Commands...)
+	}
+	return cmds
+}
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	opToken
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	subst []string // command substitutions found in a word
+}
+
+// operators are sorted so that the longest ones match first.
+var operators = []string{"&&", "||", ">>", ">&", "&>", "<<", "|", "&", ";", ">", "<", "\n"}
+
+// tokenize splits the input into words and operators, removing quotes and
+// comments.
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	var word strings.Builder
+	var subst []string
+	inWord := false
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, token{kind: wordToken, value: word.String(), subst: subst})
+		}
+		word.Reset()
+		subst = nil
+		inWord = false
+	}
+
+	r := []rune(input)
+	for i := 0; i < len(r); i++ {
+		c := r[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			flush()
+		case c == '\\':
+			if i+1 < len(r) && r[i+1] == '\n' {
+				i++ // line continuation
+				continue
+			}
+			if i+1 < len(r) {
+				i++
+				word.WriteRune(r[i])
+			}
+			inWord = true
+		case c == '\'':
+			end := indexRune(r, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("shell: unterminated single quote")
+			}
+			word.WriteString(string(r[i+1 : end]))
+			i, inWord = end, true
+		case c == '"':
+			i++
+			for ; i < len(r) && r[i] != '"'; i++ {
+				switch {
+				case r[i] == '\\' && i+1 < len(r) && strings.ContainsRune("\"\\$`", r[i+1]):
+					i++
+					word.WriteRune(r[i])
+				case r[i] == '$' && i+1 < len(r) && r[i+1] == '(' || r[i] == '`':
+					end, err := substitution(r, i)
+					if err != nil {
+						return nil, err
+					}
+					subst = append(subst, substBody(r, i, end))
+					word.WriteString(string(r[i : end+1]))
+					i = end
+				default:
+					word.WriteRune(r[i])
+				}
+			}
+			if i >= len(r) {
+				return nil, fmt.Errorf("shell: unterminated double quote")
+			}
+			inWord = true
+		case c == '$' && i+1 < len(r) && r[i+1] == '(' || c == '`':
+			end, err := substitution(r, i)
+			if err != nil {
+				return nil, err
+			}
+			subst = append(subst, substBody(r, i, end))
+			word.WriteString(string(r[i : end+1]))
+			i, inWord = end, true
+		case c == '#' && !inWord:
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+			i--
+		default:
+			op := matchOperator(r[i:])
+			if op == "" {
+				word.WriteRune(c)
+				inWord = true
+				continue
+			}
+			// A file descriptor number directly before a redirection is
+			// part of the operator, like 2> or 2>&1.
+			prefix := ""
+			if (op[0] == '>' || op[0] == '<') && inWord && isDigits(word.String()) && len(subst) == 0 {
+				prefix = word.String()
+				word.Reset()
+				inWord = false
+			}
+			flush()
+			tokens = append(tokens, token{kind: opToken, value: prefix + op})
+			i += len(op) - 1
+		}
+	}
+	flush()
+	return tokens, nil
+}
+
+func matchOperator(r []rune) string {
+	for _, op := range operators {
+		if strings.HasPrefix(string(r[:min(len(r), 2)]), op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func indexRune(r []rune, start int, c rune) int {
+	for i := start; i < len(r); i++ {
+		if r[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// substitution returns the position of the end of the command substitution
+// starting at i, with either $( or a backquote.
+func substitution(r []rune, i int) (int, error) {
+	if r[i] == '`' {
+		if end := indexRune(r, i+1, '`'); end >= 0 {
+			return end, nil
+		}
+		return 0, fmt.Errorf("shell: unterminated command substitution")
+	}
+	depth := 0
+	var quote rune
+	for j := i + 1; j < len(r); j++ {
+		switch {
+		case quote != 0:
+			if r[j] == quote {
+				quote = 0
+			}
+		case r[j] == '\'' || r[j] == '"':
+			quote = r[j]
+		case r[j] == '(':
+			depth++
+		case r[j] == ')':
+			depth--
+			if depth == 0 {
+				return j, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("shell: unterminated command substitution")
+}
+
+// substBody returns the command inside the substitution from i to end.
+func substBody(r []rune, i, end int) string {
+	if r[i] == '`' {
+		return string(r[i+1 : end])
+	}
+	return string(r[i+2 : end])
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse parses the command line.
+func Parse(input string) (*Script, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	s := &Script{Source: strings.TrimSpace(input)}
+	var pipeline Pipeline
+	var cmd Command
+	endCommand := func() error {
+		if len(cmd.Args) == 0 && len(cmd.Assignments) == 0 && len(cmd.Redirects) == 0 {
+			return fmt.Errorf("shell: syntax error: missing command")
+		}
+		pipeline.Commands = append(pipeline.Commands, cmd)
+		cmd = Command{}
+		return nil
+	}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind == wordToken {
+			if len(cmd.Args) == 0 && isAssignment(t.value) {
+				cmd.Assignments = append(cmd.Assignments, t.value)
+			} else {
+				cmd.Args = append(cmd.Args, t.value)
+			}
+			cmd.Substitutions = append(cmd.Substitutions, t.subst...)
+			continue
+		}
+		op := strings.TrimLeft(t.value, "0123456789")
+		switch op {
+		case "|":
+			if err := endCommand(); err != nil {
+				return nil, err
+			}
+		case "&&", "||", ";", "&", "\n":
+			if op == "\n" && i > 0 && tokens[i-1].value == "|" {
+				continue // pipeline continues in the next line
+			}
+			if op == "\n" {
+				op = ";"
+			}
+			if len(cmd.Args) == 0 && len(cmd.Redirects) == 0 && len(pipeline.Commands) == 0 {
+				if op == ";" {
+					continue // empty line
+				}
+				return nil, fmt.Errorf("shell: syntax error near %q", op)
+			}
+			if err := endCommand(); err != nil {
+				return nil, err
+			}
+			pipeline.Op = op
+			s.Pipelines = append(s.Pipelines, pipeline)
+			pipeline = Pipeline{}
+		default:
+			if i+1 >= len(tokens) || tokens[i+1].kind != wordToken {
+				return nil, fmt.Errorf("shell: syntax error: missing target for %q", t.value)
+			}
+			i++
+			cmd.Redirects = append(cmd.Redirects, Redirect{Op: t.value, Target: tokens[i].value})
+			cmd.Substitutions = append(cmd.Substitutions, tokens[i].subst...)
+		}
+	}
+	if len(cmd.Args) > 0 || len(cmd.Redirects) > 0 || len(cmd.Assignments) > 0 {
+		endCommand()
+	} else if len(pipeline.Commands) > 0 {
+		return nil, fmt.Errorf("shell: syntax error: missing command after pipe")
+	}
+	if len(pipeline.Commands) > 0 {
+		s.Pipelines = append(s.Pipelines, pipeline)
+	}
+	// A trailing ";" is not meaningful
+	if n := len(s.Pipelines); n > 0 && s.Pipelines[n-1].Op == ";" {
+		s.Pipelines[n-1].Op = ""
+	}
+	return s, nil
+}
+
+// isAssignment returns true if the word is a variable assignment.
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
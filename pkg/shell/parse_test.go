@@ -0,0 +1,99 @@
+package shell
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantArgs [][]string // args of each command
+		wantOps  []string   // operator after each pipeline
+		wantErr  bool
+	}{
+		{"ls -la", [][]string{{"ls", "-la"}}, []string{""}, false},
+		{`echo "a b" 'c $d' e\ f`, [][]string{{"echo", "a b", "c $d", "e f"}}, []string{""}, false},
+		{`echo "say \"hi\""`, [][]string{{"echo", `say "hi"`}}, []string{""}, false},
+		{"du -sh * | sort -h | tail -n 5", [][]string{{"du", "-sh", "*"}, {"sort", "-h"}, {"tail", "-n", "5"}}, []string{""}, false},
+		{"make && make install || echo falhou; ls &", [][]string{{"make"}, {"make", "install"}, {"echo", "falhou"}, {"ls"}}, []string{"&&", "||", ";", "&"}, false},
+		{"ls # comentário", [][]string{{"ls"}}, []string{""}, false},
+		{"LANG=C sort a.txt", [][]string{{"sort", "a.txt"}}, []string{""}, false},
+		{"tar -czf \\\n  backup.tgz ~", [][]string{{"tar", "-czf", "backup.tgz", "~"}}, []string{""}, false},
+		{"ls |\n  wc -l", [][]string{{"ls"}, {"wc", "-l"}}, []string{""}, false},
+		{"echo $(date +%F) `whoami`", [][]string{{"echo", "$(date +%F)", "`whoami`"}}, []string{""}, false},
+		{"echo 'sem fim", nil, nil, true},
+		{`echo "sem fim`, nil, nil, true},
+		{"echo $(date", nil, nil, true},
+		{"| grep x", nil, nil, true},
+		{"ls |", nil, nil, true},
+		{"ls > ", nil, nil, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			s, err := Parse(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var args [][]string
+			for _, c := range s.Commands() {
+				args = append(args, c.Args)
+			}
+			var ops []string
+			for _, p := range s.Pipelines {
+				ops = append(ops, p.Op)
+			}
+			if !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Errorf("got args %q, want %q", args, tc.wantArgs)
+			}
+			if !reflect.DeepEqual(ops, tc.wantOps) {
+				t.Errorf("got operators %q, want %q", ops, tc.wantOps)
+			}
+		})
+	}
+}
+
+func TestParseRedirects(t *testing.T) {
+	s, err := Parse("LANG=C make 2>&1 >> build.log < /dev/null 2> erros.txt &> tudo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := s.Commands()[0]
+	want := []Redirect{{"2>&", "1"}, {">>", "build.log"}, {"<", "/dev/null"}, {"2>", "erros.txt"}, {"&>", "tudo.txt"}}
+	if !reflect.DeepEqual(c.Redirects, want) {
+		t.Errorf("got redirects %q, want %q", c.Redirects, want)
+	}
+	if !reflect.DeepEqual(c.Assignments, []string{"LANG=C"}) || !reflect.DeepEqual(c.Args, []string{"make"}) {
+		t.Errorf("got assignments %q and args %q", c.Assignments, c.Args)
+	}
+
+	s, err = Parse(`sh -c "$(curl -fsSL https://example.com/install.sh)"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Commands()[0].Substitutions; len(got) != 1 || !strings.HasPrefix(got[0], "curl ") {
+		t.Errorf("got substitutions %q", got)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	s, err := Parse("sudo du -sh /var/log/* | sort -rh > uso.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range Explain(s) {
+		got = append(got, p.Text)
+		if p.Description == "" {
+			t.Errorf("part %q has no description", p.Text)
+		}
+	}
+	want := []string{"sudo", "du", "-s", "-h", "|", "sort", "-r", "-h", "> uso.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got parts %q, want %q", got, want)
+	}
+}
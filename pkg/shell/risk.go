@@ -0,0 +1,512 @@
+package shell
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Risk is how dangerous it is to run a command.
+type Risk int
+
+const (
+	// Low risk commands only read information or change files in the
+	// current directory.
+	Low Risk = iota
+	// Medium risk commands need attention, like removing files, running
+	// as root or writing outside the home directory.
+	Medium
+	// High risk commands can destroy data or compromise the system, and
+	// are not executed unless forced.
+	High
+)
+
+func (r Risk) String() string {
+	switch r {
+	case Low:
+		return "baixo"
+	case Medium:
+		return "médio"
+	default:
+		return "alto"
+	}
+}
+
+// Assessment is the risk of a command and the reasons for it.
+type Assessment struct {
+	Risk    Risk
+	Reasons []string
+}
+
+func (a *Assessment) add(r Risk, format string, args ...interface{}) {
+	reason := fmt.Sprintf(format, args...)
+	for _, existing := range a.Reasons {
+		if existing == reason {
+			return
+		}
+	}
+	a.Reasons = append(a.Reasons, reason)
+	if r > a.Risk {
+		a.Risk = r
+	}
+}
+
+// systemDirs are the directories where writes can break the system.
+var systemDirs = []string{"/etc", "/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/boot", "/dev", "/sys", "/proc", "/var", "/opt", "/root", "/srv", "/snap"}
+
+// safeTargets are locations outside the home directory that are safe to
+// write to.
+var safeTargets = []string{"/tmp", "/var/tmp", "/dev/null", "/dev/stdout", "/dev/stderr", "/dev/tty"}
+
+var forkBomb = regexp.MustCompile(`:\s*\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}`)
+
+// maxDepth limits how many nested eval and sh -c payloads are assessed.
+const maxDepth = 5
+
+// AssessLine parses and assesses the command line. Commands that cannot
+// be parsed are returned with a nil Script and high risk, since what they
+// run is unknown.
+func AssessLine(line, home string) (*Script, Assessment) {
+	s, err := Parse(line)
+	if err != nil {
+		a := Assessment{}
+		a.add(High, "não foi possível analisar o comando: %v", err)
+		if forkBomb.MatchString(line) {
+			a.add(High, "cria processos indefinidamente (fork bomb)")
+		}
+		return nil, a
+	}
+	return s, Assess(s, home)
+}
+
+// Assess classifies the risk of running the script. The home directory is
+// used to detect writes outside of it. Control structures are not fully
+// understood by the parser, so scripts using them are at least high risk,
+// but the commands inside them are still assessed.
+func Assess(s *Script, home string) Assessment {
+	a := Assessment{}
+	assess(&a, s, home, 0)
+	return a
+}
+
+func assess(a *Assessment, s *Script, home string, depth int) {
+	if forkBomb.MatchString(s.Source) {
+		a.add(High, "cria processos indefinidamente (fork bomb)")
+	}
+	for _, p := range s.Pipelines {
+		downloads := false
+		for _, c := range p.Commands {
+			args, sudo, xargs := c.Args, false, false
+			for {
+				inner, structure := control(args)
+				if structure != "" {
+					a.add(High, "usa a estrutura %q do shell, que não é analisada por completo", structure)
+				}
+				inner, s, x := unwrap(inner)
+				sudo, xargs = sudo || s, xargs || x
+				done := len(inner) == len(args)
+				if args = inner; done {
+					break
+				}
+			}
+			name := ""
+			if len(args) > 0 {
+				name = filepath.Base(args[0])
+			}
+			if sudo {
+				a.add(Medium, "executa como superusuário")
+			}
+			if downloads && isInterpreter(name) {
+				a.add(High, "executa um script baixado da internet sem revisão")
+			}
+			if name == "curl" || name == "wget" {
+				downloads = true
+			}
+			if isInterpreter(name) {
+				for _, sub := range append(c.Substitutions, redirectTargets(c, "<")...) {
+					if strings.Contains(sub, "curl") || strings.Contains(sub, "wget") {
+						a.add(High, "executa um script baixado da internet sem revisão")
+					}
+				}
+			}
+			if xargs && name != "" {
+				a.add(Medium, "executa %s com os argumentos recebidos pela entrada", name)
+			}
+			if code, ok := payload(a, name, args); ok {
+				assessPayload(a, code, home, depth)
+			}
+			for _, sub := range c.Substitutions {
+				assessPayload(a, sub, home, depth)
+			}
+			assessCommand(a, name, args, home)
+			for _, r := range c.Redirects {
+				if strings.TrimLeft(r.Op, "0123456789") == "<<" {
+					a.add(High, "usa um here-document, cujo conteúdo não é analisado")
+				}
+				if strings.Contains(r.Op, ">") && !(strings.Contains(r.Op, "&") && isDigits(r.Target)) {
+					checkWrite(a, r.Target, home)
+				}
+			}
+		}
+	}
+}
+
+// assessPayload parses and assesses the commands run by eval, sh -c or a
+// command substitution.
+func assessPayload(a *Assessment, code, home string, depth int) {
+	if depth >= maxDepth {
+		a.add(High, "executa comandos aninhados demais para serem analisados")
+		return
+	}
+	s, err := Parse(code)
+	if err != nil {
+		a.add(High, "executa um comando que não foi possível analisar: %v", err)
+		return
+	}
+	assess(a, s, home, depth+1)
+}
+
+// payload returns the code run by eval or by a shell with -c. Code for
+// other interpreters can't be analyzed and is reported as high risk.
+func payload(a *Assessment, name string, args []string) (string, bool) {
+	switch name {
+	case "eval":
+		return strings.Join(args[1:], " "), len(args) > 1
+	case "sh", "bash", "zsh", "dash", "ksh", "fish":
+		command := false
+		for _, arg := range args[1:] {
+			switch {
+			case arg == "--":
+			case strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "+"):
+				if !strings.HasPrefix(arg, "--") && strings.Contains(arg, "c") {
+					command = true
+				}
+			case command:
+				return arg, true
+			default:
+				return "", false
+			}
+		}
+	case "python", "python3", "perl", "ruby", "node":
+		for _, arg := range args[1:] {
+			if arg == "-c" || arg == "-e" || arg == "--eval" {
+				a.add(High, "executa código %s que não é analisado", name)
+			}
+		}
+	}
+	return "", false
+}
+
+// control removes the reserved words and the group delimiters before the
+// command, so that the command inside a group, loop or condition is
+// assessed. It returns the control structure found, if any, as those are
+// not modeled by the parser. Loop headers and case patterns have no
+// command, and nil is returned for them.
+func control(args []string) (inner []string, structure string) {
+	args = append([]string(nil), args...)
+	for len(args) > 0 {
+		first := args[0]
+		switch {
+		case first == "for" || first == "select" || first == "case" || first == "function" || strings.HasPrefix(first, "(("):
+			return nil, first
+		case first == "if" || first == "elif" || first == "while" || first == "until":
+			structure = first
+		case first == "{" || first == "}" || first == "!" || first == "do" || first == "done" ||
+			first == "then" || first == "else" || first == "fi" || first == "esac" || first == ")":
+		case len(first) > 2 && strings.HasSuffix(first, "()"):
+			structure = "função"
+		case strings.HasPrefix(first, "("):
+			args[0] = strings.TrimLeft(first, "(")
+			if args[0] != "" {
+				continue
+			}
+		default:
+			if last := args[len(args)-1]; strings.HasSuffix(last, ")") && !strings.Contains(last, "(") {
+				if args[len(args)-1] = strings.TrimRight(last, ")"); args[len(args)-1] == "" {
+					args = args[:len(args)-1]
+				}
+			}
+			return args, structure
+		}
+		args = args[1:]
+	}
+	return args, structure
+}
+
+// unwrap removes the commands that run another command, like sudo or env,
+// returning the arguments of the command that is run.
+func unwrap(args []string) (inner []string, sudo, xargs bool) {
+	for len(args) > 0 {
+		switch filepath.Base(args[0]) {
+		case "sudo", "doas", "pkexec":
+			sudo = true
+		case "xargs":
+			xargs = true
+		case "env", "nohup", "time", "nice", "ionice", "exec", "command", "stdbuf", "timeout":
+		default:
+			return args, sudo, xargs
+		}
+		args = args[1:]
+		// Skip the options of the wrapper command and their values.
+		for len(args) > 0 && (strings.HasPrefix(args[0], "-") || isAssignment(args[0]) || isDigits(args[0])) {
+			if args[0] == "-u" || args[0] == "-g" || args[0] == "-n" || args[0] == "-I" {
+				args = args[1:]
+			}
+			if len(args) > 0 {
+				args = args[1:]
+			}
+		}
+	}
+	return args, sudo, xargs
+}
+
+func isInterpreter(name string) bool {
+	switch name {
+	case "sh", "bash", "zsh", "dash", "ksh", "fish", "python", "python3", "perl", "ruby", "node":
+		return true
+	}
+	return false
+}
+
+// redirectTargets returns the targets of the redirections with the op.
+func redirectTargets(c Command, op string) []string {
+	var targets []string
+	for _, r := range c.Redirects {
+		if strings.TrimLeft(r.Op, "0123456789") == op {
+			targets = append(targets, r.Target)
+		}
+	}
+	return targets
+}
+
+// flags splits the arguments into options, with combined short options
+// split like -rf into -r and -f, and operands.
+func flags(args []string) (opts map[string]bool, operands []string) {
+	opts = make(map[string]bool)
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return opts, append(operands, args[i+1:]...)
+		case strings.HasPrefix(arg, "--"):
+			name, _, _ := strings.Cut(arg, "=")
+			opts[name] = true
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for _, c := range arg[1:] {
+				opts["-"+string(c)] = true
+			}
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	return opts, operands
+}
+
+// assessCommand checks the rules specific to each command.
+func assessCommand(a *Assessment, name string, args []string, home string) {
+	if len(args) == 0 {
+		return
+	}
+	if strings.HasPrefix(name, "mkfs.") {
+		name = "mkfs"
+	}
+	opts, operands := flags(args[1:])
+	recursive := opts["-r"] || opts["-R"] || opts["--recursive"]
+	switch name {
+	case "rm", "rmdir", "unlink":
+		for _, target := range operands {
+			if isCritical(target, home) {
+				a.add(High, "remove %s", target)
+			} else if t := expand(target, home); path.IsAbs(t) && !under(t, home) && !isSafe(t) {
+				a.add(High, "remove arquivos fora da pasta pessoal (%s)", target)
+			}
+		}
+		if recursive || opts["-f"] || opts["--force"] {
+			a.add(Medium, "remove arquivos de forma recursiva ou forçada, sem possibilidade de recuperação")
+		} else {
+			a.add(Medium, "remove arquivos sem possibilidade de recuperação")
+		}
+	case "dd":
+		for _, arg := range args[1:] {
+			if target, ok := strings.CutPrefix(arg, "of="); ok {
+				if strings.HasPrefix(expand(target, home), "/dev/") && !isSafe(target) {
+					a.add(High, "grava diretamente no dispositivo %s", target)
+				} else {
+					checkWrite(a, target, home)
+				}
+			}
+		}
+	case "mkfs", "fdisk", "sfdisk", "gdisk", "cfdisk", "parted", "wipefs", "shred", "mkswap":
+		a.add(High, "formata ou apaga discos e partições")
+	case "chmod", "chown", "chgrp":
+		targets := operands
+		if len(targets) > 0 {
+			targets = targets[1:] // mode or owner
+		}
+		for _, target := range targets {
+			if isSystem(expand(target, home)) {
+				a.add(High, "altera permissões de arquivos do sistema (%s)", target)
+			}
+		}
+		if recursive {
+			a.add(Medium, "altera permissões de forma recursiva")
+		}
+		if name == "chmod" && len(operands) > 0 && (strings.HasSuffix(operands[0], "777") || operands[0] == "a+rwx") {
+			a.add(Medium, "permite que qualquer usuário altere os arquivos")
+		}
+	case "find":
+		if containsAny(args, "-delete") || containsAll(args, "-exec", "rm") {
+			a.add(Medium, "remove os arquivos encontrados")
+			// The paths to search come before the expression
+			for _, target := range args[1:] {
+				if strings.HasPrefix(target, "-") {
+					break
+				}
+				if t := expand(target, home); t == "/" || t == home || isSystem(t) {
+					a.add(High, "remove arquivos encontrados em %s", target)
+				}
+			}
+		}
+	case "mv", "cp", "ln", "install", "rsync", "scp":
+		if len(operands) > 0 {
+			checkWrite(a, operands[len(operands)-1], home)
+		}
+		if name == "mv" {
+			for _, source := range operands[:max(len(operands)-1, 0)] {
+				if isCritical(source, home) {
+					a.add(High, "move %s", source)
+				}
+			}
+		}
+	case "touch", "mkdir", "truncate", "tee":
+		for _, target := range operands {
+			checkWrite(a, target, home)
+		}
+	case "kill", "killall", "pkill":
+		a.add(Medium, "encerra processos")
+	case "reboot", "shutdown", "poweroff", "halt":
+		a.add(Medium, "reinicia ou desliga o sistema")
+	case "systemctl", "service":
+		if containsAny(args, "stop", "disable", "mask", "restart", "reboot", "poweroff", "halt") {
+			a.add(Medium, "para, reinicia ou desativa serviços")
+		}
+	case "apt", "apt-get", "dnf", "yum", "zypper", "snap", "flatpak", "pacman":
+		if containsAny(args, "remove", "purge", "autoremove", "erase", "uninstall", "-R", "-Rs", "-Rns") {
+			a.add(Medium, "remove pacotes do sistema")
+		}
+	case "git":
+		if containsAll(args, "push") && (opts["-f"] || opts["--force"]) ||
+			containsAll(args, "reset", "--hard") || containsAll(args, "clean") && opts["-f"] {
+			a.add(Medium, "descarta alterações do repositório")
+		}
+	case "iptables", "ip6tables", "nft", "ufw", "firewall-cmd":
+		a.add(Medium, "altera as regras do firewall")
+	case "userdel", "usermod", "passwd", "visudo", "chpasswd":
+		a.add(Medium, "altera usuários ou senhas")
+	case "crontab":
+		if opts["-r"] {
+			a.add(Medium, "remove todas as tarefas agendadas")
+		}
+	}
+}
+
+// checkWrite adds the risk of writing to the target.
+func checkWrite(a *Assessment, target, home string) {
+	t := expand(target, home)
+	if !path.IsAbs(t) || under(t, home) || isSafe(t) {
+		return
+	}
+	if isSystem(t) {
+		a.add(High, "grava em %s, um diretório do sistema", target)
+		return
+	}
+	a.add(Medium, "grava em %s, fora da pasta pessoal", target)
+}
+
+// expand replaces the home directory references in the path and cleans
+// absolute paths.
+func expand(p, home string) string {
+	switch {
+	case p == "~" || p == "$HOME" || p == "${HOME}":
+		p = home
+	case strings.HasPrefix(p, "~/"):
+		p = home + p[1:]
+	case strings.HasPrefix(p, "$HOME/"):
+		p = home + p[5:]
+	case strings.HasPrefix(p, "${HOME}/"):
+		p = home + p[7:]
+	}
+	if path.IsAbs(p) {
+		p = path.Clean(p)
+	}
+	return p
+}
+
+// under returns true if p is dir or is inside it.
+func under(p, dir string) bool {
+	if dir == "" || dir == "/" {
+		return false
+	}
+	return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/")
+}
+
+func isSafe(p string) bool {
+	for _, dir := range safeTargets {
+		if under(p, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSystem(p string) bool {
+	if p == "/" {
+		return true
+	}
+	for _, dir := range systemDirs {
+		if under(p, dir) && !isSafe(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCritical returns true for targets whose removal destroys the system,
+// the home directory or everything in the current directory.
+func isCritical(target, home string) bool {
+	switch target {
+	case "*", ".", "..", ".*", "./*", "/*":
+		return true
+	}
+	t := expand(strings.TrimSuffix(target, "/*"), home)
+	if t == "/" || t == home {
+		return true
+	}
+	for _, dir := range systemDirs {
+		if t == dir {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(args []string, values ...string) bool {
+	for _, arg := range args {
+		for _, v := range values {
+			if arg == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsAll(args []string, values ...string) bool {
+	for _, v := range values {
+		if !containsAny(args, v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package summarize
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunk is a piece of the input that fits in a single prompt.
+type Chunk struct {
+	// Index is the position of the chunk in the input, starting at zero.
+	Index int `json:"index"`
+	// Start and End are the byte offsets of the chunk in the input.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Units is the number of semantic units, like JSON entries or
+	// paragraphs, that are part of the chunk.
+	Units int    `json:"units"`
+	Text  string `json:"-"`
+}
+
+// unit is a piece of the input that should not be split, if possible.
+type unit struct {
+	start, end int
+}
+
+// Split breaks the input into chunks of at most maxChars bytes, keeping
+// semantic boundaries whenever possible: entries of a JSON array,
+// lines of newline-delimited JSON or paragraphs of plain text are never
+// split, unless a single one is larger than maxChars.
+func Split(input string, maxChars int) []Chunk {
+	if maxChars <= 0 {
+		maxChars = len(input)
+	}
+	units := jsonArrayUnits(input)
+	if units == nil {
+		units = ndjsonUnits(input)
+	}
+	if units == nil {
+		units = paragraphUnits(input)
+	}
+
+	var chunks []Chunk
+	var current *Chunk
+	flush := func() {
+		if current != nil {
+			current.Text = input[current.Start:current.End]
+			chunks = append(chunks, *current)
+			current = nil
+		}
+	}
+	for _, u := range splitLarge(input, units, maxChars) {
+		if current != nil && u.end-current.Start > maxChars {
+			flush()
+		}
+		if current == nil {
+			current = &Chunk{Index: len(chunks), Start: u.start}
+		}
+		current.End = u.end
+		current.Units++
+	}
+	flush()
+	return chunks
+}
+
+// jsonArrayUnits returns one unit for each element of a JSON array, or nil
+// if the input is not a valid JSON array.
+func jsonArrayUnits(input string) []unit {
+	if !strings.HasPrefix(strings.TrimSpace(input), "[") {
+		return nil
+	}
+	dec := json.NewDecoder(strings.NewReader(input))
+	if _, err := dec.Token(); err != nil {
+		return nil
+	}
+	var units []unit
+	for dec.More() {
+		var raw json.RawMessage
+		before := int(dec.InputOffset())
+		if err := dec.Decode(&raw); err != nil {
+			return nil
+		}
+		// InputOffset before decoding may point to the separator or
+		// whitespace preceding the value; skip them.
+		end := int(dec.InputOffset())
+		start := before + bytes.Index([]byte(input[before:end]), raw[:1])
+		units = append(units, unit{start, end})
+	}
+	return units
+}
+
+// ndjsonUnits returns one unit for each line of newline-delimited JSON, or
+// nil if any non-empty line is not a JSON value.
+func ndjsonUnits(input string) []unit {
+	var units []unit
+	pos := 0
+	for _, line := range strings.SplitAfter(input, "\n") {
+		start, end := pos, pos+len(line)
+		pos = end
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if !json.Valid([]byte(trimmed)) {
+			return nil
+		}
+		units = append(units, unit{start, end})
+	}
+	if len(units) < 2 {
+		return nil
+	}
+	return units
+}
+
+// paragraphUnits returns one unit for each paragraph, separated by blank
+// lines.
+func paragraphUnits(input string) []unit {
+	var units []unit
+	start := -1
+	pos := 0
+	for _, line := range strings.SplitAfter(input, "\n") {
+		blank := strings.TrimSpace(line) == ""
+		if blank && start >= 0 {
+			units = append(units, unit{start, pos})
+			start = -1
+		} else if !blank && start < 0 {
+			start = pos
+		}
+		pos += len(line)
+	}
+	if start >= 0 {
+		units = append(units, unit{start, pos})
+	}
+	return units
+}
+
+// splitLarge breaks units larger than maxChars at line boundaries, and
+// lines larger than maxChars at the last space before the limit, or at
+// the last character that fits if there is none.
+func splitLarge(input string, units []unit, maxChars int) []unit {
+	var result []unit
+	for _, u := range units {
+		if u.end-u.start <= maxChars {
+			result = append(result, u)
+			continue
+		}
+		start := u.start
+		for start < u.end {
+			end := min(start+maxChars, u.end)
+			if end < u.end {
+				cut := input[start:end]
+				if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+					end = start + i + 1
+				} else if i := strings.LastIndexByte(cut, ' '); i > 0 {
+					end = start + i + 1
+				} else {
+					for end > start && !utf8.RuneStart(input[end]) {
+						end--
+					}
+					if end == start {
+						// maxChars is smaller than the character
+						_, size := utf8.DecodeRuneInString(input[start:])
+						end = start + size
+					}
+				}
+			}
+			result = append(result, unit{start, end})
+			start = end
+		}
+	}
+	return result
+}
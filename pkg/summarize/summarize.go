@@ -0,0 +1,242 @@
+// Package summarize implements map-reduce summarization of inputs that are
+// larger than the context window of the text generation models.
+//
+// The input is split into chunks on semantic boundaries, each chunk is
+// summarized concurrently and the partial summaries are then recursively
+// merged until a single summary remains. Every summary keeps a reference
+// to the source chunks it was derived from.
+package summarize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// DefaultChunkContext is the prompt used to summarize each chunk.
+var DefaultChunkContext = `Summarize the following text in a few short topics,
+keeping relevant names, numbers and errors:
+
+%s
+`
+
+// DefaultMergeContext is the prompt used to merge partial summaries.
+var DefaultMergeContext = `The following are summaries of consecutive parts of a larger text.
+Merge them into a single summary in a few short topics, without repeating information:
+
+%s
+`
+
+// Summary is the result of summarizing one or more chunks.
+type Summary struct {
+	Text string `json:"text"`
+	// Sources are the indexes of the input chunks that this summary is
+	// derived from.
+	Sources []int `json:"sources"`
+	// Level is zero for chunk summaries and increases with each merge.
+	Level int `json:"level"`
+}
+
+// Result contains the final summary, as well as the intermediate ones.
+type Result struct {
+	Summary Summary   `json:"summary"`
+	Chunks  []Chunk   `json:"chunks"`
+	Partial []Summary `json:"partial"`
+}
+
+// Summarizer summarizes arbitrarily large texts with a text.Generator.
+type Summarizer struct {
+	Generator text.Generator
+	Params    text.Parameters
+
+	// ChunkContext and MergeContext are the prompt contexts used to
+	// summarize each chunk and to merge the partial summaries. If empty,
+	// DefaultChunkContext and DefaultMergeContext are used.
+	ChunkContext string
+	MergeContext string
+
+	// MaxChars is the maximum size of the text sent in each call.
+	MaxChars int
+	// Concurrency is the maximum number of concurrent calls.
+	Concurrency int
+}
+
+// New returns a Summarizer with the default prompts and limits.
+func New(gen text.Generator) *Summarizer {
+	return &Summarizer{
+		Generator:   gen,
+		Params:      text.DefaultParameters,
+		MaxChars:    16000,
+		Concurrency: 4,
+	}
+}
+
+// Summarize splits the input into chunks, summarizes them and merges the
+// summaries until a single one is left.
+func (s *Summarizer) Summarize(ctx context.Context, input string) (*Result, error) {
+	chunks := Split(input, s.MaxChars)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("summarize: empty input")
+	}
+	res := &Result{Chunks: chunks}
+
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Text
+	}
+	generated, err := s.generateAll(ctx, s.chunkContext(), texts)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]Summary, len(chunks))
+	for i, c := range chunks {
+		summaries[i] = Summary{Text: generated[i], Sources: []int{c.Index}}
+	}
+
+	for level := 1; len(summaries) > 1; level++ {
+		res.Partial = append(res.Partial, summaries...)
+		groups := s.group(summaries)
+		texts := make([]string, len(groups))
+		for i, g := range groups {
+			parts := make([]string, len(g))
+			for j, sum := range g {
+				parts[j] = sum.Text
+			}
+			texts[i] = strings.Join(parts, "\n\n")
+		}
+		generated, err := s.generateAll(ctx, s.mergeContext(), texts)
+		if err != nil {
+			return nil, err
+		}
+		merged := make([]Summary, len(groups))
+		for i, g := range groups {
+			merged[i] = Summary{Text: generated[i], Sources: sources(g), Level: level}
+		}
+		summaries = merged
+	}
+	res.Summary = summaries[0]
+	return res, nil
+}
+
+// group packs consecutive summaries into groups that fit in MaxChars.
+// Each group has at least two summaries, so that every merge level
+// reduces the number of summaries.
+func (s *Summarizer) group(summaries []Summary) [][]Summary {
+	var groups [][]Summary
+	var current []Summary
+	size := 0
+	for _, sum := range summaries {
+		if len(current) >= 2 && s.MaxChars > 0 && size+len(sum.Text) > s.MaxChars {
+			groups = append(groups, current)
+			current, size = nil, 0
+		}
+		current = append(current, sum)
+		size += len(sum.Text) + 2
+	}
+	if len(current) == 1 && len(groups) > 0 {
+		// Avoid carrying a lone summary to the next level.
+		groups[len(groups)-1] = append(groups[len(groups)-1], current[0])
+	} else if len(current) > 0 {
+		groups = append(groups, current)
+	}
+	return groups
+}
+
+// generateAll calls the model for each text, with at most Concurrency
+// calls in flight, returning the generated texts in the same order.
+func (s *Summarizer) generateAll(ctx context.Context, promptContext string, texts []string) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := s.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	results := make([]string, len(texts))
+	errs := make([]error, len(texts))
+	var wg sync.WaitGroup
+	for i := range texts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+			resp, err := s.Generator.GenerateText(ctx, promptContext, texts[i], s.Params)
+			switch {
+			case err != nil:
+				errs[i] = err
+				cancel()
+			case len(resp.Predictions) == 0:
+				errs[i] = fmt.Errorf("no predictions returned")
+				cancel()
+			default:
+				results[i] = strings.TrimSpace(resp.Predictions[0].Content)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, fmt.Errorf("summarize: part %d: %w", i+1, err)
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("summarize: %w", err)
+		}
+	}
+	return results, nil
+}
+
+func (s *Summarizer) chunkContext() string {
+	if s.ChunkContext == "" {
+		return DefaultChunkContext
+	}
+	return s.ChunkContext
+}
+
+func (s *Summarizer) mergeContext() string {
+	if s.MergeContext == "" {
+		return DefaultMergeContext
+	}
+	return s.MergeContext
+}
+
+// sources returns the sorted union of the sources of all summaries.
+func sources(summaries []Summary) []int {
+	var all []int
+	for _, s := range summaries {
+		all = append(all, s.Sources...)
+	}
+	sort.Ints(all)
+	return all
+}
+
+// FormatSources describes a list of chunk indexes as ranges, like "1-3, 5",
+// using one-based numbers.
+func FormatSources(sources []int) string {
+	var parts []string
+	for i := 0; i < len(sources); {
+		j := i
+		for j+1 < len(sources) && sources[j+1] == sources[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprintf("%d", sources[i]+1))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sources[i]+1, sources[j]+1))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,131 @@
+package summarize
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+	"github.com/ronoaldo/genai-demos/pkg/text/texttest"
+)
+
+func TestSplit(t *testing.T) {
+	entries := `[
+  {"severity": "ERROR", "textPayload": "first"},
+  {"severity": "INFO", "textPayload": "second"},
+  {"severity": "INFO", "textPayload": "third"}
+]`
+	ndjson := "{\"a\": 1}\n{\"b\": 2}\n{\"c\": 3}\n"
+	paragraphs := "First paragraph\nstill first.\n\nSecond paragraph.\n\n\nThird paragraph."
+	tests := []struct {
+		name      string
+		input     string
+		maxChars  int
+		wantUnits []int
+	}{
+		{"json array in one chunk", entries, 1000, []int{3}},
+		{"json array per entry", entries, 50, []int{1, 1, 1}},
+		{"json array two per chunk", entries, 100, []int{2, 1}},
+		{"ndjson", ndjson, 20, []int{2, 1}},
+		{"paragraphs", paragraphs, 30, []int{1, 1, 1}},
+		{"paragraphs together", paragraphs, 1000, []int{3}},
+		{"large paragraph", strings.Repeat("word ", 20), 30, []int{1, 1, 1, 1}},
+		{"large word", strings.Repeat("ção", 4), 4, []int{1, 1, 1, 1, 1, 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			chunks := Split(tc.input, tc.maxChars)
+			var units []int
+			for i, c := range chunks {
+				units = append(units, c.Units)
+				if c.Index != i {
+					t.Errorf("chunk %d has index %d", i, c.Index)
+				}
+				if len(c.Text) > tc.maxChars {
+					t.Errorf("chunk %d has %d chars, max %d", i, len(c.Text), tc.maxChars)
+				}
+				if !utf8.ValidString(c.Text) {
+					t.Errorf("chunk %d is not valid UTF-8: %q", i, c.Text)
+				}
+				if c.Text != tc.input[c.Start:c.End] {
+					t.Errorf("chunk %d text does not match offsets", i)
+				}
+			}
+			if fmt.Sprint(units) != fmt.Sprint(tc.wantUnits) {
+				t.Errorf("got units %v, want %v", units, tc.wantUnits)
+			}
+		})
+	}
+}
+
+func TestSplitJSONEntries(t *testing.T) {
+	entries := `[{"n": 1}, {"n": 2}]`
+	for _, c := range Split(entries, 10) {
+		var v map[string]int
+		if err := json.Unmarshal([]byte(c.Text), &v); err != nil {
+			t.Errorf("chunk %q is not a JSON entry: %v", c.Text, err)
+		}
+	}
+}
+
+// firstWords summarizes a text by keeping the first word of each line.
+func firstWords(c texttest.Call) (*text.Response, error) {
+	var words []string
+	for _, line := range strings.Split(c.Prompt, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			words = append(words, fields[0])
+		}
+	}
+	return texttest.Reply(strings.Join(words, "\n")), nil
+}
+
+func TestSummarize(t *testing.T) {
+	var paragraphs, want []string
+	for i := 0; i < 10; i++ {
+		id := fmt.Sprintf("paragraph-%02d", i)
+		paragraphs = append(paragraphs, id+" text to be summarized")
+		want = append(want, id)
+	}
+	s := New(&texttest.Generator{Respond: firstWords})
+	s.MaxChars = 40
+	res, err := s.Summarize(context.Background(), strings.Join(paragraphs, "\n\n"))
+	if err != nil {
+		t.Fatalf("Summarize() error = %v", err)
+	}
+	if len(res.Chunks) != 10 {
+		t.Errorf("got %d chunks, want 10", len(res.Chunks))
+	}
+	if got := strings.Fields(res.Summary.Text); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got summary %v, want %v", got, want)
+	}
+	if got, want := FormatSources(res.Summary.Sources), "1-10"; got != want {
+		t.Errorf("got sources %q, want %q", got, want)
+	}
+	if res.Summary.Level < 2 {
+		t.Errorf("got level %d, want recursive merges", res.Summary.Level)
+	}
+	for _, p := range res.Partial {
+		if len(p.Sources) == 0 {
+			t.Errorf("partial summary %q has no sources", p.Text)
+		}
+	}
+}
+
+func TestFormatSources(t *testing.T) {
+	tests := []struct {
+		sources []int
+		want    string
+	}{
+		{nil, ""},
+		{[]int{0}, "1"},
+		{[]int{0, 1, 2, 4, 6, 7}, "1-3, 5, 7-8"},
+	}
+	for _, tc := range tests {
+		if got := FormatSources(tc.sources); got != tc.want {
+			t.Errorf("FormatSources(%v) = %q, want %q", tc.sources, got, tc.want)
+		}
+	}
+}
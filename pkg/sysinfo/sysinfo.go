@@ -0,0 +1,173 @@
+// Package sysinfo collects a summary of the local system, like the Linux
+// distribution, kernel, shell and package managers, so that answers can
+// suggest commands that work on the user's machine.
+//
+// Only the information listed in Info is collected, and Summary shows
+// exactly what is shared with the model.
+package sysinfo
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Info is the information collected from the system.
+type Info struct {
+	// OS is the distribution name from /etc/os-release, like "Fedora Linux 38".
+	OS string `json:"os,omitempty"`
+	// ID and IDLike identify the distribution and its parents, like
+	// "ubuntu" and ["debian"].
+	ID     string   `json:"id,omitempty"`
+	IDLike []string `json:"idLike,omitempty"`
+	Kernel string   `json:"kernel,omitempty"`
+	Arch   string   `json:"arch"`
+	// Shell is the name of the user's shell, like "bash" or "zsh".
+	Shell string `json:"shell,omitempty"`
+	// PackageManagers are the package managers found in $PATH, with the
+	// one of the distribution first.
+	PackageManagers []string `json:"packageManagers,omitempty"`
+	// Binaries are the relevant tools found in $PATH.
+	Binaries []string `json:"binaries,omitempty"`
+}
+
+// packageManagers lists the known package managers, with the
+// distributions that use each one by default.
+var packageManagers = []struct {
+	name    string
+	distros []string
+}{
+	{"apt", []string{"debian", "ubuntu"}},
+	{"dnf", []string{"fedora", "rhel", "centos"}},
+	{"yum", []string{"rhel", "centos"}},
+	{"zypper", []string{"opensuse", "suse", "sles"}},
+	{"pacman", []string{"arch"}},
+	{"apk", []string{"alpine"}},
+	{"emerge", []string{"gentoo"}},
+	{"xbps-install", []string{"void"}},
+	{"nix-env", []string{"nixos"}},
+	{"brew", nil},
+	{"snap", nil},
+	{"flatpak", nil},
+}
+
+// Binaries are the tools looked up in $PATH. Their presence changes which
+// commands are suggested, like ip instead of ifconfig.
+var Binaries = []string{
+	"systemctl", "service", "journalctl", "ip", "ifconfig", "ss", "netstat",
+	"nmcli", "ufw", "firewall-cmd", "iptables", "docker", "podman", "kubectl",
+	"git", "curl", "wget", "rsync", "tar", "zip", "unzip", "python3", "vim",
+	"nano", "sudo", "doas", "lsblk", "fdisk",
+}
+
+// Collector reads the system information. The fields allow replacing the
+// sources of information in tests.
+type Collector struct {
+	// Root is the root of the file system, where etc/os-release and
+	// proc/sys/kernel/osrelease are read from.
+	Root     string
+	Getenv   func(key string) string
+	LookPath func(file string) (string, error)
+}
+
+// NewCollector returns a Collector for the local system.
+func NewCollector() *Collector {
+	return &Collector{Root: "/", Getenv: os.Getenv, LookPath: exec.LookPath}
+}
+
+// Collect returns the system information. Missing information is left
+// empty.
+func (c *Collector) Collect() *Info {
+	info := &Info{Arch: runtime.GOARCH}
+	release := c.osRelease()
+	info.OS = release["PRETTY_NAME"]
+	if info.OS == "" {
+		info.OS = strings.TrimSpace(release["NAME"] + " " + release["VERSION_ID"])
+	}
+	info.ID = release["ID"]
+	info.IDLike = strings.Fields(release["ID_LIKE"])
+	if b, err := os.ReadFile(filepath.Join(c.Root, "proc/sys/kernel/osrelease")); err == nil {
+		info.Kernel = strings.TrimSpace(string(b))
+	}
+	if shell := c.Getenv("SHELL"); shell != "" {
+		info.Shell = filepath.Base(shell)
+	}
+
+	distros := append([]string{info.ID}, info.IDLike...)
+	var others []string
+	for _, pm := range packageManagers {
+		if _, err := c.LookPath(pm.name); err != nil {
+			continue
+		}
+		if matches(distros, pm.distros) {
+			info.PackageManagers = append(info.PackageManagers, pm.name)
+		} else {
+			others = append(others, pm.name)
+		}
+	}
+	info.PackageManagers = append(info.PackageManagers, others...)
+	for _, name := range Binaries {
+		if _, err := c.LookPath(name); err == nil {
+			info.Binaries = append(info.Binaries, name)
+		}
+	}
+	return info
+}
+
+// osRelease parses the os-release file, looking also at the fallback
+// location in /usr/lib.
+func (c *Collector) osRelease() map[string]string {
+	values := make(map[string]string)
+	for _, name := range []string{"etc/os-release", "usr/lib/os-release"} {
+		f, err := os.Open(filepath.Join(c.Root, name))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			key, value, ok := strings.Cut(strings.TrimSpace(s.Text()), "=")
+			if !ok || strings.HasPrefix(key, "#") {
+				continue
+			}
+			values[key] = strings.Trim(value, `"'`)
+		}
+		break
+	}
+	return values
+}
+
+func matches(values, candidates []string) bool {
+	for _, v := range values {
+		for _, c := range candidates {
+			if v == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Summary returns the information formatted to be included in a prompt.
+func (i *Info) Summary() string {
+	var b strings.Builder
+	b.WriteString("Sistema do usuário (use comandos compatíveis com ele):\n")
+	line := func(label, value string) {
+		if value != "" {
+			b.WriteString("- " + label + ": " + value + "\n")
+		}
+	}
+	distro := i.OS
+	if i.ID != "" {
+		distro += " (" + strings.Join(append([]string{i.ID}, i.IDLike...), ", ") + ")"
+	}
+	line("Distribuição", strings.TrimSpace(distro))
+	line("Kernel", strings.TrimSpace(i.Kernel+" "+i.Arch))
+	line("Shell", i.Shell)
+	line("Gerenciadores de pacotes", strings.Join(i.PackageManagers, ", "))
+	line("Comandos disponíveis", strings.Join(i.Binaries, ", "))
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package sysinfo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func fakeCollector(t *testing.T, osRelease string, binaries ...string) *Collector {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"etc/os-release":            osRelease,
+		"proc/sys/kernel/osrelease": "6.5.6-300.fc39.x86_64\n",
+	}
+	for name, content := range files {
+		if content == "" {
+			continue
+		}
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Collector{
+		Root: root,
+		Getenv: func(key string) string {
+			if key == "SHELL" {
+				return "/usr/bin/zsh"
+			}
+			return ""
+		},
+		LookPath: func(file string) (string, error) {
+			for _, b := range binaries {
+				if b == file {
+					return "/usr/bin/" + file, nil
+				}
+			}
+			return "", errors.New("not found")
+		},
+	}
+}
+
+func TestCollect(t *testing.T) {
+	tests := []struct {
+		name         string
+		osRelease    string
+		binaries     []string
+		wantOS       string
+		wantManagers []string
+	}{
+		{
+			"fedora",
+			"NAME=\"Fedora Linux\"\nVERSION_ID=39\nID=fedora\nPRETTY_NAME=\"Fedora Linux 39 (Workstation Edition)\"\n",
+			[]string{"flatpak", "dnf", "yum", "git"},
+			"Fedora Linux 39 (Workstation Edition)",
+			[]string{"dnf", "yum", "flatpak"},
+		},
+		{
+			"ubuntu derivative",
+			"NAME=\"Linux Mint\"\nVERSION_ID=\"21.2\"\nID=linuxmint\nID_LIKE=\"ubuntu debian\"\n",
+			[]string{"snap", "apt"},
+			"Linux Mint 21.2",
+			[]string{"apt", "snap"},
+		},
+		{
+			"no os-release",
+			"",
+			nil,
+			"",
+			nil,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			info := fakeCollector(t, tc.osRelease, tc.binaries...).Collect()
+			if info.OS != tc.wantOS {
+				t.Errorf("got OS %q, want %q", info.OS, tc.wantOS)
+			}
+			if !reflect.DeepEqual(info.PackageManagers, tc.wantManagers) {
+				t.Errorf("got package managers %q, want %q", info.PackageManagers, tc.wantManagers)
+			}
+			if info.Shell != "zsh" || info.Kernel != "6.5.6-300.fc39.x86_64" {
+				t.Errorf("got shell %q and kernel %q", info.Shell, info.Kernel)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	info := fakeCollector(t, "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nID=debian\n", "apt", "ip", "git").Collect()
+	summary := info.Summary()
+	for _, want := range []string{
+		"Distribuição: Debian GNU/Linux 12 (bookworm) (debian)",
+		"Shell: zsh",
+		"Gerenciadores de pacotes: apt",
+		"Comandos disponíveis: ip, git",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary does not contain %q:\n%s", want, summary)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package text
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PredictPermission is the IAM permission required to call the models.
+const PredictPermission = "aiplatform.endpoints.predict"
+
+var (
+	// ErrNoCredentials is returned when no credentials were provided and
+	// the Application Default Credentials are not configured.
+	ErrNoCredentials = errors.New("text: no Google Cloud credentials found")
+	// ErrPermissionDenied is returned when the credentials are not allowed
+	// to call the model.
+	ErrPermissionDenied = errors.New("text: permission denied")
+)
+
+// Auth selects the credentials used by the TextClient. The zero value uses
+// the Application Default Credentials.
+type Auth struct {
+	// CredentialsFile is a service account key, or any other credentials
+	// JSON file, used instead of the Application Default Credentials.
+	CredentialsFile string
+	// TokenSource provides the access tokens, like an
+	// oauth2.StaticTokenSource with the output of
+	// `gcloud auth print-access-token`. It can't be used together with
+	// CredentialsFile.
+	TokenSource oauth2.TokenSource
+	// Impersonate is the email of a service account to impersonate with
+	// the credentials above. They need the
+	// iam.serviceAccounts.getAccessToken permission on it.
+	Impersonate string
+	// QuotaProject is the project billed for the quota of the calls,
+	// instead of the project of the credentials.
+	QuotaProject string
+}
+
+// Validate reports conflicting options.
+func (a Auth) Validate() error {
+	if a.CredentialsFile != "" && a.TokenSource != nil {
+		return errors.New("text: use either a credentials file or a token source, not both")
+	}
+	if a.Impersonate != "" && !strings.Contains(a.Impersonate, "@") {
+		return fmt.Errorf("text: invalid service account to impersonate: %q", a.Impersonate)
+	}
+	return nil
+}
+
+// ClientOptions returns the options that apply the credentials to the
+// clients of the Google Cloud APIs.
+func (a Auth) ClientOptions() []option.ClientOption {
+	var opts []option.ClientOption
+	if a.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(a.CredentialsFile))
+	}
+	if a.TokenSource != nil {
+		opts = append(opts, option.WithTokenSource(a.TokenSource))
+	}
+	if a.Impersonate != "" {
+		// The google.golang.org/api/impersonate package that replaces this
+		// option is not vendored.
+		opts = append(opts, option.ImpersonateCredentials(a.Impersonate))
+	}
+	if a.QuotaProject != "" {
+		opts = append(opts, option.WithQuotaProject(a.QuotaProject))
+	}
+	return opts
+}
+
+// SetAuth changes the credentials used to call the model. Conflicting
+// options are reported by Validate.
+func (t *TextClient) SetAuth(auth Auth) error {
+	if err := auth.Validate(); err != nil {
+		return err
+	}
+	t.auth = auth
+	return nil
+}
+
+// authError explains the errors caused by missing or insufficient
+// credentials. Other errors are returned unchanged.
+func (t *TextClient) authError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case strings.Contains(err.Error(), "could not find default credentials"):
+		return fmt.Errorf("%w: run `gcloud auth application-default login`, "+
+			"set GOOGLE_APPLICATION_CREDENTIALS or provide a credentials file", ErrNoCredentials)
+	case t.auth.Impersonate != "" && strings.Contains(err.Error(), "impersonate:"):
+		return fmt.Errorf("%w: cannot impersonate %s, the credentials need the "+
+			"iam.serviceAccounts.getAccessToken permission (roles/iam.serviceAccountTokenCreator): %v",
+			ErrPermissionDenied, t.auth.Impersonate, err)
+	}
+	switch status.Code(err) {
+	case codes.PermissionDenied:
+		return fmt.Errorf("%w: the credentials need the %s permission on project %q "+
+			"(roles/aiplatform.user): %v", ErrPermissionDenied, PredictPermission, t.projectID, err)
+	case codes.Unauthenticated:
+		return fmt.Errorf("%w: the credentials were rejected, they may be expired or revoked: %v",
+			ErrNoCredentials, err)
+	}
+	return err
+}
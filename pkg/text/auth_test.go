@@ -0,0 +1,74 @@
+package text
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    Auth
+		wantErr bool
+	}{
+		{"default credentials", Auth{}, false},
+		{"all options", Auth{CredentialsFile: "key.json", Impersonate: "bot@my-project.iam.gserviceaccount.com", QuotaProject: "billing"}, false},
+		{"token source", Auth{TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ya29.x"})}, false},
+		{"file and token source", Auth{CredentialsFile: "key.json", TokenSource: oauth2.StaticTokenSource(&oauth2.Token{})}, true},
+		{"invalid service account", Auth{Impersonate: "bot"}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.auth.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthError(t *testing.T) {
+	client := NewClient("my-project")
+	client.SetAuth(Auth{Impersonate: "bot@my-project.iam.gserviceaccount.com"})
+	other := errors.New("connection reset")
+	tests := []struct {
+		name     string
+		err      error
+		want     error
+		contains string
+	}{
+		{"no default credentials", errors.New("google: could not find default credentials. See https://example.com"), ErrNoCredentials, "gcloud auth application-default login"},
+		{"permission denied", status.Error(codes.PermissionDenied, "Permission 'aiplatform.endpoints.predict' denied"), ErrPermissionDenied, `project "my-project"`},
+		{"impersonation", fmt.Errorf("rpc error: %v", errors.New("impersonate: status code 403")), ErrPermissionDenied, "roles/iam.serviceAccountTokenCreator"},
+		{"unauthenticated", status.Error(codes.Unauthenticated, "invalid token"), ErrNoCredentials, "expired"},
+		{"other error", other, other, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := client.authError(tc.err)
+			if !errors.Is(err, tc.want) || !strings.Contains(err.Error(), tc.contains) {
+				t.Errorf("authError() = %v, want %v containing %q", err, tc.want, tc.contains)
+			}
+		})
+	}
+}
+
+func TestMissingCredentialsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "missing.json")
+	client := NewClient("my-project")
+	if err := client.SetAuth(Auth{CredentialsFile: file}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.GenerateText(context.Background(), "", "hello", DefaultParameters)
+	if err == nil || !strings.Contains(err.Error(), file) {
+		t.Errorf("GenerateText() = %v, want an error about %s", err, file)
+	}
+}
@@ -0,0 +1,116 @@
+package text
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CallOptions control how long each call to the model can take and how
+// transient errors are retried. The deadlines also apply when the context
+// of the call has a later deadline or none.
+type CallOptions struct {
+	// Timeout limits the whole call, including the retries. Zero means no
+	// limit besides the context.
+	Timeout time.Duration
+	// AttemptTimeout limits each attempt of the call. An attempt that
+	// times out is retried while the Timeout allows it. Zero means no
+	// limit.
+	AttemptTimeout time.Duration
+	// Retries is the number of times a call is attempted again after a
+	// transient error, like an unavailable service or exhausted quota.
+	Retries int
+	// Backoff is the wait before the first retry, doubled after each one.
+	Backoff time.Duration
+}
+
+// DefaultCallOptions are the call options of a new TextClient.
+var DefaultCallOptions = CallOptions{
+	Timeout:        5 * time.Minute,
+	AttemptTimeout: time.Minute,
+	Retries:        2,
+	Backoff:        time.Second,
+}
+
+// SetCallOptions changes the deadlines and retries of the calls.
+func (t *TextClient) SetCallOptions(opts CallOptions) {
+	t.calls = opts
+}
+
+// final marks an error that must not be retried, like an error from a
+// stream that already sent part of the answer.
+type final struct{ error }
+
+func (f final) Unwrap() error { return f.error }
+
+// call runs fn within the deadlines of the call options, retrying the
+// transient errors. When the context of the call is done, its error is
+// returned; when the deadlines expire, the error wraps
+// context.DeadlineExceeded.
+func (t *TextClient) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	callCtx := ctx
+	if t.calls.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, t.calls.Timeout)
+		defer cancel()
+	}
+	backoff := t.calls.Backoff
+	for attempt := 0; ; attempt++ {
+		err := t.attempt(callCtx, fn)
+		if err == nil {
+			return nil
+		}
+		var f final
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case callCtx.Err() != nil:
+			return fmt.Errorf("text: no response within %v: %w", t.calls.Timeout, context.DeadlineExceeded)
+		case errors.As(err, &f):
+			return f.error
+		case !retryable(err):
+			return err
+		case attempt >= t.calls.Retries:
+			if status.Code(err) == codes.DeadlineExceeded {
+				return fmt.Errorf("text: no response within %v in %d attempts: %w",
+					t.calls.AttemptTimeout, attempt+1, context.DeadlineExceeded)
+			}
+			return err
+		}
+		t.debug("Retrying after error => %v", err)
+		select {
+		case <-time.After(backoff):
+		case <-callCtx.Done():
+		}
+		backoff *= 2
+	}
+}
+
+// attempt runs fn within the attempt timeout.
+func (t *TextClient) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if t.calls.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.calls.AttemptTimeout)
+		defer cancel()
+	}
+	err := fn(ctx)
+	var f final
+	if err != nil && !errors.As(err, &f) && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// The deadline may expire before the server reports it
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return err
+}
+
+// retryable reports if the error is transient.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package text
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCall(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+	denied := status.Error(codes.PermissionDenied, "denied")
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	tests := []struct {
+		name     string
+		opts     CallOptions
+		attempts []func(ctx context.Context) error
+		want     error
+		calls    int
+	}{
+		{
+			name:     "success",
+			opts:     CallOptions{Retries: 2},
+			attempts: []func(ctx context.Context) error{func(context.Context) error { return nil }},
+			calls:    1,
+		},
+		{
+			name: "retry transient error",
+			opts: CallOptions{Retries: 2, Backoff: time.Millisecond},
+			attempts: []func(ctx context.Context) error{
+				func(context.Context) error { return unavailable },
+				func(context.Context) error { return unavailable },
+				func(context.Context) error { return nil },
+			},
+			calls: 3,
+		},
+		{
+			name: "retries exhausted",
+			opts: CallOptions{Retries: 1, Backoff: time.Millisecond},
+			attempts: []func(ctx context.Context) error{
+				func(context.Context) error { return unavailable },
+				func(context.Context) error { return unavailable },
+			},
+			want:  unavailable,
+			calls: 2,
+		},
+		{
+			name:     "permanent error",
+			opts:     CallOptions{Retries: 2, Backoff: time.Millisecond},
+			attempts: []func(ctx context.Context) error{func(context.Context) error { return denied }},
+			want:     denied,
+			calls:    1,
+		},
+		{
+			name:     "final error",
+			opts:     CallOptions{Retries: 2, Backoff: time.Millisecond},
+			attempts: []func(ctx context.Context) error{func(context.Context) error { return final{unavailable} }},
+			want:     unavailable,
+			calls:    1,
+		},
+		{
+			name:     "attempt timeout retried",
+			opts:     CallOptions{AttemptTimeout: 10 * time.Millisecond, Retries: 1, Backoff: time.Millisecond},
+			attempts: []func(ctx context.Context) error{hang, func(context.Context) error { return nil }},
+			calls:    2,
+		},
+		{
+			name:     "attempt timeout exhausted",
+			opts:     CallOptions{AttemptTimeout: 10 * time.Millisecond, Retries: 1, Backoff: time.Millisecond},
+			attempts: []func(ctx context.Context) error{hang, hang},
+			want:     context.DeadlineExceeded,
+			calls:    2,
+		},
+		{
+			name:     "overall timeout",
+			opts:     CallOptions{Timeout: 30 * time.Millisecond, AttemptTimeout: 20 * time.Millisecond, Retries: 5, Backoff: time.Millisecond},
+			attempts: []func(ctx context.Context) error{hang, hang, hang},
+			want:     context.DeadlineExceeded,
+			calls:    2,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient("my-project")
+			client.SetCallOptions(tc.opts)
+			calls := 0
+			err := client.call(context.Background(), func(ctx context.Context) error {
+				calls++
+				return tc.attempts[calls-1](ctx)
+			})
+			if !errors.Is(err, tc.want) || (tc.want == nil && err != nil) {
+				t.Errorf("call() = %v, want %v", err, tc.want)
+			}
+			if calls != tc.calls {
+				t.Errorf("call() made %d attempts, want %d", calls, tc.calls)
+			}
+		})
+	}
+}
+
+func TestCallCanceled(t *testing.T) {
+	client := NewClient("my-project")
+	client.SetCallOptions(CallOptions{Retries: 2, Backoff: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := client.call(ctx, func(ctx context.Context) error {
+		calls++
+		cancel()
+		return status.Error(codes.Canceled, "canceled")
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("call() = %v after %d attempts, want context.Canceled after 1", err, calls)
+	}
+}
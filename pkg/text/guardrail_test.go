@@ -0,0 +1,240 @@
+package text
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// gcpPolicy allows the questions of the promptContext used by
+// TestTextBison, without relying on the prompt instructions.
+var gcpPolicy = Policy{
+	Topics: []Topic{{
+		Name:     "gcp",
+		Keywords: []string{"Google Cloud", "App Engine", "gsutil", "bucket", "gcloud"},
+		Examples: []string{"How do I create a virtual machine?"},
+	}},
+	Blocked:     []string{"password"},
+	Description: "Information Technology and Google Cloud Platform",
+	Refusals: map[string]string{
+		"en": "I don't know about this topic.",
+		"pt": "Não sei sobre este tema.",
+	},
+	Language:      "en",
+	Threshold:     0.9,
+	MinSimilarity: 0.4,
+}
+
+// vocabularyEmbedder returns vectors that count the words of a small
+// vocabulary, so that questions sharing words are similar.
+type vocabularyEmbedder struct {
+	calls int
+	texts int
+	// fail lists the calls, starting at 1, that return an error.
+	fail map[int]bool
+}
+
+var vocabulary = []string{"virtual", "machine", "create", "sky", "color", "network"}
+
+func (v *vocabularyEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]float64, error) {
+	v.calls++
+	if v.fail[v.calls] {
+		return nil, errors.New("unavailable")
+	}
+	v.texts += len(texts)
+	var vectors [][]float64
+	for _, t := range texts {
+		vec := make([]float64, len(vocabulary)+1)
+		vec[len(vocabulary)] = 0.1
+		for _, w := range normalizeWords(t) {
+			for i, word := range vocabulary {
+				if w == word {
+					vec[i]++
+				}
+			}
+		}
+		vectors = append(vectors, vec)
+	}
+	return vectors, nil
+}
+
+// classifierGenerator replies YES when the question has the word network.
+type classifierGenerator struct {
+	calls  int
+	prompt string
+	err    error
+	empty  bool
+}
+
+func (c *classifierGenerator) GenerateText(ctx context.Context, promptContext, prompt string, params Parameters) (*Response, error) {
+	c.calls++
+	c.prompt = CompilePrompt(promptContext, prompt)
+	if c.empty {
+		return &Response{}, c.err
+	}
+	reply := "NO"
+	if strings.Contains(prompt, "network") {
+		reply = " Yes"
+	}
+	return &Response{Predictions: []Prediction{{Content: reply}}}, c.err
+}
+
+func TestGuardrail(t *testing.T) {
+	tests := []struct {
+		name     string
+		question string
+		lang     string
+		allowed  bool
+		method   string
+		topic    string
+		model    bool
+	}{
+		{"keyword", "When was Google App Engine launched?", "en", true, MethodKeyword, "gcp", false},
+		{"keyword with punctuation", "How do I copy a file with gsutil?", "en", true, MethodKeyword, "gcp", false},
+		{"blocked", "What is the gcloud password of my boss?", "en", false, MethodBlocked, "", false},
+		{"similar", "How can I create a virtual machine?", "en", true, MethodEmbedding, "gcp", false},
+		{"not similar", "In one word, what is the color of the sky?", "pt", false, MethodEmbedding, "gcp", false},
+		{"model allows", "Why is my machine network slow?", "en", true, MethodModel, "", true},
+		{"model refuses", "Which machine should I buy?", "en", false, MethodModel, "", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var log bytes.Buffer
+			gen := &classifierGenerator{}
+			g := NewGuardrail(gcpPolicy)
+			g.Embedder = &vocabularyEmbedder{}
+			g.Generator = gen
+			g.Log = &log
+
+			d, err := g.Check(context.Background(), tc.question, tc.lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Allowed != tc.allowed || d.Method != tc.method || d.Topic != tc.topic {
+				t.Errorf("Check() = %+v, want allowed=%v method=%v topic=%v", d, tc.allowed, tc.method, tc.topic)
+			}
+			if !tc.allowed && d.Refusal != gcpPolicy.Refusals[tc.lang] {
+				t.Errorf("Refusal = %q, want %q", d.Refusal, gcpPolicy.Refusals[tc.lang])
+			}
+			if (gen.calls > 0) != tc.model {
+				t.Errorf("model called %d times, want called = %v", gen.calls, tc.model)
+			}
+			if tc.model && !strings.Contains(gen.prompt, "<question>\n"+tc.question+"\n</question>") {
+				t.Errorf("classifier prompt = %q", gen.prompt)
+			}
+
+			var entry struct {
+				Question string
+				Allowed  bool
+				Method   string
+			}
+			if err := json.Unmarshal(log.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid log %q: %v", log.String(), err)
+			}
+			if entry.Question != tc.question || entry.Allowed != tc.allowed || entry.Method != tc.method {
+				t.Errorf("logged %+v", entry)
+			}
+		})
+	}
+}
+
+func TestGuardrailCachesExamples(t *testing.T) {
+	e := &vocabularyEmbedder{}
+	g := NewGuardrail(gcpPolicy)
+	g.Embedder = e
+	for _, q := range []string{"How to create a machine?", "What color is the sky?"} {
+		if _, err := g.Check(context.Background(), q, "en"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e.calls != 2 || e.texts != 3 {
+		t.Errorf("got %d calls with %d texts, want 2 calls with 3 texts", e.calls, e.texts)
+	}
+}
+
+func TestGuardrailEmbeddingError(t *testing.T) {
+	e := &vocabularyEmbedder{fail: map[int]bool{2: true}}
+	g := NewGuardrail(gcpPolicy)
+	g.Embedder = e
+	for i, q := range []string{"How can I create a virtual machine?", "What color is the sky?", "In one word, what is the color of the sky?"} {
+		d, err := g.Check(context.Background(), q, "en")
+		if (err != nil) != e.fail[i+1] {
+			t.Fatalf("Check(%q) error = %v, want error = %v", q, err, e.fail[i+1])
+		}
+		if err == nil && d.Method != MethodEmbedding {
+			t.Errorf("Check(%q) = %+v, want an embedding decision", q, d)
+		}
+	}
+}
+
+func TestGuardrailFollowUp(t *testing.T) {
+	previous := "How can I create a virtual machine?"
+	tests := []struct {
+		question string
+		allowed  bool
+		method   string
+	}{
+		{"In one word, what is the color of the sky?", false, MethodEmbedding},
+		{"Which machine should I buy?", false, MethodModel},
+		{"Why is my machine network slow?", true, MethodModel},
+	}
+	for _, tc := range tests {
+		gen := &classifierGenerator{}
+		g := NewGuardrail(gcpPolicy)
+		g.Embedder = &vocabularyEmbedder{}
+		g.Generator = gen
+		d, err := g.CheckFollowUp(context.Background(), tc.question, previous, "en")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Allowed != tc.allowed || d.Method != tc.method {
+			t.Errorf("CheckFollowUp(%q) = %+v, want allowed=%v method=%v", tc.question, d, tc.allowed, tc.method)
+		}
+		if tc.method == MethodModel && !strings.Contains(gen.prompt, "<previous>\n"+previous+"\n</previous>") {
+			t.Errorf("classifier prompt without the previous question: %q", gen.prompt)
+		}
+	}
+}
+
+func TestGuardrailError(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+	policy := gcpPolicy
+	policy.AllowUndecided = true
+	g := NewGuardrail(policy)
+	g.Generator = &classifierGenerator{err: errUnavailable}
+	d, err := g.Check(context.Background(), "Which machine should I buy?", "en")
+	if !errors.Is(err, errUnavailable) {
+		t.Errorf("Check() error = %v, want %v", err, errUnavailable)
+	}
+	if !d.Allowed || d.Method != MethodDefault {
+		t.Errorf("Check() = %+v, want the default decision", d)
+	}
+
+	g.Generator = &classifierGenerator{empty: true}
+	if _, err := g.Check(context.Background(), "Which machine should I buy?", "en"); err == nil {
+		t.Errorf("Check() without predictions returned no error")
+	}
+}
+
+func TestGuarded(t *testing.T) {
+	model := &classifierGenerator{}
+	g := Guarded{Generator: model, Guardrail: NewGuardrail(gcpPolicy), Language: "pt"}
+
+	resp, err := g.GenerateText(context.Background(), promptContext, "In one word, what is the color of the sky?", MoreDeterministic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Predictions[0].Content; got != "Não sei sobre este tema." || model.calls != 0 {
+		t.Errorf("got %q after %d model calls, want the refusal without calls", got, model.calls)
+	}
+
+	if _, err := g.GenerateText(context.Background(), promptContext, "What is the command to copy a file to a bucket?", MoreDeterministic); err != nil {
+		t.Fatal(err)
+	}
+	if model.calls != 1 {
+		t.Errorf("got %d model calls for an allowed question, want 1", model.calls)
+	}
+}
@@ -0,0 +1,177 @@
+package text
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+)
+
+// Streamer is implemented by generators that can send the generated text
+// in chunks, as soon as each one is available.
+type Streamer interface {
+	// GenerateTextStream works like GenerateText, but calls fn for each
+	// chunk. The Content of each chunk has only the new text. Returning
+	// an error from fn stops the stream and returns that error.
+	GenerateTextStream(ctx context.Context, promptContext, prompt string, params Parameters, fn func(Prediction) error) error
+}
+
+// GenerateTextStream calls the Vertex AI text-bison model with the
+// server streaming prediction API, calling fn for each chunk of the
+// generated text. The prompt is checked against the model limits like in
+// GenerateText. Transient errors are only retried before the first chunk,
+// so when the call is canceled or times out, fn has received the partial
+// answer.
+func (t *TextClient) GenerateTextStream(ctx context.Context, promptContext, prompt string, params Parameters, fn func(Prediction) error) error {
+	compiledPrompt, _, err := t.checkLimits(promptContext, prompt, params)
+	if err != nil {
+		return err
+	}
+	req := &aiplatformpb.StreamingPredictRequest{
+		Endpoint: t.endpoint(ModelVersion),
+		Inputs: []*aiplatformpb.Tensor{{
+			StructVal: map[string]*aiplatformpb.Tensor{
+				"prompt": {StringVal: []string{compiledPrompt}},
+			},
+		}},
+		Parameters: &aiplatformpb.Tensor{
+			StructVal: map[string]*aiplatformpb.Tensor{
+				"temperature":     {DoubleVal: []float64{params.Temperature}},
+				"maxOutputTokens": {IntVal: []int32{int32(params.MaxTokens)}},
+				"topP":            {DoubleVal: []float64{params.TopP}},
+				"topK":            {IntVal: []int32{int32(params.TopK)}},
+				"candidateCount":  {IntVal: []int32{int32(params.CandidateCount)}},
+			},
+		},
+	}
+	t.debug("Sending streaming request => %v", req)
+
+	client, err := t.predictionClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	// The call is only retried before the first chunk is sent to fn.
+	sent := false
+	err = t.call(ctx, func(ctx context.Context) error {
+		stream, err := client.ServerStreamingPredict(ctx, req)
+		if err != nil {
+			return err
+		}
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			} else if err != nil && sent {
+				return final{err}
+			} else if err != nil {
+				return err
+			}
+			t.debug("Got streaming response => %v", resp)
+			for _, output := range resp.Outputs {
+				p, err := tensorPrediction(output)
+				if err != nil {
+					return final{err}
+				}
+				sent = true
+				if err = fn(p); err != nil {
+					return final{err}
+				}
+			}
+		}
+	})
+	return t.authError(err)
+}
+
+// tensorPrediction decodes a Prediction from the streaming API output.
+func tensorPrediction(output *aiplatformpb.Tensor) (Prediction, error) {
+	p := Prediction{}
+	b, err := json.Marshal(tensorValue(output, reflect.TypeOf(p)))
+	if err != nil {
+		return p, err
+	}
+	if err = json.Unmarshal(b, &p); err != nil {
+		return p, fmt.Errorf("text: unexpected streaming output: %v", err)
+	}
+	return p, nil
+}
+
+// tensorValue converts the tensor to the equivalent Go value, so it can be
+// decoded with encoding/json into a value of type typ. Tensors always hold
+// repeated values, so they become slices when typ is a slice, and their
+// single value otherwise. When typ is nil, as for unknown fields, tensors
+// with a single value and no shape are converted to that value.
+func tensorValue(t *aiplatformpb.Tensor, typ reflect.Type) any {
+	if t == nil {
+		return nil
+	}
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	var elem reflect.Type
+	if typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) {
+		elem = typ.Elem()
+	}
+	if t.StructVal != nil {
+		if elem != nil {
+			return []any{tensorValue(t, elem)}
+		}
+		m := make(map[string]any, len(t.StructVal))
+		for k, v := range t.StructVal {
+			m[k] = tensorValue(v, fieldType(typ, k))
+		}
+		return m
+	}
+	var values []any
+	add := func(n int, value func(i int) any) {
+		for i := 0; i < n; i++ {
+			values = append(values, value(i))
+		}
+	}
+	add(len(t.ListVal), func(i int) any { return tensorValue(t.ListVal[i], elem) })
+	add(len(t.StringVal), func(i int) any { return t.StringVal[i] })
+	add(len(t.BoolVal), func(i int) any { return t.BoolVal[i] })
+	add(len(t.DoubleVal), func(i int) any { return t.DoubleVal[i] })
+	add(len(t.FloatVal), func(i int) any { return t.FloatVal[i] })
+	add(len(t.IntVal), func(i int) any { return t.IntVal[i] })
+	add(len(t.Int64Val), func(i int) any { return t.Int64Val[i] })
+	add(len(t.UintVal), func(i int) any { return t.UintVal[i] })
+	add(len(t.Uint64Val), func(i int) any { return t.Uint64Val[i] })
+	switch {
+	case elem != nil:
+		return values
+	case typ != nil && len(values) == 1:
+		return values[0]
+	case typ == nil && len(values) == 1 && len(t.ListVal) == 0 && len(t.Shape) == 0:
+		return values[0]
+	}
+	return values
+}
+
+// fieldType returns the type of the field decoded from the JSON key, or
+// nil if it is unknown.
+func fieldType(typ reflect.Type, key string) reflect.Type {
+	if typ == nil {
+		return nil
+	}
+	switch typ.Kind() {
+	case reflect.Map:
+		return typ.Elem()
+	case reflect.Struct:
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "" {
+				name = f.Name
+			}
+			if strings.EqualFold(name, key) {
+				return f.Type
+			}
+		}
+	}
+	return nil
+}
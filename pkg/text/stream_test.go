@@ -0,0 +1,64 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+)
+
+func TestTensorPrediction(t *testing.T) {
+	output := &aiplatformpb.Tensor{
+		StructVal: map[string]*aiplatformpb.Tensor{
+			"content": {StringVal: []string{"Hello"}},
+			"safetyAttributes": {StructVal: map[string]*aiplatformpb.Tensor{
+				"blocked":    {BoolVal: []bool{false}},
+				"categories": {Shape: []int64{1}, StringVal: []string{"Finance"}},
+				"scores":     {Shape: []int64{1}, DoubleVal: []float64{0.1}},
+			}},
+		},
+	}
+	got, err := tensorPrediction(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Prediction{Content: "Hello"}
+	want.SafetyAttributes.Categories = []string{"Finance"}
+	want.SafetyAttributes.Scores = []float64{0.1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestTensorPredictionWithoutShape(t *testing.T) {
+	citation := map[string]*aiplatformpb.Tensor{
+		"url":        {StringVal: []string{"https://example.com"}},
+		"startIndex": {IntVal: []int32{2}},
+	}
+	for name, citations := range map[string]*aiplatformpb.Tensor{
+		"list":   {ListVal: []*aiplatformpb.Tensor{{StructVal: citation}}},
+		"struct": {StructVal: citation},
+	} {
+		output := &aiplatformpb.Tensor{
+			StructVal: map[string]*aiplatformpb.Tensor{
+				"content":          {StringVal: []string{"Hello"}},
+				"citationMetadata": {StructVal: map[string]*aiplatformpb.Tensor{"citations": citations}},
+				"safetyAttributes": {StructVal: map[string]*aiplatformpb.Tensor{
+					"blocked":    {BoolVal: []bool{true}},
+					"categories": {StringVal: []string{"Finance"}},
+					"scores":     {DoubleVal: []float64{0.1}},
+				}},
+			},
+		}
+		got, err := tensorPrediction(output)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := Prediction{Content: "Hello"}
+		want.CitationMetadata.Citations = []Citation{{URL: "https://example.com", StartIndex: 2}}
+		want.SafetyAttributes = SafetyAttributes{Blocked: true, Categories: []string{"Finance"}, Scores: []float64{0.1}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %#v, want %#v", name, got, want)
+		}
+	}
+}
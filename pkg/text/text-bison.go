@@ -3,8 +3,10 @@ package text
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 
 	aiplatform "cloud.google.com/go/aiplatform/apiv1"
@@ -65,6 +67,24 @@ var MoreCreative = Parameters{
 	CandidateCount: 1,
 }
 
+// Presets maps the name of each suggested parameter set to its values, so
+// they can be selected by name from command line flags or configuration.
+var Presets = map[string]Parameters{
+	"default":       DefaultParameters,
+	"deterministic": MoreDeterministic,
+	"creative":      MoreCreative,
+}
+
+// PresetNames returns the sorted list of names available in Presets.
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Citation describes a citation reference when the model detects that
 // one is needed.
 type Citation struct {
@@ -118,6 +138,9 @@ type TokenMetadata struct {
 type Response struct {
 	Predictions []Prediction  `json:"predictions,omitempty"`
 	Metadata    TokenMetadata `json:"tokenMetadata,omitempty"`
+	// Truncation is set when the prompt was truncated to fit the model
+	// input limit.
+	Truncation *Truncation `json:"truncation,omitempty"`
 }
 
 func (r Response) String() string {
@@ -128,16 +151,35 @@ func (r Response) String() string {
 	return string(b)
 }
 
+// Generator is implemented by types that can generate text from a prompt,
+// like the TextClient. It allows other packages to compose text generation
+// and to replace the model with a fake one during tests.
+type Generator interface {
+	GenerateText(ctx context.Context, promptContext, prompt string, params Parameters) (*Response, error)
+}
+
 // TextClient is a helper to setup a Generative AI client for
 // text generation.
 type TextClient struct {
 	projectID string
 	debugFlag bool
+	strategy  Strategy
+	auth      Auth
+	calls     CallOptions
 }
 
 // NewClient initializes a new TextClient using the provided projectID.
+// Prompts larger than the model input limit are rejected by default;
+// use SetStrategy to change this behavior. The calls use the
+// DefaultCallOptions; use SetCallOptions to change them.
 func NewClient(projectID string) *TextClient {
-	return &TextClient{projectID: projectID}
+	return &TextClient{projectID: projectID, strategy: Reject, calls: DefaultCallOptions}
+}
+
+// SetStrategy changes how GenerateText handles prompts that are estimated
+// to be larger than the model input limit.
+func (t *TextClient) SetStrategy(strategy Strategy) {
+	t.strategy = strategy
 }
 
 // GenerateText calls the Vertex AI text-bison model to generate a new text.
@@ -150,14 +192,21 @@ func NewClient(projectID string) *TextClient {
 // top-k or top-p. Note that the default struct values for them are not always what you
 // want: it is a good idea to set all parameters explicitly.
 //
+// Before calling the model, the size of the prompt is estimated with EstimateTokens
+// and checked against the ModelLimits. Prompts that are too large are rejected or
+// truncated, according to the client Strategy. Only `prompt` is truncated, so the
+// instructions in `promptContext` are always preserved.
+//
+// The call is limited by the deadlines of the client CallOptions, and transient
+// errors are retried.
+//
 // The returned Response will contain the list of predictions as well as any metadata
-// returned by the call.
+// returned by the call. ErrNoPredictions is returned when the model sends none, so
+// callers can always use the first prediction.
 func (t *TextClient) GenerateText(ctx context.Context, promptContext, prompt string, params Parameters) (response *Response, err error) {
-	// Compile the promptContext with the prompt, allowing for empty context and
-	// no formatting strings to be properly used.
-	compiledPrompt := fmt.Sprintf(promptContext, prompt)
-	if !strings.Contains(promptContext, "%s") {
-		compiledPrompt = promptContext + " " + prompt
+	compiledPrompt, truncation, err := t.checkLimits(promptContext, prompt, params)
+	if err != nil {
+		return nil, err
 	}
 	// Preparing the request data, using the structpb.Value as a
 	// conteiner for the input. This will use the gRPC APIs.
@@ -177,30 +226,13 @@ func (t *TextClient) GenerateText(ctx context.Context, promptContext, prompt str
 	if err != nil {
 		return nil, err
 	}
-	// Creating the protobuff request to send call the model prediction.
-	endpoint := fmt.Sprintf("projects/%s/locations/%s/publishers/%s/models/%s", t.projectID, "us-central1", "google", ModelVersion)
-	req := &aiplatformpb.PredictRequest{
-		Endpoint:   endpoint,
-		Instances:  []*structpb.Value{instance},
-		Parameters: parameters,
-	}
-	t.debug("Sending request => %v", req)
-
-	// Connecting to the desired server
-	client, err := aiplatform.NewPredictionClient(ctx, option.WithEndpoint("us-central1-aiplatform.googleapis.com:443"))
+	resp, err := t.predict(ctx, ModelVersion, []*structpb.Value{instance}, parameters)
 	if err != nil {
 		return nil, err
 	}
 
-	// Actually makes the call
-	resp, err := client.Predict(ctx, req)
-	if err != nil {
-		return nil, err
-	}
-	t.debug("Got Response => %v", resp)
-
 	// Decoding the response with the help of encoding/json
-	r := &Response{}
+	r := &Response{Truncation: truncation}
 	b, err := resp.Metadata.MarshalJSON()
 	if err != nil {
 		return nil, err
@@ -223,9 +255,100 @@ func (t *TextClient) GenerateText(ctx context.Context, promptContext, prompt str
 		}
 		r.Predictions = append(r.Predictions, p)
 	}
+	if len(r.Predictions) == 0 {
+		return nil, ErrNoPredictions
+	}
 	return r, nil
 }
 
+// ErrNoPredictions is returned when the model response has no predictions.
+var ErrNoPredictions = errors.New("text: no predictions returned")
+
+// CompilePrompt compiles the promptContext with the prompt, allowing for empty
+// context and no formatting strings to be properly used.
+func CompilePrompt(promptContext, prompt string) string {
+	if !strings.Contains(promptContext, "%s") {
+		return promptContext + " " + prompt
+	}
+	return fmt.Sprintf(promptContext, prompt)
+}
+
+// checkLimits compiles the prompt and verifies that it fits in the model
+// limits, truncating it if required by the client strategy.
+func (t *TextClient) checkLimits(promptContext, prompt string, params Parameters) (string, *Truncation, error) {
+	compiled := CompilePrompt(promptContext, prompt)
+	limits, ok := ModelLimits[ModelVersion]
+	if !ok || t.strategy == NoCheck {
+		return compiled, nil, nil
+	}
+	if params.MaxTokens > limits.OutputTokens {
+		return "", nil, fmt.Errorf("text: %d max output tokens exceeds the %s limit of %d",
+			params.MaxTokens, ModelVersion, limits.OutputTokens)
+	}
+	estimated := EstimateTokens(compiled)
+	if estimated <= limits.InputTokens {
+		return compiled, nil, nil
+	}
+	available := limits.InputTokens - EstimateTokens(CompilePrompt(promptContext, ""))
+	if t.strategy == Reject || available <= 0 {
+		return "", nil, fmt.Errorf("%w: estimated %d tokens, %s accepts %d",
+			ErrPromptTooLong, estimated, ModelVersion, limits.InputTokens)
+	}
+	truncated, truncation := Truncate(prompt, available, t.strategy)
+	compiled = CompilePrompt(promptContext, truncated)
+	truncation.EstimatedTokens = estimated
+	truncation.MaxTokens = limits.InputTokens
+	t.debug("Truncated prompt => %v", truncation)
+	return compiled, truncation, nil
+}
+
+// predict calls the prediction API of the Google published model with the
+// given instances and parameters.
+func (t *TextClient) predict(ctx context.Context, model string, instances []*structpb.Value, parameters *structpb.Value) (*aiplatformpb.PredictResponse, error) {
+	// Creating the protobuff request to send call the model prediction.
+	req := &aiplatformpb.PredictRequest{
+		Endpoint:   t.endpoint(model),
+		Instances:  instances,
+		Parameters: parameters,
+	}
+	t.debug("Sending request => %v", req)
+
+	// Connecting to the desired server
+	client, err := t.predictionClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	// Actually makes the call, retrying transient errors
+	var resp *aiplatformpb.PredictResponse
+	err = t.call(ctx, func(ctx context.Context) (err error) {
+		resp, err = client.Predict(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, t.authError(err)
+	}
+	t.debug("Got Response => %v", resp)
+	return resp, nil
+}
+
+// endpoint returns the resource name of the Google published model.
+func (t *TextClient) endpoint(model string) string {
+	return fmt.Sprintf("projects/%s/locations/%s/publishers/%s/models/%s", t.projectID, "us-central1", "google", model)
+}
+
+// predictionClient connects to the prediction API in the model region,
+// using the credentials configured with SetAuth.
+func (t *TextClient) predictionClient(ctx context.Context) (*aiplatform.PredictionClient, error) {
+	opts := append([]option.ClientOption{option.WithEndpoint("us-central1-aiplatform.googleapis.com:443")}, t.auth.ClientOptions()...)
+	client, err := aiplatform.NewPredictionClient(ctx, opts...)
+	if err != nil {
+		return nil, t.authError(err)
+	}
+	return client, nil
+}
+
 // EnableDebug activates extra messages printed to stderr for debugging.
 func (t *TextClient) Debug(enable bool) {
 	t.debugFlag = enable
@@ -0,0 +1,90 @@
+package text
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// EmbeddingModelVersion is the currently used model version for text
+// embeddings used by the prediction API calls.
+const EmbeddingModelVersion = "textembedding-gecko@001"
+
+// maxEmbeddingInstances is the maximum number of texts the embedding model
+// accepts in a single request.
+const maxEmbeddingInstances = 5
+
+// Embedder is implemented by types that can compute vector embeddings
+// for texts, like the TextClient.
+type Embedder interface {
+	EmbedTexts(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// embeddingPrediction is the format of each prediction returned by the
+// embedding model.
+type embeddingPrediction struct {
+	Embeddings struct {
+		Values []float64 `json:"values"`
+	} `json:"embeddings"`
+}
+
+// EmbedTexts calls the Vertex AI textembedding-gecko model to compute the
+// embeddings of each text. The returned slice has one vector for each
+// input text, in the same order.
+func (t *TextClient) EmbedTexts(ctx context.Context, texts []string) ([][]float64, error) {
+	var embeddings [][]float64
+	for start := 0; start < len(texts); start += maxEmbeddingInstances {
+		end := min(start+maxEmbeddingInstances, len(texts))
+		var instances []*structpb.Value
+		for _, text := range texts[start:end] {
+			instance, err := structpb.NewValue(map[string]interface{}{
+				"content": text,
+			})
+			if err != nil {
+				return nil, err
+			}
+			instances = append(instances, instance)
+		}
+		resp, err := t.predict(ctx, EmbeddingModelVersion, instances, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Predictions) != len(instances) {
+			return nil, fmt.Errorf("text: got %d embeddings for %d texts", len(resp.Predictions), len(instances))
+		}
+		for i := range resp.Predictions {
+			b, err := json.Marshal(resp.Predictions[i].GetStructValue().AsMap())
+			if err != nil {
+				return nil, err
+			}
+			p := embeddingPrediction{}
+			if err = json.Unmarshal(b, &p); err != nil {
+				return nil, err
+			}
+			embeddings = append(embeddings, p.Embeddings.Values)
+		}
+	}
+	return embeddings, nil
+}
+
+// CosineSimilarity returns the cosine of the angle between the vectors a
+// and b, ranging from -1 to 1. It returns 0 if any vector is empty or if
+// they have different dimensions.
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,82 @@
+// Package texttest provides a fake text.Generator for the tests of the
+// packages that call the models.
+package texttest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// Call is a request received by the Generator.
+type Call struct {
+	PromptContext string
+	Prompt        string
+	Params        text.Parameters
+}
+
+// Compiled returns the prompt compiled with the prompt context, as sent
+// to the model.
+func (c Call) Compiled() string {
+	return text.CompilePrompt(c.PromptContext, c.Prompt)
+}
+
+// Generator is a fake text.Generator that records the calls. It replies
+// with Respond, when set, or with each of the Replies in order, repeating
+// the last one. Without replies, the response has no predictions. It is
+// safe for concurrent use.
+type Generator struct {
+	// Respond returns the response to the call.
+	Respond func(Call) (*text.Response, error)
+	// Replies are the contents of the predictions of each call.
+	Replies []string
+	// Metadata is returned with the Replies.
+	Metadata text.TokenMetadata
+	// Err, if set, is returned by all calls.
+	Err error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// GenerateText implements text.Generator.
+func (g *Generator) GenerateText(ctx context.Context, promptContext, prompt string, params text.Parameters) (*text.Response, error) {
+	c := Call{PromptContext: promptContext, Prompt: prompt, Params: params}
+	g.mu.Lock()
+	g.calls = append(g.calls, c)
+	n := len(g.calls)
+	g.mu.Unlock()
+	switch {
+	case g.Err != nil:
+		return nil, g.Err
+	case g.Respond != nil:
+		return g.Respond(c)
+	case len(g.Replies) == 0:
+		return &text.Response{}, nil
+	}
+	reply := g.Replies[min(n, len(g.Replies))-1]
+	return &text.Response{Predictions: []text.Prediction{{Content: reply}}, Metadata: g.Metadata}, nil
+}
+
+// Calls returns the calls received so far.
+func (g *Generator) Calls() []Call {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Call(nil), g.calls...)
+}
+
+// Last returns the last call received, or the zero Call if there is none.
+func (g *Generator) Last() Call {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.calls) == 0 {
+		return Call{}
+	}
+	return g.calls[len(g.calls)-1]
+}
+
+// Reply returns a response with a single prediction with the content.
+func Reply(content string) *text.Response {
+	return &text.Response{Predictions: []text.Prediction{{Content: content}}}
+}
@@ -0,0 +1,174 @@
+package text
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode"
+)
+
+// Limits are the maximum number of input and output tokens of a model.
+type Limits struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+}
+
+// ModelLimits is the table of known input and output token limits for the
+// models available on Vertex AI, according to the public documentation.
+var ModelLimits = map[string]Limits{
+	"text-bison@001":          {InputTokens: 8192, OutputTokens: 1024},
+	"text-bison@002":          {InputTokens: 8192, OutputTokens: 2048},
+	"text-bison-32k@002":      {InputTokens: 32768 - 8192, OutputTokens: 8192},
+	"chat-bison@001":          {InputTokens: 4096, OutputTokens: 1024},
+	"chat-bison@002":          {InputTokens: 8192, OutputTokens: 2048},
+	"code-bison@001":          {InputTokens: 6144, OutputTokens: 1024},
+	"textembedding-gecko@001": {InputTokens: 3072},
+}
+
+// Strategy defines what to do when a prompt is larger than the model
+// input limit.
+type Strategy string
+
+// Available strategies to handle prompts larger than the model limit.
+const (
+	// NoCheck disables the pre-flight check, sending the prompt as is.
+	NoCheck Strategy = "none"
+	// Reject returns an error wrapping ErrPromptTooLong.
+	Reject Strategy = "reject"
+	// KeepHead keeps the beginning of the prompt, dropping the end.
+	KeepHead Strategy = "head"
+	// KeepTail keeps the end of the prompt, dropping the beginning.
+	KeepTail Strategy = "tail"
+	// MiddleOut keeps both the beginning and the end of the prompt,
+	// dropping the middle.
+	MiddleOut Strategy = "middle-out"
+)
+
+// Strategies is the list of valid strategies.
+var Strategies = []Strategy{NoCheck, Reject, KeepHead, KeepTail, MiddleOut}
+
+// ParseStrategy returns the Strategy with the given name.
+func ParseStrategy(name string) (Strategy, error) {
+	for _, s := range Strategies {
+		if string(s) == name {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("text: invalid strategy %q", name)
+}
+
+// ErrPromptTooLong is returned when the prompt is larger than the model
+// input limit and the Reject strategy is used.
+var ErrPromptTooLong = errors.New("text: prompt too long")
+
+// truncationMarker is inserted in place of the text removed by MiddleOut.
+const truncationMarker = "\n[...]\n"
+
+// Truncation reports the part of the prompt dropped by the pre-flight
+// check to fit in the model limits.
+type Truncation struct {
+	Strategy Strategy `json:"strategy"`
+	// EstimatedTokens is the estimated size of the original prompt,
+	// including the prompt context.
+	EstimatedTokens int `json:"estimatedTokens"`
+	// MaxTokens is the model input limit.
+	MaxTokens int `json:"maxTokens"`
+	// DroppedStart and DroppedEnd are the byte offsets of the dropped text
+	// in the original prompt.
+	DroppedStart  int `json:"droppedStart"`
+	DroppedEnd    int `json:"droppedEnd"`
+	DroppedChars  int `json:"droppedChars"`
+	DroppedTokens int `json:"droppedTokens"`
+}
+
+func (t Truncation) String() string {
+	return fmt.Sprintf("dropped %d characters (~%d tokens) at [%d:%d] using strategy %s to fit in %d tokens",
+		t.DroppedChars, t.DroppedTokens, t.DroppedStart, t.DroppedEnd, t.Strategy, t.MaxTokens)
+}
+
+// EstimateTokens returns an offline estimate of the number of tokens in s.
+// It counts roughly one token for every four letters or digits in a word,
+// and one token for each punctuation or symbol, so it tends to slightly
+// overestimate the count reported by the API.
+func EstimateTokens(s string) int {
+	tokens, word := 0, 0
+	flush := func() {
+		tokens += (word + 3) / 4
+		word = 0
+	}
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if r > unicode.MaxASCII {
+				// Accented and non-latin characters are usually split
+				// into more tokens.
+				word += 2
+			} else {
+				word++
+			}
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// BillableCharacters returns the number of characters billed for s, which
+// are all characters except whitespace.
+func BillableCharacters(s string) int {
+	n := 0
+	for _, r := range s {
+		if !unicode.IsSpace(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// Truncate reduces s to at most maxTokens estimated tokens using the
+// given strategy. It returns the truncated text and the report of what
+// was dropped, or nil if s already fits.
+func Truncate(s string, maxTokens int, strategy Strategy) (string, *Truncation) {
+	estimated := EstimateTokens(s)
+	if estimated <= maxTokens {
+		return s, nil
+	}
+	runes := []rune(s)
+	keep := func(n int) (string, int, int) {
+		switch strategy {
+		case KeepTail:
+			start := len(string(runes[:len(runes)-n]))
+			return s[start:], 0, start
+		case MiddleOut:
+			head, tail := n/2, n-n/2
+			start, end := len(string(runes[:head])), len(s)-len(string(runes[len(runes)-tail:]))
+			return s[:start] + truncationMarker + s[end:], start, end
+		default:
+			end := len(string(runes[:n]))
+			return s[:end], end, len(s)
+		}
+	}
+	// Find the largest number of runes that fits in maxTokens.
+	n := sort.Search(len(runes)+1, func(n int) bool {
+		kept, _, _ := keep(n)
+		return EstimateTokens(kept) > maxTokens
+	}) - 1
+	if n < 0 {
+		n = 0
+	}
+	kept, start, end := keep(n)
+	dropped := s[start:end]
+	return kept, &Truncation{
+		Strategy:        strategy,
+		EstimatedTokens: estimated,
+		MaxTokens:       maxTokens,
+		DroppedStart:    start,
+		DroppedEnd:      end,
+		DroppedChars:    len([]rune(dropped)),
+		DroppedTokens:   EstimateTokens(dropped),
+	}
+}
@@ -0,0 +1,127 @@
+package text
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBillableCharacters(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"May 2008", 7},
+		{"I don't know about this topic.", 25},
+		{"gsutil cp file.txt gs://bucket/", 28},
+		{"Não sei\n\tsobre este tema", 19},
+	}
+	for _, tc := range tests {
+		if got := BillableCharacters(tc.text); got != tc.want {
+			t.Errorf("BillableCharacters(%q) = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"May 2008", 2},
+		{"gsutil cp file.txt gs://bucket/", 13},
+		{"don't", 3},
+	}
+	for _, tc := range tests {
+		if got := EstimateTokens(tc.text); got != tc.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	input := "first second third fourth fifth sixth seventh eighth ninth tenth"
+	tests := []struct {
+		strategy   Strategy
+		wantPrefix string
+		wantSuffix string
+	}{
+		{KeepHead, "first second", ""},
+		{KeepTail, "", "ninth tenth"},
+		{MiddleOut, "first", "tenth"},
+	}
+	for _, tc := range tests {
+		t.Run(string(tc.strategy), func(t *testing.T) {
+			got, tr := Truncate(input, 12, tc.strategy)
+			if tr == nil {
+				t.Fatalf("expected truncation report")
+			}
+			if EstimateTokens(got) > 12 {
+				t.Errorf("got %d tokens in %q, want at most 12", EstimateTokens(got), got)
+			}
+			if !strings.HasPrefix(got, tc.wantPrefix) || !strings.HasSuffix(got, tc.wantSuffix) {
+				t.Errorf("got %q, want prefix %q and suffix %q", got, tc.wantPrefix, tc.wantSuffix)
+			}
+			dropped := input[tr.DroppedStart:tr.DroppedEnd]
+			if tr.DroppedChars != len(dropped) || tr.DroppedChars == 0 {
+				t.Errorf("got %d dropped chars, want %d", tr.DroppedChars, len(dropped))
+			}
+			if strings.Contains(got, strings.TrimSpace(dropped)) {
+				t.Errorf("dropped text %q still in %q", dropped, got)
+			}
+		})
+	}
+
+	if got, tr := Truncate("short", 10, KeepHead); got != "short" || tr != nil {
+		t.Errorf("Truncate(short) = %q, %v; want unchanged", got, tr)
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	limit := ModelLimits[ModelVersion].InputTokens
+	large := strings.Repeat("word ", limit+100)
+	tests := []struct {
+		name           string
+		strategy       Strategy
+		prompt         string
+		params         Parameters
+		wantErr        error
+		wantTruncation bool
+	}{
+		{"fits", Reject, "small prompt", DefaultParameters, nil, false},
+		{"reject", Reject, large, DefaultParameters, ErrPromptTooLong, false},
+		{"head", KeepHead, large, DefaultParameters, nil, true},
+		{"no check", NoCheck, large, DefaultParameters, nil, false},
+		{"max tokens", Reject, "small", Parameters{MaxTokens: 1 << 20}, errAny, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewClient("test")
+			c.SetStrategy(tc.strategy)
+			compiled, tr, err := c.checkLimits(promptContext, tc.prompt, tc.params)
+			switch {
+			case tc.wantErr == errAny && err == nil:
+				t.Fatalf("expected error")
+			case tc.wantErr != nil && tc.wantErr != errAny && !errors.Is(err, tc.wantErr):
+				t.Fatalf("got error %v, want %v", err, tc.wantErr)
+			case tc.wantErr == nil && err != nil:
+				t.Fatalf("unexpected error %v", err)
+			}
+			if (tr != nil) != tc.wantTruncation {
+				t.Errorf("got truncation %v, want %v", tr, tc.wantTruncation)
+			}
+			if tr != nil {
+				if EstimateTokens(compiled) > limit {
+					t.Errorf("compiled prompt has %d tokens, limit is %d", EstimateTokens(compiled), limit)
+				}
+				if !strings.HasPrefix(compiled, "Context:") {
+					t.Errorf("prompt context was not preserved")
+				}
+			}
+		})
+	}
+}
+
+var errAny = errors.New("any error")
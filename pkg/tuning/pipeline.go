@@ -0,0 +1,137 @@
+package tuning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	aiplatform "cloud.google.com/go/aiplatform/apiv1"
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"google.golang.org/api/option"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// PipelineTemplate is the Vertex AI pipeline that tunes the text models.
+const PipelineTemplate = "https://us-kfp.pkg.dev/ml-pipeline/large-language-model-pipelines/tune-large-model/v2.0.0"
+
+// DefaultLocation is the region where the tuning pipeline runs by
+// default, which has the accelerators used by it.
+const DefaultLocation = "europe-west4"
+
+// Job describes a supervised tuning pipeline run.
+type Job struct {
+	Project string
+	// Location is the region of the pipeline, DefaultLocation if empty.
+	Location string
+	// Model is the model to be tuned, text.ModelVersion if empty.
+	Model string
+	// DisplayName is the name of the tuned model.
+	DisplayName string
+	// TrainingData and EvaluationData are the gs:// URIs of the datasets.
+	// The evaluation dataset is optional.
+	TrainingData   string
+	EvaluationData string
+	// PipelineRoot is the gs:// directory where the pipeline artifacts are
+	// saved.
+	PipelineRoot string
+	// TrainSteps is the number of tuning steps; the pipeline default is
+	// used if zero.
+	TrainSteps int
+	// LearningRateMultiplier scales the recommended learning rate; the
+	// pipeline default is used if zero.
+	LearningRateMultiplier float64
+	// ServiceAccount runs the pipeline instead of the Compute Engine
+	// default service account, if set.
+	ServiceAccount string
+}
+
+// Request returns the request that creates the pipeline job.
+func (j Job) Request() (*aiplatformpb.CreatePipelineJobRequest, error) {
+	location := j.Location
+	if location == "" {
+		location = DefaultLocation
+	}
+	model := j.Model
+	if model == "" {
+		model = text.ModelVersion
+	}
+	switch {
+	case j.Project == "":
+		return nil, errors.New("tuning: missing project")
+	case j.DisplayName == "":
+		return nil, errors.New("tuning: missing the name of the tuned model")
+	case j.TrainingData == "":
+		return nil, errors.New("tuning: missing the training dataset")
+	case j.PipelineRoot == "":
+		return nil, errors.New("tuning: missing the pipeline root directory")
+	}
+	for _, uri := range []string{j.TrainingData, j.EvaluationData, j.PipelineRoot} {
+		if uri != "" && !strings.HasPrefix(uri, "gs://") {
+			return nil, fmt.Errorf("tuning: %q is not a Cloud Storage URI (gs://bucket/path)", uri)
+		}
+	}
+
+	params := map[string]interface{}{
+		"project":               j.Project,
+		"location":              location,
+		"large_model_reference": model,
+		"model_display_name":    j.DisplayName,
+		"dataset_uri":           j.TrainingData,
+	}
+	if j.EvaluationData != "" {
+		params["evaluation_data_uri"] = j.EvaluationData
+	}
+	if j.TrainSteps > 0 {
+		params["train_steps"] = j.TrainSteps
+	}
+	if j.LearningRateMultiplier > 0 {
+		params["learning_rate_multiplier"] = j.LearningRateMultiplier
+	}
+	values := make(map[string]*structpb.Value, len(params))
+	for k, v := range params {
+		value, err := structpb.NewValue(v)
+		if err != nil {
+			return nil, err
+		}
+		values[k] = value
+	}
+	return &aiplatformpb.CreatePipelineJobRequest{
+		Parent: fmt.Sprintf("projects/%s/locations/%s", j.Project, location),
+		PipelineJob: &aiplatformpb.PipelineJob{
+			DisplayName:    "tune-" + j.DisplayName,
+			TemplateUri:    PipelineTemplate,
+			ServiceAccount: j.ServiceAccount,
+			RuntimeConfig: &aiplatformpb.PipelineJob_RuntimeConfig{
+				GcsOutputDirectory: j.PipelineRoot,
+				ParameterValues:    values,
+			},
+		},
+	}, nil
+}
+
+// NewPipelineClient connects to the pipeline API in the location, or in
+// DefaultLocation if empty.
+func NewPipelineClient(ctx context.Context, location string, opts ...option.ClientOption) (*aiplatform.PipelineClient, error) {
+	if location == "" {
+		location = DefaultLocation
+	}
+	opts = append([]option.ClientOption{option.WithEndpoint(location + "-aiplatform.googleapis.com:443")}, opts...)
+	return aiplatform.NewPipelineClient(ctx, opts...)
+}
+
+// Submit creates the pipeline job, returning it as created. The pipeline
+// runs in background; its progress can be followed in the Cloud Console.
+func Submit(ctx context.Context, client *aiplatform.PipelineClient, j Job) (*aiplatformpb.PipelineJob, error) {
+	req, err := j.Request()
+	if err != nil {
+		return nil, err
+	}
+	job, err := client.CreatePipelineJob(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("tuning: creating the pipeline job: %w", err)
+	}
+	return job, nil
+}
@@ -0,0 +1,121 @@
+package tuning
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	aiplatform "cloud.google.com/go/aiplatform/apiv1"
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// fakePipelineServer records the pipeline jobs created.
+type fakePipelineServer struct {
+	aiplatformpb.UnimplementedPipelineServiceServer
+	requests []*aiplatformpb.CreatePipelineJobRequest
+	err      error
+}
+
+func (f *fakePipelineServer) CreatePipelineJob(ctx context.Context, req *aiplatformpb.CreatePipelineJobRequest) (*aiplatformpb.PipelineJob, error) {
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return nil, f.err
+	}
+	job := req.PipelineJob
+	job.Name = req.Parent + "/pipelineJobs/123"
+	job.State = aiplatformpb.PipelineState_PIPELINE_STATE_PENDING
+	return job, nil
+}
+
+// fakePipelineClient returns a client connected to the fake server.
+func fakePipelineClient(t *testing.T, fake *fakePipelineServer) *aiplatform.PipelineClient {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	aiplatformpb.RegisterPipelineServiceServer(s, fake)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	client, err := aiplatform.NewPipelineClient(context.Background(),
+		option.WithEndpoint(l.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+var job = Job{
+	Project:                "my-project",
+	DisplayName:            "linux-guru",
+	TrainingData:           "gs://my-bucket/train.jsonl",
+	EvaluationData:         "gs://my-bucket/eval.jsonl",
+	PipelineRoot:           "gs://my-bucket/pipeline",
+	TrainSteps:             300,
+	LearningRateMultiplier: 0.5,
+}
+
+func TestSubmit(t *testing.T) {
+	fake := &fakePipelineServer{}
+	client := fakePipelineClient(t, fake)
+
+	created, err := Submit(context.Background(), client, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "projects/my-project/locations/europe-west4/pipelineJobs/123"; created.Name != want {
+		t.Errorf("job name = %q, want %q", created.Name, want)
+	}
+	if len(fake.requests) != 1 {
+		t.Fatalf("server got %d requests, want 1", len(fake.requests))
+	}
+	got := fake.requests[0].PipelineJob
+	if got.TemplateUri != PipelineTemplate || got.RuntimeConfig.GcsOutputDirectory != job.PipelineRoot {
+		t.Errorf("pipeline job = %v", got)
+	}
+	params := got.RuntimeConfig.ParameterValues
+	want := map[string]interface{}{
+		"project":                  "my-project",
+		"location":                 "europe-west4",
+		"large_model_reference":    "text-bison@001",
+		"model_display_name":       "linux-guru",
+		"dataset_uri":              "gs://my-bucket/train.jsonl",
+		"evaluation_data_uri":      "gs://my-bucket/eval.jsonl",
+		"train_steps":              300.0,
+		"learning_rate_multiplier": 0.5,
+	}
+	if len(params) != len(want) {
+		t.Errorf("parameters = %v, want %v", params, want)
+	}
+	for k, v := range want {
+		if params[k].AsInterface() != v {
+			t.Errorf("parameter %s = %v, want %v", k, params[k].AsInterface(), v)
+		}
+	}
+}
+
+func TestSubmitError(t *testing.T) {
+	fake := &fakePipelineServer{err: status.Error(codes.PermissionDenied, "aiplatform.pipelineJobs.create denied")}
+	client := fakePipelineClient(t, fake)
+	_, err := Submit(context.Background(), client, job)
+	if status.Code(errors.Unwrap(err)) != codes.PermissionDenied {
+		t.Errorf("Submit() = %v, want the permission denied error", err)
+	}
+
+	invalid := job
+	invalid.TrainingData = "/tmp/train.jsonl"
+	if _, err := Submit(context.Background(), client, invalid); err == nil || len(fake.requests) != 1 {
+		t.Errorf("Submit() with a local file = %v after %d requests", err, len(fake.requests))
+	}
+}
@@ -0,0 +1,102 @@
+package tuning
+
+import (
+	"crypto/sha256"
+	"math"
+	"sort"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// Split divides the examples in a training and an evaluation set, with
+// about fraction of the examples in the evaluation set. The split depends
+// only on the seed and on the input of the examples, not on their order,
+// and examples with the same input are kept in the same set. The examples
+// keep their order in each set. At least one example is left for
+// training, even when the fraction is 1 or more.
+func Split(examples []Example, fraction float64, seed string) (train, eval []Example) {
+	n := int(math.Round(float64(len(examples)) * fraction))
+	if fraction > 0 && n == 0 && len(examples) > 1 {
+		n = 1
+	}
+	n = min(n, len(examples)-1)
+	if n <= 0 {
+		return examples, nil
+	}
+
+	hashes := make([]string, len(examples))
+	order := make([]int, len(examples))
+	for i, e := range examples {
+		sum := sha256.Sum256([]byte(seed + "\x00" + normalize(e.Input)))
+		hashes[i], order[i] = string(sum[:]), i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return hashes[order[a]] < hashes[order[b]] })
+	// Examples with the same input as the last one also go to evaluation
+	for n < len(order) && hashes[order[n]] == hashes[order[n-1]] {
+		n++
+	}
+	inEval := make(map[int]bool, n)
+	for _, i := range order[:n] {
+		inEval[i] = true
+	}
+	for i, e := range examples {
+		if inEval[i] {
+			eval = append(eval, e)
+		} else {
+			train = append(train, e)
+		}
+	}
+	return train, eval
+}
+
+// Distribution summarizes a list of token counts.
+type Distribution struct {
+	Min   int     `json:"min"`
+	Max   int     `json:"max"`
+	Mean  float64 `json:"mean"`
+	P50   int     `json:"p50"`
+	P95   int     `json:"p95"`
+	Total int     `json:"total"`
+}
+
+// distribution returns the summary of the values.
+func distribution(values []int) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	d := Distribution{Min: sorted[0], Max: sorted[len(sorted)-1]}
+	for _, v := range sorted {
+		d.Total += v
+	}
+	d.Mean = float64(d.Total) / float64(len(sorted))
+	d.P50 = sorted[(len(sorted)-1)*50/100]
+	d.P95 = sorted[(len(sorted)-1)*95/100]
+	return d
+}
+
+// Stats are the estimated token counts of a set of examples.
+type Stats struct {
+	Examples     int          `json:"examples"`
+	InputTokens  Distribution `json:"inputTokens"`
+	OutputTokens Distribution `json:"outputTokens"`
+	// BillableCharacters is the total of billable characters of the
+	// inputs and outputs.
+	BillableCharacters int `json:"totalBillableCharacters"`
+}
+
+// NewStats estimates the tokens of the examples with text.EstimateTokens.
+func NewStats(examples []Example) Stats {
+	s := Stats{Examples: len(examples)}
+	inputs := make([]int, len(examples))
+	outputs := make([]int, len(examples))
+	for i, e := range examples {
+		inputs[i] = text.EstimateTokens(e.Input)
+		outputs[i] = text.EstimateTokens(e.Output)
+		s.BillableCharacters += text.BillableCharacters(e.Input) + text.BillableCharacters(e.Output)
+	}
+	s.InputTokens = distribution(inputs)
+	s.OutputTokens = distribution(outputs)
+	return s
+}
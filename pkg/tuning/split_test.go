@@ -0,0 +1,82 @@
+package tuning
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	all := examples(100)
+	all = append(all, Example{Input: "question 7?", Output: "Same input as example 7."})
+	train, eval := Split(all, 0.2, "seed")
+	if len(eval) < 20 || len(eval) > 21 || len(train)+len(eval) != len(all) {
+		t.Fatalf("Split() = %d train and %d eval examples", len(train), len(eval))
+	}
+
+	// The split does not depend on the order of the examples
+	reversed := make([]Example, len(all))
+	for i, e := range all {
+		reversed[len(all)-1-i] = e
+	}
+	_, eval2 := Split(reversed, 0.2, "seed")
+	if !sameInputs(eval, eval2) {
+		t.Errorf("Split() changed with the order of the examples")
+	}
+	_, other := Split(all, 0.2, "other seed")
+	if sameInputs(eval, other) {
+		t.Errorf("Split() did not change with the seed")
+	}
+
+	// Examples with the same input are in the same set
+	in := map[string]bool{}
+	for _, e := range eval {
+		in[normalize(e.Input)] = true
+	}
+	count := 0
+	for _, e := range eval {
+		if normalize(e.Input) == "question 7?" {
+			count++
+		}
+	}
+	if in["question 7?"] && count != 2 {
+		t.Errorf("Split() separated the examples with the same input")
+	}
+}
+
+func TestSplitSmall(t *testing.T) {
+	train, eval := Split(examples(3), 0.1, "")
+	if len(train) != 2 || len(eval) != 1 {
+		t.Errorf("Split() = %d train and %d eval examples, want 2 and 1", len(train), len(eval))
+	}
+	train, eval = Split(examples(3), 0, "")
+	if len(train) != 3 || eval != nil {
+		t.Errorf("Split() without evaluation = %d and %d examples", len(train), len(eval))
+	}
+	train, eval = Split(examples(3), 1.5, "")
+	if len(train) != 1 || len(eval) != 2 {
+		t.Errorf("Split() with a fraction above 1 = %d train and %d eval examples, want 1 and 2", len(train), len(eval))
+	}
+}
+
+func sameInputs(a, b []Example) bool {
+	set := func(examples []Example) map[string]bool {
+		m := map[string]bool{}
+		for _, e := range examples {
+			m[e.Input] = true
+		}
+		return m
+	}
+	return reflect.DeepEqual(set(a), set(b))
+}
+
+func TestNewStats(t *testing.T) {
+	s := NewStats([]Example{
+		{Input: "a b", Output: "c"},
+		{Input: "a b c d", Output: "e f"},
+		{Input: "a b c d e f", Output: "g h i"},
+	})
+	want := Distribution{Min: 2, Max: 6, Mean: 4, P50: 4, P95: 4, Total: 12}
+	if s.Examples != 3 || s.InputTokens != want || s.OutputTokens.Total != 6 || s.BillableCharacters != 18 {
+		t.Errorf("NewStats() = %+v", s)
+	}
+}
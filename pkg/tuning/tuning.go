@@ -0,0 +1,204 @@
+// Package tuning builds the datasets used for the supervised tuning of the
+// text models on Vertex AI, validates them and submits the tuning
+// pipeline.
+//
+// A dataset is a JSON Lines file where each line is an example with the
+// input_text sent to the model and the output_text expected from it. The
+// examples can be read from CSV files, from Markdown files with questions
+// as headings and from the sessions saved by the command line tools.
+package tuning
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ronoaldo/genai-demos/pkg/session"
+)
+
+// Example is a prompt and the response expected from the tuned model.
+type Example struct {
+	Input  string `json:"input_text"`
+	Output string `json:"output_text"`
+	// Source is where the example was read from, like file:line.
+	Source string `json:"-"`
+}
+
+// ReadFile reads the examples of a file, selecting the format by its
+// extension: .csv, .md, .jsonl or .json for a saved session.
+func ReadFile(name string) ([]Example, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return ReadCSV(f, name)
+	case ".md", ".markdown":
+		return ReadMarkdown(f, name)
+	case ".jsonl":
+		return ReadJSONL(f, name)
+	case ".json":
+		var s session.Session
+		if err := json.NewDecoder(f).Decode(&s); err != nil {
+			return nil, fmt.Errorf("tuning: %s: %v", name, err)
+		}
+		return FromSession(&s, name), nil
+	}
+	return nil, fmt.Errorf("tuning: %s: unknown format, use .csv, .md, .jsonl or .json", name)
+}
+
+// headers are the accepted names of the input and output columns of CSV
+// files, in lower case.
+var headers = map[string]int{
+	"input_text": 0, "input": 0, "question": 0, "prompt": 0, "pergunta": 0,
+	"output_text": 1, "output": 1, "answer": 1, "response": 1, "resposta": 1,
+}
+
+// ReadCSV reads the examples of a CSV file. When the first record names the
+// input and output columns, like input_text and output_text or question
+// and answer, it is used as the header; otherwise the first two columns
+// are used.
+func ReadCSV(r io.Reader, name string) ([]Example, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	columns := [2]int{0, 1}
+	var examples []Example
+	for first := true; ; first = false {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return examples, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("tuning: %s: %v", name, err)
+		}
+		if first {
+			record[0] = strings.TrimPrefix(record[0], "\ufeff")
+			if c, ok := header(record); ok {
+				columns = c
+				continue
+			}
+		}
+		line, _ := cr.FieldPos(0)
+		examples = append(examples, Example{
+			Input:  field(record, columns[0]),
+			Output: field(record, columns[1]),
+			Source: fmt.Sprintf("%s:%d", name, line),
+		})
+	}
+}
+
+// header returns the input and output columns named in the record.
+func header(record []string) (columns [2]int, ok bool) {
+	found := [2]bool{}
+	for i, field := range record {
+		if col, ok := headers[strings.ToLower(strings.TrimSpace(field))]; ok && !found[col] {
+			columns[col], found[col] = i, true
+		}
+	}
+	return columns, found[0] && found[1]
+}
+
+func field(record []string, i int) string {
+	if i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// ReadMarkdown reads the examples of a Markdown file where each question
+// is a heading of level 2 or lower, followed by its answer. Level 1
+// headings are titles and are ignored, as well as the text before the
+// first question.
+func ReadMarkdown(r io.Reader, name string) ([]Example, error) {
+	var examples []Example
+	var answer strings.Builder
+	var current *Example
+	flush := func() {
+		if current != nil {
+			current.Output = strings.TrimSpace(answer.String())
+			examples = append(examples, *current)
+		}
+		current = nil
+		answer.Reset()
+	}
+
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1024*1024)
+	fence := false
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fence = !fence
+		}
+		level := len(line) - len(strings.TrimLeft(line, "#"))
+		if fence || level == 0 || level > 6 || !strings.HasPrefix(line[level:], " ") {
+			answer.WriteString(line + "\n")
+			continue
+		}
+		flush()
+		if level > 1 {
+			current = &Example{Input: strings.TrimSpace(line[level:]), Source: fmt.Sprintf("%s:%d", name, n)}
+		}
+	}
+	flush()
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("tuning: %s: %v", name, err)
+	}
+	return examples, nil
+}
+
+// ReadJSONL reads the examples of a dataset in JSON Lines. Empty lines are
+// ignored.
+func ReadJSONL(r io.Reader, name string) ([]Example, error) {
+	var examples []Example
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1024*1024)
+	for n := 1; s.Scan(); n++ {
+		line := bytes.TrimSpace(s.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		e := Example{Source: fmt.Sprintf("%s:%d", name, n)}
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("tuning: %s: %v", e.Source, err)
+		}
+		examples = append(examples, e)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("tuning: %s: %v", name, err)
+	}
+	return examples, nil
+}
+
+// FromSession returns the turns of a saved session as examples.
+func FromSession(s *session.Session, name string) []Example {
+	examples := make([]Example, 0, len(s.Turns))
+	for i, t := range s.Turns {
+		examples = append(examples, Example{
+			Input:  strings.TrimSpace(t.Prompt),
+			Output: strings.TrimSpace(t.Response),
+			Source: fmt.Sprintf("%s:turn %d", name, i+1),
+		})
+	}
+	return examples
+}
+
+// WriteJSONL writes the examples as a dataset in JSON Lines.
+func WriteJSONL(w io.Writer, examples []Example) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for _, e := range examples {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,115 @@
+package tuning
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ronoaldo/genai-demos/pkg/session"
+)
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Example
+	}{
+		{
+			name:  "header",
+			input: "\ufeffanswer,question\n\"Use df -h.\",How to see the free disk space?\n",
+			want:  []Example{{Input: "How to see the free disk space?", Output: "Use df -h.", Source: "qa.csv:2"}},
+		},
+		{
+			name:  "no header",
+			input: "How to list files?,Use ls.\n\"How to see\nthe kernel version?\",Use uname -r.\n",
+			want: []Example{
+				{Input: "How to list files?", Output: "Use ls.", Source: "qa.csv:1"},
+				{Input: "How to see\nthe kernel version?", Output: "Use uname -r.", Source: "qa.csv:2"},
+			},
+		},
+		{
+			name:  "missing column",
+			input: "pergunta,resposta\nQual é o meu IP?\n",
+			want:  []Example{{Input: "Qual é o meu IP?", Source: "qa.csv:2"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadCSV(strings.NewReader(tc.input), "qa.csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ReadCSV() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadMarkdown(t *testing.T) {
+	input := "# Linux Q&A\n\nIntro text.\n\n" +
+		"## How to list open ports?\n\nUse:\n\n```sh\n# as root\nss -tlnp\n```\n\n" +
+		"### How to follow a log?\n\nUse `tail -f`.\n\n" +
+		"#hashtag is not a heading\n"
+	want := []Example{
+		{Input: "How to list open ports?", Output: "Use:\n\n```sh\n# as root\nss -tlnp\n```", Source: "qa.md:5"},
+		{Input: "How to follow a log?", Output: "Use `tail -f`.\n\n#hashtag is not a heading", Source: "qa.md:14"},
+	}
+	got, err := ReadMarkdown(strings.NewReader(input), "qa.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadMarkdown() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	s := &session.Session{Name: "linux", Tool: "linux-guru"}
+	s.Add(session.Turn{Prompt: " How to list files? ", Response: "Use ls.\n"})
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"linux.json": string(b),
+		"data.jsonl": `{"input_text": "How to list files?", "output_text": "Use ls."}` + "\n\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Input != "How to list files?" || got[0].Output != "Use ls." {
+			t.Errorf("ReadFile(%s) = %+v", name, got)
+		}
+	}
+	if _, err := ReadFile(filepath.Join(dir, "data.txt")); err == nil {
+		t.Errorf("ReadFile() accepted an unknown format")
+	}
+}
+
+func TestWriteJSONL(t *testing.T) {
+	examples := []Example{{Input: "<b>ls</b>?", Output: "Lista & mostra", Source: "a.csv:1"}}
+	var b bytes.Buffer
+	if err := WriteJSONL(&b, examples); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"input_text":"<b>ls</b>?","output_text":"Lista & mostra"}` + "\n"
+	if b.String() != want {
+		t.Errorf("WriteJSONL() = %q, want %q", b.String(), want)
+	}
+	got, err := ReadJSONL(&b, "out.jsonl")
+	if err != nil || len(got) != 1 || got[0].Input != examples[0].Input {
+		t.Errorf("ReadJSONL() = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,134 @@
+package tuning
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// MinExamples is the minimum number of examples accepted by the tuning
+// pipeline. The documentation recommends 100 to 500 examples.
+const MinExamples = 10
+
+// Severity tells if an issue prevents the dataset from being used.
+type Severity string
+
+// Severities of the issues reported by Validate.
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Issue is a problem found in one example or in the whole dataset.
+type Issue struct {
+	Severity Severity `json:"severity"`
+	// Rule identifies the check that failed, like encoding or duplicate.
+	Rule string `json:"rule"`
+	// Example is the index of the example, or -1 for the whole dataset.
+	Example int    `json:"example"`
+	Source  string `json:"source,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Source == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Source, i.Severity, i.Message)
+}
+
+// HasErrors reports if any of the issues is an Error.
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the encoding, the size and the uniqueness of the
+// examples. The number of tokens is estimated with text.EstimateTokens
+// and checked against the limits of the model to be tuned.
+func Validate(examples []Example, limits text.Limits) []Issue {
+	var issues []Issue
+	add := func(sev Severity, rule string, i int, format string, v ...any) {
+		issue := Issue{Severity: sev, Rule: rule, Example: i, Message: fmt.Sprintf(format, v...)}
+		if i >= 0 {
+			issue.Source = examples[i].Source
+		}
+		issues = append(issues, issue)
+	}
+
+	seen := make(map[string]int)
+	inputs := make(map[string]int)
+	for i, e := range examples {
+		for _, f := range []struct{ name, value string }{{"input_text", e.Input}, {"output_text", e.Output}} {
+			switch {
+			case !utf8.ValidString(f.value):
+				add(Error, "encoding", i, "%s is not valid UTF-8", f.name)
+			case strings.ContainsRune(f.value, utf8.RuneError):
+				add(Error, "encoding", i, "%s has replacement characters (U+FFFD) from a bad conversion", f.name)
+			case strings.IndexFunc(f.value, isControl) >= 0:
+				add(Warning, "control", i, "%s has control characters", f.name)
+			}
+			if strings.TrimSpace(f.value) == "" {
+				add(Error, "empty", i, "%s is empty", f.name)
+			}
+		}
+		if n := text.EstimateTokens(e.Input); limits.InputTokens > 0 && n > limits.InputTokens {
+			add(Error, "length", i, "input_text has about %d tokens, the limit is %d", n, limits.InputTokens)
+		}
+		if n := text.EstimateTokens(e.Output); limits.OutputTokens > 0 && n > limits.OutputTokens {
+			add(Error, "length", i, "output_text has about %d tokens, the limit is %d", n, limits.OutputTokens)
+		}
+
+		input := normalize(e.Input)
+		key := input + "\x00" + normalize(e.Output)
+		if j, ok := seen[key]; ok {
+			add(Error, "duplicate", i, "duplicate of %s", examples[j].Source)
+			continue
+		}
+		seen[key] = i
+		if j, ok := inputs[input]; ok {
+			add(Warning, "conflict", i, "same input_text as %s with a different output_text", examples[j].Source)
+		} else {
+			inputs[input] = i
+		}
+	}
+	if len(examples) < MinExamples {
+		add(Error, "size", -1, "%d examples, at least %d are required", len(examples), MinExamples)
+	}
+	return issues
+}
+
+// Dedupe returns the examples without the duplicates, keeping the first
+// one, and the number of examples removed. Examples are duplicates when
+// they differ only in letter case and spacing.
+func Dedupe(examples []Example) ([]Example, int) {
+	seen := make(map[string]bool)
+	unique := make([]Example, 0, len(examples))
+	for _, e := range examples {
+		key := normalize(e.Input) + "\x00" + normalize(e.Output)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, e)
+	}
+	return unique, len(examples) - len(unique)
+}
+
+// normalize ignores the letter case and spacing when comparing examples.
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// isControl reports the control characters other than line breaks and
+// tabs.
+func isControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\t' && r != '\r'
+}
@@ -0,0 +1,74 @@
+package tuning
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ronoaldo/genai-demos/pkg/text"
+)
+
+// examples returns n distinct valid examples.
+func examples(n int) []Example {
+	var e []Example
+	for i := 0; i < n; i++ {
+		e = append(e, Example{
+			Input:  fmt.Sprintf("Question %d?", i),
+			Output: fmt.Sprintf("Answer %d.", i),
+			Source: fmt.Sprintf("qa.csv:%d", i+1),
+		})
+	}
+	return e
+}
+
+func TestValidate(t *testing.T) {
+	limits := text.Limits{InputTokens: 50, OutputTokens: 10}
+	tests := []struct {
+		name  string
+		extra []Example
+		want  []string
+	}{
+		{"valid", nil, nil},
+		{"invalid utf-8", []Example{{Input: "Como listar arquivos?", Output: "Use ls ou \xe9 dir."}}, []string{"error encoding"}},
+		{"bad conversion", []Example{{Input: "Fun\ufffd\ufffdo", Output: "Ok."}}, []string{"error encoding"}},
+		{"control characters", []Example{{Input: "Colors \x1b[31mred?", Output: "Ok."}}, []string{"warning control"}},
+		{"empty", []Example{{Input: "Question?", Output: "  "}}, []string{"error empty"}},
+		{"output too long", []Example{{Input: "Question?", Output: strings.Repeat("word ", 20)}}, []string{"error length"}},
+		{"duplicate", []Example{{Input: "question  1?", Output: "ANSWER 1."}}, []string{"error duplicate"}},
+		{"conflict", []Example{{Input: "Question 1?", Output: "Another answer."}}, []string{"warning conflict"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			issues := Validate(append(examples(MinExamples), tc.extra...), limits)
+			var got []string
+			for _, i := range issues {
+				got = append(got, string(i.Severity)+" "+i.Rule)
+				if i.Example != MinExamples {
+					t.Errorf("issue %v in example %d, want %d", i, i.Example, MinExamples)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Validate() = %v, want %v", issues, tc.want)
+			}
+			if HasErrors(issues) != (len(tc.want) > 0 && strings.HasPrefix(tc.want[0], "error")) {
+				t.Errorf("HasErrors() = %v", HasErrors(issues))
+			}
+		})
+	}
+}
+
+func TestValidateSize(t *testing.T) {
+	issues := Validate(examples(3), text.ModelLimits[text.ModelVersion])
+	if len(issues) != 1 || issues[0].Rule != "size" || issues[0].Example != -1 {
+		t.Errorf("Validate() = %v, want a size error", issues)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	in := append(examples(3), Example{Input: "QUESTION 0?", Output: " answer 0. "}, Example{Input: "Question 0?", Output: "Other."})
+	got, removed := Dedupe(in)
+	if removed != 1 || len(got) != 4 || got[3].Output != "Other." {
+		t.Errorf("Dedupe() = %+v, %d", got, removed)
+	}
+}
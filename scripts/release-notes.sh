@@ -1,22 +1,14 @@
 #!/bin/bash
+# Summarizes the Google Cloud release notes of the last 30 days for the
+# product given as argument, using cmd/release-notes.
 
-QUERY="$(cat <<EOF
-SELECT
-  FORMAT("At %t, the product %s got the following update: %s",
-    published_at,
-    product_name,
-    REPLACE(description, "\n", ". ")) as line
+QUERY="$(cat <<EOQ
+SELECT description, release_note_type, published_at, product_name
 FROM \`bigquery-public-data.google_cloud_release_notes.release_notes\`
 WHERE published_at >= DATE_ADD(CURRENT_DATE(), INTERVAL -30 DAY)
-  AND lower(product_name) LIKE lower("%${1}%")
 ORDER BY published_at ASC
-LIMIT 10
-EOF
+EOQ
 )"
-bq query --format=json --use_legacy_sql=false "${QUERY}" | jq -r .[].line > notes.txt
-
-PROMPT="Summarize the following text in a few topics:
-
-$(cat notes.txt)"
-
-textbison "${PROMPT}" | jq -r .predictions[].content | tee notes.md
+bq query --format=json --use_legacy_sql=false --max_rows=10000 "${QUERY}" |
+	release-notes -products "${1}" -o notes.md
+cat notes.md